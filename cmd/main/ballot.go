@@ -0,0 +1,236 @@
+package main
+
+import (
+	"apiGateway/internal/ballot"
+	"apiGateway/internal/dto"
+	"apiGateway/internal/lib/logger/sl"
+	"apiGateway/internal/models"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultRevealDuration - длительность фазы раскрытия, если создатель ее не указал
+const defaultRevealDuration = 24 * time.Hour
+
+// recordVote записывает голос пользователя в Voters, счетчики и UserActivity.
+// Вес голоса включает делегаторов, для которых пользователь - первый проголосовавший представитель.
+// Должен вызываться под мьютексом
+func recordVote(voting *models.VoteSession, userAddress string, choice int) (int64, []string) {
+	userAddressLower := strings.ToLower(userAddress)
+
+	activity := userActivities[userAddressLower]
+	if activity.ParticipatedVotings == nil {
+		activity.ParticipatedVotings = make(map[string]int)
+	}
+	activity.ParticipatedVotings[voting.ID] = choice
+	userActivities[userAddressLower] = activity
+
+	represented := claimRepresented(voting, userAddressLower)
+	weight := int64(1 + len(represented))
+
+	if voting.Voters == nil {
+		voting.Voters = make(map[string]models.Voter)
+	}
+	voting.Voters[userAddressLower] = models.Voter{
+		Address:    userAddress,
+		IsVoted:    true,
+		Choice:     choice,
+		CanVote:    true, // Это поле здесь не играет роли, но сохраним для структуры
+		Weight:     weight,
+		Represents: represented,
+	}
+
+	voting.Choices[choice].CountVotes += weight
+	voting.TempNumberVotes += weight
+
+	return weight, represented
+}
+
+// CommitVoteHandler - принимает скрытый голос (хеш выбора) во время голосования
+func CommitVoteHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.CommitVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("CommitVoteHandler: Invalid request payload", sl.Err(err))
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.VotingID == "" || req.UserAddress == "" {
+		http.Error(w, "voting_id and user_address are required", http.StatusBadRequest)
+		return
+	}
+
+	hash, err := ballot.ParseCommitment(req.Commitment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userAddressLower := strings.ToLower(req.UserAddress)
+	now := time.Now()
+
+	mu.Lock()
+	voting, ok := votings[req.VotingID]
+	if !ok {
+		mu.Unlock()
+		http.Error(w, "VoteSession not found", http.StatusNotFound)
+		return
+	}
+	if !voting.IsCommitReveal() {
+		mu.Unlock()
+		http.Error(w, "This poll does not use secret ballots, vote via /vote", http.StatusConflict)
+		return
+	}
+	if now.Before(voting.StartTime) || now.After(voting.EndTime) {
+		mu.Unlock()
+		http.Error(w, "Commitments are accepted only while the poll is active", http.StatusForbidden)
+		return
+	}
+	if voting.Commitments == nil {
+		voting.Commitments = make(map[string]models.Commitment)
+	}
+	if _, exists := voting.Commitments[userAddressLower]; exists && !voting.AllowVoteChange {
+		mu.Unlock()
+		http.Error(w, "You have already voted in this poll", http.StatusConflict)
+		return
+	}
+	commitment := models.Commitment{
+		Hash:        hash.Hex(),
+		CommittedAt: now,
+	}
+	voting.Commitments[userAddressLower] = commitment
+	votings[req.VotingID] = voting
+	mu.Unlock()
+
+	err = kafkaProducer.VoteCommitProduce(r.Context(), dto.VoteCommit{
+		VotingID:      req.VotingID,
+		VoterID:       req.UserAddress,
+		Commitment:    commitment.Hash,
+		CommittedAt:   now.Format(time.RFC3339),
+		RevealEndDate: voting.RevealEndTime.Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Error("Failed to send vote commit event to Kafka", sl.Err(err),
+			slog.String("voting_id", req.VotingID),
+			slog.String("voter_id", req.UserAddress))
+	}
+
+	log.Info("Vote commitment recorded",
+		slog.String("voting_id", req.VotingID),
+		slog.String("user_address", req.UserAddress))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"message":    "Vote commitment successfully recorded",
+		"commitment": commitment.Hash,
+	}); err != nil {
+		log.Error("CommitVoteHandler: Failed to encode response", sl.Err(err))
+	}
+}
+
+// RevealVoteHandler - принимает раскрытие голоса после окончания голосования.
+// Голос учитывается только если хеш раскрытия совпадает с записанным commitment.
+func RevealVoteHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.RevealVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("RevealVoteHandler: Invalid request payload", sl.Err(err))
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userAddressLower := strings.ToLower(req.UserAddress)
+
+	mu.Lock()
+	UpdateVotingStatusAndWinner(req.VotingID)
+	voting, ok := votings[req.VotingID]
+	if !ok {
+		mu.Unlock()
+		http.Error(w, "VoteSession not found", http.StatusNotFound)
+		return
+	}
+	if !voting.IsCommitReveal() {
+		mu.Unlock()
+		http.Error(w, "This poll does not use secret ballots", http.StatusConflict)
+		return
+	}
+	if voting.Status != models.StatusReveal {
+		mu.Unlock()
+		http.Error(w, fmt.Sprintf("Reveals are not accepted while the poll is %s", voting.Status), http.StatusForbidden)
+		return
+	}
+
+	commitment, exists := voting.Commitments[userAddressLower]
+	if !exists {
+		mu.Unlock()
+		http.Error(w, "No commitment recorded for this address", http.StatusNotFound)
+		return
+	}
+	if commitment.Revealed {
+		mu.Unlock()
+		http.Error(w, "Vote has already been revealed", http.StatusConflict)
+		return
+	}
+	if req.SelectedOptionIndex < 0 || req.SelectedOptionIndex >= len(voting.Choices) {
+		mu.Unlock()
+		http.Error(w, "Invalid option selected", http.StatusBadRequest)
+		return
+	}
+	if !ballot.Verify(commitment, req.VotingID, req.UserAddress, req.SelectedOptionIndex, req.Salt) {
+		mu.Unlock()
+		log.Warn("RevealVoteHandler: Reveal does not match commitment",
+			slog.String("voting_id", req.VotingID),
+			slog.String("user_address", req.UserAddress))
+		http.Error(w, "Reveal does not match the recorded commitment", http.StatusBadRequest)
+		return
+	}
+
+	commitment.Revealed = true
+	voting.Commitments[userAddressLower] = commitment
+	weight, represented := recordVote(&voting, req.UserAddress, req.SelectedOptionIndex)
+	votings[req.VotingID] = voting
+	mu.Unlock()
+
+	// Теперь выбор можно передать Java-сервису как обычный голос
+	voteEvent := dto.VoteCast{
+		VotingID:   req.VotingID,
+		VoterID:    req.UserAddress,
+		OptionID:   fmt.Sprintf("%d", req.SelectedOptionIndex),
+		Weight:     weight,
+		Delegators: represented,
+	}
+	if err := kafkaProducer.VoteCastProduce(r.Context(), voteEvent); err != nil {
+		log.Error("Failed to send revealed vote to Kafka", sl.Err(err),
+			slog.String("voting_id", req.VotingID),
+			slog.String("voter_id", req.UserAddress))
+	}
+
+	// Отметка о раскрытии в vote-commit не дает раскрыть голос второй раз после рестарта
+	err = kafkaProducer.VoteCommitProduce(r.Context(), dto.VoteCommit{
+		VotingID:      req.VotingID,
+		VoterID:       req.UserAddress,
+		Commitment:    commitment.Hash,
+		CommittedAt:   commitment.CommittedAt.Format(time.RFC3339),
+		RevealEndDate: voting.RevealEndTime.Format(time.RFC3339),
+		Revealed:      true,
+	})
+	if err != nil {
+		log.Error("Failed to send reveal mark to Kafka", sl.Err(err),
+			slog.String("voting_id", req.VotingID),
+			slog.String("voter_id", req.UserAddress))
+	}
+
+	log.Info("Vote revealed",
+		slog.String("voting_id", req.VotingID),
+		slog.String("user_address", req.UserAddress))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Vote successfully revealed"}); err != nil {
+		log.Error("RevealVoteHandler: Failed to encode response", sl.Err(err))
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"apiGateway/internal/delegation"
+	"apiGateway/internal/dto"
+	"apiGateway/internal/lib/logger/sl"
+	"apiGateway/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-chi/chi"
+)
+
+// DelegationRequest - запрос на делегирование или его отзыв, подписанный делегатором через personal_sign
+// (текст delegationMessage), поэтому распоряжаться голосом адреса может только его владелец
+type DelegationRequest struct {
+	DelegatorAddress string `json:"delegator_address"`
+	DelegateAddress  string `json:"delegate_address"`
+	VotingID         string `json:"voting_id"` // Пустой - делегирование для всех голосований
+	Signature        string `json:"signature"` // 0x-подпись delegationMessage
+	SignedAt         int64  `json:"signed_at"` // Unix-время подписи; вместе с подписью служит nonce запроса
+}
+
+// delegationMessage - текст, который делегатор подписывает для делегирования (action "delegate")
+// или его отзыва (action "revoke"). При отзыве delegate не указывается
+func delegationMessage(action string, req DelegationRequest) string {
+	votingID := req.VotingID
+	if votingID == "" {
+		votingID = "all"
+	}
+	delegate := "-"
+	if action == "delegate" {
+		delegate = strings.ToLower(common.HexToAddress(req.DelegateAddress).Hex())
+	}
+	return fmt.Sprintf("TrustVote %s\ndelegator: %s\ndelegate: %s\nvoting_id: %s\nsigned_at: %d",
+		action, strings.ToLower(common.HexToAddress(req.DelegatorAddress).Hex()), delegate, votingID, req.SignedAt)
+}
+
+// voteWeight возвращает вес голоса; у голосов, записанных до появления делегирования, вес не заполнен
+func voteWeight(v models.Voter) int64 {
+	if v.Weight < 1 {
+		return 1
+	}
+	return v.Weight
+}
+
+// votedIn возвращает проверку "голосовал ли адрес напрямую" для голосования
+func votedIn(voting models.VoteSession) func(string) bool {
+	return func(addr string) bool {
+		v, ok := voting.Voters[addr]
+		return ok && v.IsVoted
+	}
+}
+
+// claimRepresented отдает voter голоса его делегаторов. Если делегатор уже был учтен
+// в голосе другого представителя дальше по цепочке, голос переносится оттуда.
+// Сам voter, голосуя напрямую, тоже перестает быть учтенным в голосе своего представителя.
+// Должен вызываться под мьютексом
+func claimRepresented(voting *models.VoteSession, voterAddr string) []string {
+	represented := delegations.Represented(voting.ID, voterAddr, votedIn(*voting))
+
+	unclaim(voting, strings.ToLower(voterAddr))
+	for _, delegator := range represented {
+		unclaim(voting, delegator)
+	}
+	return represented
+}
+
+// releaseRepresented передает делегаторов отозванного голоса следующему проголосовавшему в их цепочке.
+// Если такого нет, их голоса просто перестают учитываться.
+// Должен вызываться под мьютексом, после того как голос voterAddr снят
+func releaseRepresented(voting *models.VoteSession, represents []string) {
+	hasVoted := votedIn(*voting)
+	for _, delegator := range represents {
+		if rep, ok := delegations.Representative(voting.ID, delegator, hasVoted); ok {
+			assign(voting, rep, delegator)
+		}
+	}
+}
+
+// syncRepresented приводит веса проголосовавших в соответствие с текущими делегированиями:
+// после создания или отзыва делегирования голоса делегаторов переходят к новым представителям.
+// Должен вызываться под мьютексом
+func syncRepresented(voting *models.VoteSession) {
+	hasVoted := votedIn(*voting)
+	want := make(map[string][]string)
+	for addr, v := range voting.Voters {
+		if v.IsVoted {
+			want[addr] = delegations.Represented(voting.ID, addr, hasVoted)
+		}
+	}
+
+	// Сначала снимаем лишних делегаторов, потом добавляем новых, чтобы делегатор не оказался учтен дважды
+	for addr, represented := range want {
+		for _, delegator := range slices.Clone(voting.Voters[addr].Represents) {
+			if !slices.Contains(represented, delegator) {
+				unclaim(voting, delegator)
+			}
+		}
+	}
+	for addr, represented := range want {
+		for _, delegator := range represented {
+			if !slices.Contains(voting.Voters[addr].Represents, delegator) {
+				assign(voting, addr, delegator)
+			}
+		}
+	}
+}
+
+// unclaim убирает delegator из голоса представителя, в котором он учтен, вместе с его весом в счетчиках
+func unclaim(voting *models.VoteSession, delegator string) {
+	for addr, other := range voting.Voters {
+		idx := slices.Index(other.Represents, delegator)
+		if idx == -1 {
+			continue
+		}
+		other.Represents = slices.Delete(other.Represents, idx, idx+1)
+		other.Weight = voteWeight(other) - 1
+		if other.Choice >= 0 && other.Choice < len(voting.Choices) && voting.Choices[other.Choice].CountVotes > 0 {
+			voting.Choices[other.Choice].CountVotes--
+		}
+		if voting.TempNumberVotes > 0 {
+			voting.TempNumberVotes--
+		}
+		voting.Voters[addr] = other
+		return
+	}
+}
+
+// assign добавляет голос delegator к голосу представителя rep
+func assign(voting *models.VoteSession, rep, delegator string) {
+	other := voting.Voters[rep]
+	other.Represents = append(other.Represents, delegator)
+	other.Weight = voteWeight(other) + 1
+	if other.Choice >= 0 && other.Choice < len(voting.Choices) {
+		voting.Choices[other.Choice].CountVotes++
+	}
+	voting.TempNumberVotes++
+	voting.Voters[rep] = other
+}
+
+// resyncDelegations пересчитывает веса голосов в незавершенных голосованиях, затронутых делегированием:
+// в голосовании votingID или, для глобального делегирования, во всех
+func resyncDelegations(votingID string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	for id, voting := range votings {
+		if (votingID != "" && id != votingID) || now.After(voting.EndTime) {
+			continue
+		}
+		syncRepresented(&voting)
+		votings[id] = voting
+		UpdateVotingStatusAndWinner(id)
+	}
+}
+
+// produceDelegation отправляет событие делегирования в Kafka; ошибка только логируется
+func produceDelegation(r *http.Request, d delegation.Delegation, action string) {
+	// Время события - время изменения: по нему консюмер отбрасывает устаревшие события при восстановлении
+	changedAt := d.CreatedAt
+	if action == dto.DelegationActionRevoked {
+		changedAt = time.Now()
+	}
+	event := dto.Delegation{
+		DelegatorID: d.Delegator,
+		DelegateID:  d.Delegate,
+		VotingID:    d.VotingID,
+		Action:      action,
+		CreatedAt:   changedAt.Format(time.RFC3339),
+	}
+	if err := kafkaProducer.DelegationProduce(r.Context(), event); err != nil {
+		log.Error("Failed to send delegation event to Kafka", sl.Err(err),
+			slog.String("delegator", d.Delegator),
+			slog.String("action", action))
+	}
+}
+
+// checkDelegationVoting проверяет, что в голосовании еще можно менять делегирование
+func checkDelegationVoting(votingID string) (int, string) {
+	if votingID == "" {
+		return http.StatusOK, ""
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	voting, ok := votings[votingID]
+	if !ok {
+		return http.StatusNotFound, "VoteSession not found"
+	}
+	if time.Now().After(voting.EndTime) {
+		return http.StatusForbidden, "VoteSession has already ended"
+	}
+	return http.StatusOK, ""
+}
+
+// DelegateHandler - делегирует голос другому адресу глобально или в конкретном голосовании
+func DelegateHandler(w http.ResponseWriter, r *http.Request) {
+	var req DelegationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("DelegateHandler: Invalid request payload", sl.Err(err))
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if !common.IsHexAddress(req.DelegatorAddress) || !common.IsHexAddress(req.DelegateAddress) {
+		http.Error(w, "delegator_address and delegate_address must be valid addresses", http.StatusBadRequest)
+		return
+	}
+
+	if !verifySignedRequest(w, log, delegationMessage("delegate", req), req.Signature, req.SignedAt, common.HexToAddress(req.DelegatorAddress)) {
+		return
+	}
+
+	if status, msg := checkDelegationVoting(req.VotingID); status != http.StatusOK {
+		http.Error(w, msg, status)
+		return
+	}
+
+	d, err := delegations.Delegate(req.VotingID, req.DelegatorAddress, req.DelegateAddress)
+	if err != nil {
+		log.Warn("DelegateHandler: Delegation rejected", sl.Err(err),
+			slog.String("delegator", req.DelegatorAddress),
+			slog.String("delegate", req.DelegateAddress),
+			slog.String("voting_id", req.VotingID))
+		status := http.StatusBadRequest
+		if errors.Is(err, delegation.ErrCycle) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	resyncDelegations(d.VotingID)
+	produceDelegation(r, d, dto.DelegationActionDelegated)
+
+	log.Info("Vote delegated",
+		slog.String("delegator", d.Delegator),
+		slog.String("delegate", d.Delegate),
+		slog.String("voting_id", d.VotingID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		log.Error("DelegateHandler: Failed to encode response", sl.Err(err))
+	}
+}
+
+// RevokeDelegationHandler - отзывает делегирование
+func RevokeDelegationHandler(w http.ResponseWriter, r *http.Request) {
+	var req DelegationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("RevokeDelegationHandler: Invalid request payload", sl.Err(err))
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if !common.IsHexAddress(req.DelegatorAddress) {
+		http.Error(w, "delegator_address must be a valid address", http.StatusBadRequest)
+		return
+	}
+
+	if !verifySignedRequest(w, log, delegationMessage("revoke", req), req.Signature, req.SignedAt, common.HexToAddress(req.DelegatorAddress)) {
+		return
+	}
+
+	if status, msg := checkDelegationVoting(req.VotingID); status != http.StatusOK {
+		http.Error(w, msg, status)
+		return
+	}
+
+	d, err := delegations.Revoke(req.VotingID, req.DelegatorAddress)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	resyncDelegations(d.VotingID)
+	produceDelegation(r, d, dto.DelegationActionRevoked)
+
+	log.Info("Delegation revoked",
+		slog.String("delegator", d.Delegator),
+		slog.String("delegate", d.Delegate),
+		slog.String("voting_id", d.VotingID))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Delegation successfully revoked"}); err != nil {
+		log.Error("RevokeDelegationHandler: Failed to encode response", sl.Err(err))
+	}
+}
+
+// GetDelegationsHandler - возвращает делегирования адреса и цепочку представителей в голосовании
+func GetDelegationsHandler(w http.ResponseWriter, r *http.Request) {
+	address := chi.URLParam(r, "address")
+	if !common.IsHexAddress(address) {
+		http.Error(w, "Invalid address", http.StatusBadRequest)
+		return
+	}
+	votingID := r.URL.Query().Get("voting_id")
+
+	response := struct {
+		Address     string                  `json:"address"`
+		Delegations []delegation.Delegation `json:"delegations"`
+		Chain       []string                `json:"chain,omitempty"`
+	}{
+		Address:     strings.ToLower(address),
+		Delegations: delegations.Of(address),
+	}
+	if response.Delegations == nil {
+		response.Delegations = []delegation.Delegation{}
+	}
+	if votingID != "" {
+		response.Chain = delegations.Chain(votingID, address)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error("GetDelegationsHandler: Failed to encode response", sl.Err(err))
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"apiGateway/internal/client"
+	"apiGateway/internal/http-server/chainerr"
+	"apiGateway/internal/http-server/resp"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/render"
+)
+
+// isDryRun - запрос с ?dry_run=true только симулирует транзакцию через eth_call и ничего не отправляет
+func isDryRun(r *http.Request) bool {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	return dryRun
+}
+
+// writeSimulation отвечает результатом симуляции; revert контракта переводится в статус через chainerr
+func writeSimulation(w http.ResponseWriter, r *http.Request, log *slog.Logger, sim client.Simulation, err error, action string) {
+	if err != nil {
+		chainerr.Write(w, r, log, err, action)
+		return
+	}
+	log.Info("Transaction simulated", slog.String("method", sim.Method), slog.Uint64("gas", sim.Gas))
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, resp.OK("Dry run: transaction was simulated and not sent", sim))
+}
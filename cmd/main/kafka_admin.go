@@ -0,0 +1,159 @@
+package main
+
+import (
+	"apiGateway/internal/bootstrap"
+	"apiGateway/internal/config"
+	"apiGateway/internal/kafka/consumer"
+	"apiGateway/internal/kafka/dlq"
+	"apiGateway/internal/kafka/producer"
+	"apiGateway/internal/lib/logger/sl"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxDeliveryFailures - сколько последних ошибок доставки продюсера показывает /admin/producer
+const maxDeliveryFailures = 50
+
+// deliveryFailures - последние сообщения, которые продюсер не смог доставить
+var deliveryFailures failedDeliveries
+
+// DeliveryFailure - сообщение, которое продюсер не смог доставить в Kafka
+type DeliveryFailure struct {
+	Topic         string    `json:"topic"`
+	EventType     string    `json:"event_type"`
+	Key           string    `json:"key,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Error         string    `json:"error"`
+	FailedAt      time.Time `json:"failed_at"`
+}
+
+type failedDeliveries struct {
+	mu    sync.Mutex
+	items []DeliveryFailure // От старых к новым, не больше maxDeliveryFailures
+}
+
+// record получает результаты доставки от продюсера и запоминает неудачные
+func (f *failedDeliveries) record(report producer.DeliveryReport) {
+	if report.Err == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.items = append(f.items, DeliveryFailure{
+		Topic:         report.Topic,
+		EventType:     report.EventType,
+		Key:           report.Key,
+		CorrelationID: report.CorrelationID,
+		Error:         report.Err.Error(),
+		FailedAt:      time.Now().UTC(),
+	})
+	if over := len(f.items) - maxDeliveryFailures; over > 0 {
+		f.items = slices.Delete(f.items, 0, over)
+	}
+}
+
+// recent возвращает последние ошибки доставки, от новых к старым
+func (f *failedDeliveries) recent() []DeliveryFailure {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := slices.Clone(f.items)
+	slices.Reverse(out)
+	return out
+}
+
+// ProducerMetricsResponse - ответ /admin/producer
+type ProducerMetricsResponse struct {
+	producer.DeliveryMetrics
+	RecentFailures []DeliveryFailure `json:"recent_failures"` // Последние недоставленные сообщения, от новых к старым
+}
+
+// consumedTopics - топики, которые читает шлюз; только для них есть dead-letter топики
+var consumedTopics = []string{config.TopicAllVotingsResponse, config.TopicVotingResponse, config.TopicVoteHistoryResponse, config.TopicVoteCommit}
+
+type ReplayDLQRequest struct {
+	Topic string `json:"topic"`           // Логическое имя исходного топика, например "voting-response"
+	Limit int    `json:"limit,omitempty"` // 0 - повторить все сообщения
+}
+
+// ReplayDLQHandler - возвращает сообщения из <topic>.dlq обратно в исходный топик после исправления ошибки
+func ReplayDLQHandler(log *slog.Logger, kafkaCfg config.Kafka, deadLetters *dlq.Writer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deadLetters == nil {
+			http.Error(w, "Dead-letter topics are disabled", http.StatusServiceUnavailable)
+			return
+		}
+
+		var req ReplayDLQRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Error("ReplayDLQHandler: Invalid request payload", sl.Err(err))
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(consumedTopics, req.Topic) {
+			http.Error(w, "Unknown topic", http.StatusBadRequest)
+			return
+		}
+
+		topic := kafkaCfg.TopicName(req.Topic)
+		replayed, err := deadLetters.Replay(r.Context(), topic, req.Limit)
+		if err != nil {
+			log.Error("ReplayDLQHandler: Replay failed", sl.Err(err),
+				slog.String("topic", topic), slog.Int("replayed", replayed))
+			http.Error(w, "Failed to replay dead-letter messages", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{
+			"topic":     topic,
+			"dlq_topic": deadLetters.Topic(topic),
+			"replayed":  replayed,
+		}); err != nil {
+			log.Error("ReplayDLQHandler: Failed to encode response", sl.Err(err))
+		}
+	}
+}
+
+// ConsumerMetricsHandler - счетчики обработки сообщений по каждому топику
+func ConsumerMetricsHandler(log *slog.Logger, rt *consumer.Runtime) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rt.Metrics()); err != nil {
+			log.Error("ConsumerMetricsHandler: Failed to encode response", sl.Err(err))
+		}
+	}
+}
+
+// ProducerMetricsHandler - счетчики доставки сообщений продюсера и последние недоставленные сообщения
+func ProducerMetricsHandler(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if kafkaProducer == nil {
+			http.Error(w, "Kafka producer is not initialized", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(ProducerMetricsResponse{
+			DeliveryMetrics: kafkaProducer.Metrics(),
+			RecentFailures:  deliveryFailures.recent(),
+		}); err != nil {
+			log.Error("ProducerMetricsHandler: Failed to encode response", sl.Err(err))
+		}
+	}
+}
+
+// ReadinessHandler - готовность сервиса: 200 после загрузки состояния из Kafka, до этого 503
+func ReadinessHandler(log *slog.Logger, loader *bootstrap.Bootstrapper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !loader.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(loader.Status()); err != nil {
+			log.Error("ReadinessHandler: Failed to encode response", sl.Err(err))
+		}
+	}
+}
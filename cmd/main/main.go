@@ -1,18 +1,35 @@
 package main
 
 import (
+	"apiGateway/internal/ballot"
+	"apiGateway/internal/bootstrap"
 	"apiGateway/internal/client"
 	"apiGateway/internal/config"
+	"apiGateway/internal/delegation"
 	"apiGateway/internal/dto"
+	"apiGateway/internal/events"
+	"apiGateway/internal/http-server/chainerr"
+	"apiGateway/internal/http-server/middleware/adminauth"
+	"apiGateway/internal/http-server/middleware/correlation"
 	"apiGateway/internal/http-server/middleware/mwlogger"
 	"apiGateway/internal/http-server/resp"
+	"apiGateway/internal/kafka/broker"
 	"apiGateway/internal/kafka/consumer"
+	"apiGateway/internal/kafka/dlq"
 	"apiGateway/internal/kafka/producer"
+	"apiGateway/internal/kafka/topics"
+	"apiGateway/internal/lib/amount"
+	"apiGateway/internal/lib/ethsig"
 	"apiGateway/internal/lib/logger/handlers/slogpretty"
 	"apiGateway/internal/lib/logger/sl"
 	"apiGateway/internal/models"
+	"apiGateway/internal/refresh"
+	"apiGateway/internal/results"
+	"apiGateway/internal/scheduler"
+	"apiGateway/internal/stakehistory"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -24,29 +41,103 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 )
 
+// shutdownTimeout - сколько ждать завершения текущих HTTP-запросов при остановке
+const shutdownTimeout = 15 * time.Second
+
 var (
 	log            *slog.Logger
 	kafkaProducer  *producer.Producer
 	votingClient   *client.VotingClient
 	stakeClient    *client.StakeClient
+	tokenClient    *client.TokenClient
+	receipts       *client.ReceiptWatcher // Одна подписка на новые блоки для всех ожидающих квитанций
+	stakeHistory   *stakehistory.Indexer  // События стейк-менеджера по адресам
 	votings        = make(map[string]models.VoteSession)
 	userActivities = make(map[string]models.UserActivity)
-	mu             sync.Mutex
+	delegations    = delegation.NewRegistry()
+	mu             sync.RWMutex // Защищает votings и userActivities; консюмеры голосований используют его же
 	err            error
+
+	eventBus        *events.Bus
+	statusScheduler *scheduler.Scheduler
+	refresher       *refresh.Coordinator // Решает, когда читающие эндпоинты запрашивают обновление из Kafka
 )
 
 type ConnectWalletRequest struct {
 	WalletAddress string `json:"walletAddress"`
 }
 
+// StakeResponse - ответ на стейкинг. Суммы отдаются в wei и ETH
+type StakeResponse struct {
+	Message string        `json:"message"`
+	TxHash  string        `json:"tx_hash"`
+	Status  string        `json:"status"`
+	Amount  amount.Amount `json:"amount"`
+	Balance amount.Amount `json:"balance"` // Баланс кошелька после стейкинга, без учета газа
+}
+
+// UnstakeRequest - запрос на вывод стейка, подписанный стейкером через personal_sign.
+// Подписывается текст unstakeMessage, поэтому вывести стейк может только владелец адреса
 type UnstakeRequest struct {
-	StakerAddress string `json:"staker_address"` // Адрес, который хочет вывести ETH
+	StakerAddress string         `json:"staker_address"`   // Адрес, который хочет вывести ETH
+	Amount        *amount.Amount `json:"amount,omitempty"` // Пусто - вывести весь стейк
+	Signature     string         `json:"signature"`        // 0x-подпись unstakeMessage
+	SignedAt      int64          `json:"signed_at"`        // Unix-время подписи, запрос действителен signedRequestMaxAge
+}
+
+// UnstakeResponse - ответ на вывод стейка
+type UnstakeResponse struct {
+	Message string         `json:"message"`
+	TxHash  string         `json:"tx_hash"`
+	Amount  *amount.Amount `json:"amount,omitempty"` // Выведенная сумма, если она известна
+}
+
+// signedRequestMaxAge - сколько действует подпись запроса
+const signedRequestMaxAge = 5 * time.Minute
+
+// signedRequests - уже принятые подписанные запросы; каждая подпись принимается один раз
+var signedRequests = ethsig.NewReplayGuard(signedRequestMaxAge)
+
+// verifySignedRequest проверяет, что message подписан address не раньше signedRequestMaxAge назад
+// и еще не использовался. При ошибке сам отвечает клиенту: 403 - запрос подписан другим адресом,
+// 401 - подпись неверна, просрочена или уже использована
+func verifySignedRequest(w http.ResponseWriter, log *slog.Logger, message, signature string, signedAt int64, address common.Address) bool {
+	if signature == "" {
+		http.Error(w, "signature is required", http.StatusUnauthorized)
+		return false
+	}
+	if _, err := ethsig.Verify(message, signature, time.Unix(signedAt, 0), signedRequestMaxAge, address); err != nil {
+		log.Warn("Request signature rejected", slog.String("address", address.Hex()), sl.Err(err))
+		if errors.Is(err, ethsig.ErrSignerMismatch) {
+			http.Error(w, fmt.Sprintf("address does not match the request signer: %v", err), http.StatusForbidden)
+			return false
+		}
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return false
+	}
+	if err := signedRequests.Use(message, time.Unix(signedAt, 0)); err != nil {
+		log.Warn("Signed request replayed", slog.String("address", address.Hex()), sl.Err(err))
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// unstakeMessage - текст, который стейкер подписывает для вывода стейка
+func unstakeMessage(staker common.Address, value *amount.Amount, signedAt int64) string {
+	amountText := "all"
+	if value != nil {
+		amountText = value.Wei().String()
+	}
+	return fmt.Sprintf("TrustVote unstake\nstaker: %s\namount: %s\nsigned_at: %d",
+		strings.ToLower(staker.Hex()), amountText, signedAt)
 }
 
 const (
@@ -66,41 +157,116 @@ func main() {
 	log.Debug("Debug messages are enabled")
 
 	ctx, cancel := context.WithCancel(context.Background())
-	rwmu := &sync.RWMutex{}
 	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	allVotingConsumer := consumer.NewConsumer(cfg.Kafka, "all-votings-response", votings, log)
-	wg.Add(1)
-	go allVotingConsumer.RunAllVotingsMain(ctx, wg)
+	eventBus = events.NewBus(log)
+	statusScheduler = scheduler.New(log, onVotingTimer)
+
+	// kafka.driver: настоящий кластер или брокер в памяти для тестов и запуска без Kafka
+	kafkaBroker, err := broker.New(cfg.Kafka, log)
+	if err != nil {
+		log.Error("failed to create kafka broker", sl.Err(err))
+		os.Exit(1)
+	}
+	defer func() {
+		if err := kafkaBroker.Close(); err != nil {
+			log.Error("failed to close kafka broker", sl.Err(err))
+		}
+	}()
+
+	// Топики проверяются до запуска консюмеров, чтобы сразу увидеть ошибку в конфиге
+	if err := topics.Ensure(ctx, cfg.Kafka, kafkaBroker, log); err != nil {
+		log.Error("Kafka topics check failed", sl.Err(err))
+	}
+
+	// Сообщения, которые консюмеры не смогли обработать, уходят в <topic>.dlq
+	deadLetters, err := dlq.New(cfg.Kafka, kafkaBroker, log)
+	if err != nil {
+		log.Error("failed to create dead-letter writer", sl.Err(err))
+		os.Exit(1)
+	}
+	defer func() {
+		if err := deadLetters.Close(); err != nil {
+			log.Error("failed to close dead-letter writer", sl.Err(err))
+		}
+	}()
 
-	votingConsumer := consumer.NewConsumer(cfg.Kafka, "voting-response", votings, log)
+	kafkaConsumer := consumer.NewConsumer(votings, log)
+	kafkaConsumer.Mu = &mu
+	kafkaConsumer.Events = eventBus
+	kafkaConsumer.UpdateStatus = UpdateVotingStatusAndWinner
+	kafkaConsumer.Delegations = delegations
+	kafkaConsumer.DelegationChanged = resyncDelegations
+
+	consumerRuntime := consumer.NewRuntime(cfg.Kafka, kafkaBroker, log)
+	consumerRuntime.DLQ = deadLetters
+	kafkaConsumer.Register(consumerRuntime)
+
+	kafkaProducer, err = producer.NewProducer(cfg.Kafka, kafkaBroker, log, deliveryFailures.record)
+	if err != nil {
+		log.Error("failed to create kafka producer", sl.Err(err))
+	}
+	defer kafkaProducer.Close()
+
+	// Состояние восстанавливается из Kafka в фоне, /readyz отвечает 503, пока оно не загружено.
+	// Bootstrapper сам запускает рантайм консюмеров
+	stateLoader := bootstrap.New(cfg.Kafka.Bootstrap, log, consumerRuntime, kafkaConsumer.SnapshotApplied(), func(ctx context.Context) error {
+		if kafkaProducer == nil {
+			return fmt.Errorf("kafka producer is not initialized")
+		}
+		return kafkaProducer.TriggerAllVotingsProduce(ctx)
+	})
 	wg.Add(1)
-	go votingConsumer.RunVotingByIdMain(ctx, wg)
+	go stateLoader.Run(ctx, wg)
 
-	historyConsumer := consumer.NewConsumer(cfg.Kafka, "vote-history-response", votings, log)
+	refresher = refresh.New(cfg.Refresh, log,
+		func(ctx context.Context) error {
+			if kafkaProducer == nil {
+				return fmt.Errorf("kafka producer is not initialized")
+			}
+			return kafkaProducer.TriggerAllVotingsProduce(ctx)
+		},
+		func(ctx context.Context, votingID string) error {
+			if kafkaProducer == nil {
+				return fmt.Errorf("kafka producer is not initialized")
+			}
+			return kafkaProducer.VotingRequestProduce(ctx, votingID)
+		})
 	wg.Add(1)
-	go historyConsumer.RunVoteHistoryConsumer(ctx, wg)
+	go refresher.Run(ctx, wg, eventBus)
 
-	kafkaProducer, err = producer.NewProducer(cfg.Kafka, log)
+	receipts, err = client.NewReceiptWatcher(cfg, log)
 	if err != nil {
-		log.Error("failed to create kafka producer", err)
+		log.Error("Failed to create receipt watcher", sl.Err(err))
+		os.Exit(1)
 	}
-	defer kafkaProducer.Close()
 
 	votingClient, err = client.NewVotingClient(cfg, log)
 	if err != nil {
-		log.Error("Failed to create voting client: %v", err)
+		log.Error("Failed to create voting client", sl.Err(err))
+	} else {
+		votingClient.Receipts = receipts
 	}
 
 	stakeClient, err = client.NewStakeClient(cfg, log)
 	if err != nil {
-		log.Error("Failed to create stake client: %v", err)
+		log.Error("Failed to create stake client", sl.Err(err))
 		os.Exit(1)
 	}
+
+	// История стейков, выводов и клеймов индексируется из логов стейк-менеджера в фоне
+	stakeHistory = stakehistory.New(cfg.Blockchain, stakeClient, log)
+	wg.Add(1)
+	go stakeHistory.Run(ctx, wg)
+
+	tokenClient, err = client.NewTokenClient(cfg, log)
+	if err != nil {
+		log.Error("Failed to create token client", sl.Err(err))
+	}
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID)
+	router.Use(correlation.New())
 	router.Use(mwlogger.New(log))
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
@@ -115,13 +281,32 @@ func main() {
 
 	router.Get("/voting/{id}", GetVotingByID)
 	router.Get("/voting", GetAllVotings)
-	router.Post("/user-data", GetUserData(log, historyConsumer, kafkaProducer, userActivities, votings, rwmu))
+	router.Post("/user-data", GetUserData(log, kafkaConsumer, kafkaProducer, userActivities, votings, &mu, stakeHistory))
 	router.Post("/vote", SubmitVote)
+	router.Post("/vote/revoke", RevokeVoteHandler)
+	router.Post("/vote/commit", CommitVoteHandler)
+	router.Post("/vote/reveal", RevealVoteHandler)
+	router.Post("/delegation", DelegateHandler)
+	router.Post("/delegation/revoke", RevokeDelegationHandler)
+	router.Get("/delegation/{address}", GetDelegationsHandler)
 	router.Post("/connect-wallet", ConnectWalletHandler)
 	router.Post("/voting", CreateVotingHandler)
+	router.Post("/voting/{id}/decide", DecideTieHandler)
 	router.Post("/staking", StakeHandler(log, stakeClient))
+	router.Get("/staking/projection", GetStakingProjectionHandler(log, stakeClient))
+	router.Get("/staking/{address}", GetStakingPositionHandler(log, stakeClient))
+	router.Get("/staking/{address}/history", GetStakingHistoryHandler(log, stakeHistory))
+	router.Get("/token", GetTokenInfoHandler(log, tokenClient))
+	router.Get("/token/balance/{address}", GetTokenBalanceHandler(log, tokenClient))
 	router.Post("/unstake", UnstakeHandler(log, stakeClient))
 	router.Post("/get_tokens", GetTokensHandler(log, stakeClient))
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(adminauth.New(log, cfg.HTTPServer.AdminToken))
+		r.Post("/dlq/replay", ReplayDLQHandler(log, cfg.Kafka, deadLetters))
+		r.Get("/consumers", ConsumerMetricsHandler(log, consumerRuntime))
+		r.Get("/producer", ProducerMetricsHandler(log))
+	})
+	router.Get("/readyz", ReadinessHandler(log, stateLoader))
 
 	log.Info("starting server", slog.String("address", cfg.HTTPServer.Address))
 
@@ -133,21 +318,10 @@ func main() {
 		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
 	}
 
-	// Горутина для регулярного обновления статусов голосований
-	go func() {
-		defer wg.Done()
-		ticker := time.NewTicker(10 * time.Second) // Проверяем каждые 10 секунд
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				UpdateAllVotingStatuses()
-			case <-ctx.Done(): // Используем ctx для graceful shutdown
-				log.Info("Voting status update goroutine stopped")
-				return
-			}
-		}
-	}()
+	// Планировщик переводит голосования в новый статус точно в момент начала/окончания
+	// и публикует voting-status-changed
+	wg.Add(1)
+	go runStatusEvents(ctx, wg)
 
 	/*address := cfg.Blockchain.WalletAddress
 
@@ -167,20 +341,37 @@ func main() {
 		}
 	}*/
 
-	if err := srv.ListenAndServe(); err != nil {
-		log.Error("failed to start server", sl.Err(err))
-	}
-
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT, os.Interrupt)
 
-	sign := <-stop
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case sign := <-stop:
+		log.Info("application stopping", slog.String("signal", sign.String()))
+	case err := <-serverErr:
+		log.Error("failed to start server", sl.Err(err))
+	}
+
+	// Сначала перестаем принимать запросы, чтобы обработчики больше ничего не отправляли в Kafka
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer shutdownCancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error("failed to shutdown server", sl.Err(err))
+	}
 
-	log.Info("application stopping", slog.String("signal", sign.String()))
 	cancel()
 	wg.Wait()
 	log.Info("Kafka consumers stopped.")
 
+	// В режиме async продюсер держит неотправленные пачки: Close дожидается их доставки
+	kafkaProducer.Close()
+
 	log.Info("application stopped")
 }
 
@@ -254,14 +445,18 @@ func ConnectWalletHandler(w http.ResponseWriter, r *http.Request) {
 // CreateVotingHandler - обработчик HTTP для создания голосования
 func CreateVotingHandler(w http.ResponseWriter, r *http.Request) {
 	var requestPayload struct {
-		Title          string   `json:"title"`
-		Description    string   `json:"description"`
-		IsPrivate      bool     `json:"is_private"`
-		MinNumberVotes int64    `json:"min_votes"`
-		StartTime      string   `json:"start_date"`
-		EndTime        string   `json:"end_date"`
-		Choices        []string `json:"options"`
-		CreatorAddress string   `json:"creator_address"`
+		Title          string             `json:"title"`
+		Description    string             `json:"description"`
+		IsPrivate      bool               `json:"is_private"`
+		MinNumberVotes int64              `json:"min_votes"`
+		StartTime      string             `json:"start_date"`
+		EndTime        string             `json:"end_date"`
+		Choices        []string           `json:"options"`
+		CreatorAddress string             `json:"creator_address"`
+		Rules          models.ResultRules `json:"rules"`
+		AllowChange    bool               `json:"allow_vote_change"`
+		BallotMode     string             `json:"ballot_mode"`
+		RevealEndTime  string             `json:"reveal_end_date"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&requestPayload)
@@ -271,6 +466,22 @@ func CreateVotingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := results.Validate(requestPayload.Rules); err != nil {
+		log.Warn("Invalid result rules in create voting request", sl.Err(err))
+		http.Error(w, fmt.Sprintf("Invalid rules: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	rules := results.Normalize(requestPayload.Rules)
+
+	ballotMode := requestPayload.BallotMode
+	if ballotMode == "" {
+		ballotMode = models.BallotModeOpen
+	}
+	if ballotMode != models.BallotModeOpen && ballotMode != models.BallotModeCommitReveal {
+		http.Error(w, fmt.Sprintf("Unknown ballot mode: %s", ballotMode), http.StatusBadRequest)
+		return
+	}
+
 	voters := []client.Voter{
 		{Addr: votingClient.FromAddress, HasVoted: false, Choice: "", CanVote: client.VoteAccessHasAccess},
 		{Addr: common.HexToAddress("0x70997970C12345dc3A0108C7934CDCc3FbF7b2cc"), HasVoted: false, Choice: "", CanVote: client.VoteAccessHasAccess},
@@ -284,9 +495,37 @@ func CreateVotingHandler(w http.ResponseWriter, r *http.Request) {
 	endTimeMs := tEnd.UnixNano() / int64(time.Second)
 	endTime := big.NewInt(endTimeMs)
 
+	// Фаза раскрытия commit-reveal по умолчанию длится сутки после окончания голосования
+	var tRevealEnd time.Time
+	if ballotMode == models.BallotModeCommitReveal {
+		tRevealEnd = tEnd.Add(defaultRevealDuration)
+		if requestPayload.RevealEndTime != "" {
+			tRevealEnd, err = time.Parse(time.RFC3339, requestPayload.RevealEndTime)
+			if err != nil || !tRevealEnd.After(tEnd) {
+				http.Error(w, "reveal_end_date must be an RFC3339 time after end_date", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
 	minVotes := new(big.Int)
 	minVotes.SetInt64(requestPayload.MinNumberVotes)
 
+	if isDryRun(r) {
+		sim, err := votingClient.SimulateAddVoteSession(r.Context(),
+			requestPayload.Title,
+			requestPayload.Description,
+			startTime,
+			endTime,
+			minVotes,
+			requestPayload.IsPrivate,
+			voters,
+			requestPayload.Choices,
+		)
+		writeSimulation(w, r, log, sim, err, "Voting would be rejected by voting contract")
+		return
+	}
+
 	bigVotingID, _, txHash, err := votingClient.AddVoteSession(
 		requestPayload.Title,
 		requestPayload.Description,
@@ -298,10 +537,10 @@ func CreateVotingHandler(w http.ResponseWriter, r *http.Request) {
 		requestPayload.Choices,
 	)
 	if err != nil {
-		log.Error("Failed to add vote session to blockchain", sl.Err(err))
-	} else {
-		log.Info("Vote session added to blockchain successfully", slog.String("tx_hash", txHash.Hex()))
+		chainerr.Write(w, r, log, err, "Failed to create voting on blockchain")
+		return
 	}
+	log.Info("Vote session added to blockchain successfully", slog.String("tx_hash", txHash.Hex()))
 
 	votingID := bigVotingID.String()
 
@@ -330,8 +569,55 @@ func CreateVotingHandler(w http.ResponseWriter, r *http.Request) {
 		EndDate:     requestPayload.EndTime,
 		StartDate:   requestPayload.StartTime,
 		Options:     optionsForKafka,
+		ResultRules: &dto.ResultRules{
+			QuorumType:     rules.QuorumType,
+			Quorum:         rules.Quorum,
+			ThresholdType:  rules.ThresholdType,
+			ThresholdBase:  rules.ThresholdBase,
+			TieBreak:       rules.TieBreak,
+			EligibleVoters: rules.EligibleVoters,
+		},
+		AllowVoteChange: requestPayload.AllowChange,
+		BallotMode:      ballotMode,
+	}
+	if !tRevealEnd.IsZero() {
+		votingEvent.RevealEndDate = tRevealEnd.Format(time.RFC3339)
 	}
 
+	// Сохраняем голосование локально, чтобы правила подсчета не потерялись
+	// до ответа Java-сервиса (voting-response не содержит правил)
+	choices := make([]models.Choice, len(requestPayload.Choices))
+	for i, choiceText := range requestPayload.Choices {
+		choices[i] = models.Choice{Title: choiceText}
+	}
+	sessionVoters := make(map[string]models.Voter, len(voters))
+	for _, v := range voters {
+		addr := strings.ToLower(v.Addr.Hex())
+		sessionVoters[addr] = models.Voter{Address: v.Addr.Hex(), Choice: -1, CanVote: v.CanVote == client.VoteAccessHasAccess}
+	}
+	mu.Lock()
+	votings[votingID] = models.VoteSession{
+		ID:              votingID,
+		CreatorAddr:     requestPayload.CreatorAddress,
+		Title:           requestPayload.Title,
+		Description:     requestPayload.Description,
+		StartTime:       tStart,
+		EndTime:         tEnd,
+		MinNumberVotes:  requestPayload.MinNumberVotes,
+		IsPrivate:       requestPayload.IsPrivate,
+		Choices:         choices,
+		Voters:          sessionVoters,
+		Winner:          []string{},
+		Rules:           rules,
+		AllowVoteChange: requestPayload.AllowChange,
+		BallotMode:      ballotMode,
+		RevealEndTime:   tRevealEnd,
+		Commitments:     make(map[string]models.Commitment),
+	}
+	UpdateVotingStatusAndWinner(votingID)
+	mu.Unlock()
+	eventBus.Publish(events.Event{Type: events.VotingUpdated, VotingID: votingID})
+
 	// Отправка сообщения в Kafka
 	err = kafkaProducer.VotingCreateProduce(r.Context(), votingEvent)
 	if err != nil {
@@ -354,6 +640,65 @@ func CreateVotingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// DecideTieHandler - создатель голосования выбирает победителя при ничьей (tie_break = creator_decides)
+func DecideTieHandler(w http.ResponseWriter, r *http.Request) {
+	votingID := chi.URLParam(r, "id")
+
+	var req struct {
+		CreatorAddress string `json:"creator_address"`
+		OptionIndex    int    `json:"option_index"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("Failed to decode tie decision request", sl.Err(err))
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	UpdateVotingStatusAndWinner(votingID)
+	voting, ok := votings[votingID]
+	if !ok {
+		http.Error(w, "VoteSession not found", http.StatusNotFound)
+		return
+	}
+
+	if !strings.EqualFold(voting.CreatorAddr, req.CreatorAddress) {
+		log.Warn("DecideTieHandler: caller is not the creator",
+			slog.String("voting_id", votingID),
+			slog.String("creator_address", req.CreatorAddress))
+		http.Error(w, "Only the creator can decide the tie", http.StatusForbidden)
+		return
+	}
+
+	if voting.Status != models.StatusAwaitingDecision {
+		http.Error(w, "VoteSession is not waiting for a creator decision", http.StatusConflict)
+		return
+	}
+
+	if !results.IsTied(voting, req.OptionIndex) {
+		http.Error(w, "Selected option is not one of the tied options", http.StatusBadRequest)
+		return
+	}
+
+	decision := req.OptionIndex
+	voting.TieDecision = &decision
+	votings[votingID] = voting
+	UpdateVotingStatusAndWinner(votingID)
+	voting = votings[votingID]
+
+	log.Info("Tie decided by creator",
+		slog.String("voting_id", votingID),
+		slog.Int("option_index", decision),
+		slog.Any("winner", voting.Winner))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ballot.PublicView(voting)); err != nil {
+		log.Error("Failed to encode response for DecideTieHandler", sl.Err(err))
+	}
+}
+
 // StakeHandler - обрабатывает запрос на стейкинг
 func StakeHandler(
 	log *slog.Logger,
@@ -361,43 +706,46 @@ func StakeHandler(
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var requestPayload struct {
-			Amount        float64 `json:"amount"`         // Сумма в ETH (например, 0.1)
-			StakerAddress string  `json:"staker_address"` // Адрес стейкера
+			Amount        amount.Amount `json:"amount"`         // "0.1", "1.5 gwei", "100 wei" или число в ETH
+			StakerAddress string        `json:"staker_address"` // Адрес стейкера
 		}
 
 		err := json.NewDecoder(r.Body).Decode(&requestPayload)
 		if err != nil {
 			log.Error("Failed to decode stake request", sl.Err(err))
-			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf("Invalid request payload: %v", err), http.StatusBadRequest)
 			return
 		}
 
-		// --- Преобразуем ETH в Wei ---
-		// 1 ETH = 10^18 Wei
-		amountInWei := big.NewInt(int64(requestPayload.Amount * 1e18)) // Осторожно: это может потерять точность для нецелых ETH
-		// Лучше использовать пакет decimal или функцию ethutil.ParseEther
-		// Для простоты, пока будем так, но в продакшене это неточно
-		// Пример более точного преобразования:
-		// amountFloat := big.NewFloat(requestPayload.Amount)
-		// ethToWei := new(big.Float).Mul(amountFloat, big.NewFloat(1e18))
-		// amountInWei, _ := ethToWei.Int(nil) // Преобразуем в big.Int
-
-		if amountInWei.Cmp(big.NewInt(0)) <= 0 {
-			log.Error("Stake amount must be greater than zero", slog.Float64("amount", requestPayload.Amount))
+		stakeAmount := requestPayload.Amount
+		if stakeAmount.Sign() <= 0 {
+			log.Error("Stake amount must be greater than zero", slog.String("amount_wei", stakeAmount.Wei().String()))
 			http.Error(w, "Stake amount must be greater than zero", http.StatusBadRequest)
 			return
 		}
+		amountInWei := stakeAmount.Wei()
 
 		log.Info("Received request to stake ETH",
-			slog.Float64("amount_eth", requestPayload.Amount),
+			slog.String("amount_eth", stakeAmount.Ether()),
 			slog.String("staker_address", requestPayload.StakerAddress),
-			slog.Any("amount_wei", amountInWei))
+			slog.String("amount_wei", amountInWei.String()))
+
+		balance, err := stakeClient.CheckStakeBalance(r.Context(), stakeAmount)
+		if err != nil {
+			chainerr.Write(w, r, log, err, "Failed to check balance before stake")
+			return
+		}
+
+		if isDryRun(r) {
+			sim, err := stakeClient.SimulateStake(r.Context(), amountInWei)
+			writeSimulation(w, r, log, sim, err, "Stake would be rejected by stake manager")
+			return
+		}
 
 		// --- Вызов метода Stake на блокчейне ---
 		txHash, err := stakeClient.Stake(amountInWei)
 		if err != nil {
-			log.Error("Failed to send Stake transaction to blockchain", sl.Err(err))
-			http.Error(w, fmt.Sprintf("Failed to stake ETH on blockchain: %v", err), http.StatusInternalServerError)
+			chainerr.Write(w, r, log, err, "Failed to stake ETH on blockchain")
 			return
 		}
 
@@ -405,10 +753,9 @@ func StakeHandler(
 
 		// --- Ожидание подтверждения транзакции ---
 		fmt.Println("Waiting for blockchain transaction to be mined for staking...")
-		receipt, err := waitForTransactionReceipt(r.Context(), stakeClient, txHash, log) // Используем вспомогательную функцию
+		receipt, err := receipts.Wait(r.Context(), txHash)
 		if err != nil {
-			log.Error("Failed to get transaction receipt or stake transaction failed", sl.Err(err), slog.String("tx_hash", txHash.Hex()))
-			http.Error(w, fmt.Sprintf("Blockchain stake transaction failed or timed out: %v", err), http.StatusInternalServerError)
+			chainerr.Write(w, r, log.With(slog.String("tx_hash", txHash.Hex())), err, "Blockchain stake transaction failed or timed out")
 			return
 		}
 
@@ -421,10 +768,12 @@ func StakeHandler(
 		log.Info("Blockchain stake transaction successfully mined and executed!", slog.String("tx_hash", txHash.Hex()))
 
 		// --- Возвращаем ответ фронтенду ---
-		responseBody := map[string]string{
-			"message": "ETH staked successfully!",
-			"tx_hash": txHash.Hex(),
-			"status":  "success",
+		responseBody := StakeResponse{
+			Message: "ETH staked successfully!",
+			TxHash:  txHash.Hex(),
+			Status:  "success",
+			Amount:  stakeAmount,
+			Balance: amount.FromWei(new(big.Int).Sub(balance.Wei(), amountInWei)),
 		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -443,34 +792,56 @@ func UnstakeHandler(log *slog.Logger, sc *client.StakeClient) http.HandlerFunc {
 			http.Error(w, "Service is not properly initialized.", http.StatusInternalServerError)
 			return
 		}
-		log.Info("Received request to unstake ETH")
 
 		var req UnstakeRequest
 		err := json.NewDecoder(r.Body).Decode(&req)
 		if err != nil {
 			log.Error("Failed to decode unstake request", sl.Err(err))
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
 			return
 		}
+		if !common.IsHexAddress(req.StakerAddress) {
+			http.Error(w, "Invalid staker_address", http.StatusBadRequest)
+			return
+		}
+		staker := common.HexToAddress(req.StakerAddress)
 
-		// Логируем полученные данные (для отладки)
 		log.Info("Received request to unstake ETH",
 			slog.String("staker_address", req.StakerAddress),
-		)
+			slog.Bool("partial", req.Amount != nil))
 
-		// Важно: Адрес, который стейкает, берется из приватного ключа сервиса
-		// (который в config.yaml), а не из req.StakerAddress.
-		// req.StakerAddress используется только для логирования или потенциальных проверок.
-		// Фактическая транзакция будет подписана приватным ключом, настроенным в VotingClient.
+		// Запрос должен быть подписан самим стейкером
+		if !verifySignedRequest(w, log, unstakeMessage(staker, req.Amount, req.SignedAt), req.Signature, req.SignedAt, staker) {
+			return
+		}
+
+		// Транзакцию подписывает ключ шлюза, а контракт выводит стейк msg.sender,
+		// поэтому шлюз может вывести только собственный стейк
+		if staker != sc.FromAddress {
+			http.Error(w, fmt.Sprintf("staker_address %s does not match the transaction signer %s: the gateway can only unstake its own stake",
+				strings.ToLower(staker.Hex()), strings.ToLower(sc.FromAddress.Hex())), http.StatusForbidden)
+			return
+		}
 
 		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second) // Увеличиваем таймаут
 		defer cancel()
 
-		// Вызываем функцию Unstake из VotingClient
-		txHash, err := sc.Unstake() // Функция Unstake не принимает аргументов
+		value, unstaked, status, err := unstakeValue(ctx, sc, staker, req.Amount)
+		if err != nil {
+			log.Warn("Unstake request rejected", slog.String("staker_address", req.StakerAddress), sl.Err(err))
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		if isDryRun(r) {
+			sim, err := sc.SimulateUnstake(ctx, value)
+			writeSimulation(w, r, log, sim, err, "Unstake would be rejected by stake manager")
+			return
+		}
+
+		txHash, err := sc.Unstake(value)
 		if err != nil {
-			log.Error("Failed to send Unstake transaction to blockchain", sl.Err(err))
-			http.Error(w, fmt.Sprintf("Failed to unstake ETH: %s", err.Error()), http.StatusInternalServerError)
+			chainerr.Write(w, r, log, err, "Failed to unstake ETH")
 			return
 		}
 
@@ -478,10 +849,9 @@ func UnstakeHandler(log *slog.Logger, sc *client.StakeClient) http.HandlerFunc {
 
 		// Ожидаем подтверждения транзакции
 		log.Info("Waiting for blockchain transaction to be mined for unstaking...")
-		receipt, err := waitForTransactionReceipt(ctx, sc, txHash, log) // Используем вспомогательную функцию
+		receipt, err := receipts.Wait(ctx, txHash)
 		if err != nil {
-			log.Error("Failed to get transaction receipt for unstake", sl.Err(err), slog.String("tx_hash", txHash.Hex()))
-			http.Error(w, fmt.Sprintf("Transaction for unstake failed or timed out: %s", err.Error()), http.StatusInternalServerError)
+			chainerr.Write(w, r, log.With(slog.String("tx_hash", txHash.Hex())), err, "Transaction for unstake failed or timed out")
 			return
 		}
 
@@ -494,33 +864,91 @@ func UnstakeHandler(log *slog.Logger, sc *client.StakeClient) http.HandlerFunc {
 		log.Info("Unstake transaction successfully mined", slog.String("tx_hash", txHash.Hex()))
 
 		w.Header().Set("Content-Type", "application/json")
-		err = json.NewEncoder(w).Encode(map[string]string{
-			"message": "Unstake transaction sent and mined successfully",
-			"tx_hash": txHash.Hex(),
+		err = json.NewEncoder(w).Encode(UnstakeResponse{
+			Message: "Unstake transaction sent and mined successfully",
+			TxHash:  txHash.Hex(),
+			Amount:  unstaked,
 		})
 		if err != nil {
-			return
+			log.Error("Failed to encode response body for UnstakeHandler", sl.Err(err))
 		}
 	}
 }
 
-func waitForTransactionReceipt(ctx context.Context, sc *client.StakeClient, txHash common.Hash, log *slog.Logger) (*types.Receipt, error) {
-	const maxRetries = 60                 // Попробуем до 60 раз
-	const retryInterval = 1 * time.Second // Каждую секунду
+// unstakeValue выбирает аргумент для StakeClient.Unstake по запрошенной сумме и возможностям контракта.
+// Возвращает сумму для контракта (nil - весь стейк), выводимую сумму для ответа и HTTP-статус ошибки
+func unstakeValue(ctx context.Context, sc *client.StakeClient, staker common.Address, requested *amount.Amount) (*big.Int, *amount.Amount, int, error) {
+	var staked *amount.Amount
+	if position, err := sc.StakePosition(ctx, staker); err != nil {
+		log.Warn("Failed to read staked amount before unstake", slog.String("staker_address", staker.Hex()), sl.Err(err))
+	} else {
+		staked = position.Staked
+	}
+	if staked != nil && staked.Sign() == 0 {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("nothing staked for %s", strings.ToLower(staker.Hex()))
+	}
 
-	for i := 0; i < maxRetries; i++ {
-		receipt, err := sc.Client.TransactionReceipt(ctx, txHash)
-		if err != nil {
-			if err.Error() == "not found" {
-				log.Debug("Transaction receipt not yet available. Retrying...", slog.String("tx_hash", txHash.Hex()))
-				time.Sleep(retryInterval)
-				continue
-			}
-			return nil, fmt.Errorf("failed to get transaction receipt for %s: %w", txHash.Hex(), err)
+	if requested == nil {
+		if !sc.SupportsPartialUnstake() {
+			return nil, staked, 0, nil
+		}
+		if staked == nil {
+			return nil, nil, http.StatusBadRequest, fmt.Errorf("amount is required: staked amount cannot be read from the contract")
+		}
+		return staked.Wei(), staked, 0, nil
+	}
+
+	if requested.Sign() <= 0 {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("unstake amount must be greater than zero")
+	}
+	if staked != nil && requested.Cmp(*staked) > 0 {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("unstake amount %s exceeds staked %s", requested, staked)
+	}
+	if !sc.SupportsPartialUnstake() {
+		// Сумма, равная всему стейку, - это обычный полный вывод
+		if staked != nil && requested.Cmp(*staked) == 0 {
+			return nil, requested, 0, nil
 		}
-		return receipt, nil // Квитанция получена
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("%w: omit amount to withdraw the full stake", client.ErrPartialUnstakeUnsupported)
+	}
+	return requested.Wei(), requested, 0, nil
+}
+
+// checkOpenVote проверяет, что открытый голос можно принять: голосование существует и не использует
+// commit-reveal, идет сейчас, вариант допустим и пользователь еще не голосовал.
+// Возвращает HTTP-статус для ответа при ошибке. Должен вызываться под мьютексом
+func checkOpenVote(req models.VoteRequest) (int, error) {
+	voting, ok := votings[req.VotingID]
+	if !ok {
+		return http.StatusNotFound, fmt.Errorf("VoteSession not found")
+	}
+
+	// В режиме commit-reveal открытый голос раскрыл бы выбор до окончания голосования
+	if voting.IsCommitReveal() {
+		return http.StatusConflict, fmt.Errorf("This poll uses secret ballots, submit a commitment to /vote/commit")
+	}
+
+	now := time.Now()
+	if now.Before(voting.StartTime) {
+		return http.StatusForbidden, fmt.Errorf("VoteSession has not started yet")
+	}
+	if now.After(voting.EndTime) {
+		return http.StatusForbidden, fmt.Errorf("VoteSession has already ended")
+	}
+
+	if req.SelectedOptionIndex < 0 || req.SelectedOptionIndex >= len(voting.Choices) {
+		return http.StatusBadRequest, fmt.Errorf("Invalid option selected")
+	}
+
+	// Проверяем, голосовал ли пользователь уже: через Voters и через UserActivity (для обратной совместимости)
+	userAddressLower := strings.ToLower(req.UserAddress)
+	if voter, exists := voting.Voters[userAddressLower]; exists && voter.IsVoted {
+		return http.StatusConflict, fmt.Errorf("You have already voted in this poll")
+	}
+	if _, alreadyVoted := userActivities[userAddressLower].ParticipatedVotings[req.VotingID]; alreadyVoted {
+		return http.StatusConflict, fmt.Errorf("You have already voted in this poll")
 	}
-	return nil, fmt.Errorf("timed out waiting for transaction receipt for tx_hash: %s", txHash.Hex())
+	return http.StatusOK, nil
 }
 
 // SubmitVote - хендлер для обработки голосования пользователя
@@ -534,6 +962,29 @@ func SubmitVote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Повторный голос в голосовании с разрешенным изменением (и голос после отзыва) - меняем выбор вместо ошибки 409.
+	// Контракт не поддерживает переголосование и отзыв, поэтому транзакция в этой ветке не отправляется:
+	// в контракте остается первый голос пользователя.
+	// dry_run проверяет только вызов контракта
+	dryRun := isDryRun(r)
+	if !dryRun && hasChangeableVote(req.VotingID, req.UserAddress) {
+		changeVote(w, r, req)
+		return
+	}
+
+	// Все проверки выполняются до обращения к контракту: иначе, например, открытый голос
+	// в голосовании commit-reveal раскрыл бы выбор в блокчейне до окончания голосования
+	mu.RLock()
+	status, err := checkOpenVote(req)
+	mu.RUnlock()
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		slog.Warn("SubmitVote: Vote rejected", sl.Err(err),
+			slog.String("voting_id", req.VotingID),
+			slog.String("user_address", req.UserAddress))
+		return
+	}
+
 	voteSessionID, ok := new(big.Int).SetString(req.VotingID, 10)
 	if !ok {
 		log.Error("Invalid vote_session_id format", slog.String("vote_session_id", req.VotingID))
@@ -542,11 +993,22 @@ func SubmitVote(w http.ResponseWriter, r *http.Request) {
 	}
 	choiceIndex := big.NewInt(int64(req.SelectedOptionIndex))
 
+	if dryRun {
+		sim, err := votingClient.SimulateVote(r.Context(), voteSessionID, choiceIndex)
+		writeSimulation(w, r, log, sim, err, "Vote would be rejected by voting contract")
+		return
+	}
+
 	txHash, err := votingClient.Vote(
 		voteSessionID,
 		choiceIndex,
 	)
 	if err != nil {
+		// Голос, отклоненный контрактом, не принимаем; при недоступности узла голос, как и раньше, учитывается локально
+		if chainerr.IsRevert(err) {
+			chainerr.Write(w, r, log, err, "Vote rejected by voting contract")
+			return
+		}
 		log.Error("Failed to add vote option to blockchain", sl.Err(err))
 	} else {
 		log.Info("Vote option added to blockchain successfully", slog.String("tx_hash", txHash.Hex()))
@@ -555,74 +1017,19 @@ func SubmitVote(w http.ResponseWriter, r *http.Request) {
 	mu.Lock() // Блокируем доступ к общим данным
 	defer mu.Unlock()
 
-	voting, ok := votings[req.VotingID]
-	if !ok {
-		http.Error(w, "VoteSession not found", http.StatusNotFound)
-		slog.Error("SubmitVote: VoteSession not found", slog.String("voting_id", req.VotingID))
-		return
-	}
-
-	// Проверяем, началось ли голосование
-	startDate := voting.StartTime
-	if time.Now().Before(startDate) {
-		http.Error(w, "VoteSession has not started yet", http.StatusForbidden)
-		slog.Warn("SubmitVote: VoteSession has not started", slog.String("voting_id", req.VotingID))
-		return
-	}
-
-	// Проверяем, закончилось ли голосование
-	endDate := voting.EndTime
-	if time.Now().After(endDate) {
-		http.Error(w, "VoteSession has already ended", http.StatusForbidden)
-		slog.Warn("SubmitVote: VoteSession has ended", slog.String("voting_id", req.VotingID))
-		return
-	}
-
-	// Проверяем валидность выбранной опции
-	if req.SelectedOptionIndex < 0 || req.SelectedOptionIndex >= len(voting.Choices) {
-		http.Error(w, "Invalid option selected", http.StatusBadRequest)
-		slog.Warn("SubmitVote: Invalid option index", slog.Int("option_index", req.SelectedOptionIndex), slog.String("voting_id", req.VotingID))
-		return
-	}
-
-	userAddressLower := strings.ToLower(req.UserAddress)
-
-	// Проверяем, голосовал ли пользователь уже (через Voters)
-	if voter, exists := voting.Voters[userAddressLower]; exists && voter.IsVoted {
-		http.Error(w, "You have already voted in this poll", http.StatusConflict) // 409 Conflict
-		slog.Warn("SubmitVote: User already voted via Voters map", slog.String("user_address", req.UserAddress), slog.String("voting_id", req.VotingID))
-		return
-	}
-
-	// Проверяем, голосовал ли пользователь уже (через UserActivity - для обратной совместимости или если нужно учитывать централизованно)
-	activity := userActivities[userAddressLower]
-	if activity.ParticipatedVotings == nil {
-		activity.ParticipatedVotings = make(map[string]int)
-	}
-	if _, alreadyVoted := activity.ParticipatedVotings[req.VotingID]; alreadyVoted {
-		http.Error(w, "You have already voted in this poll", http.StatusConflict) // 409 Conflict
-		slog.Warn("SubmitVote: User already voted via UserActivity map", slog.String("user_address", req.UserAddress), slog.String("voting_id", req.VotingID))
+	// Пока отправлялась транзакция, состояние могло измениться (например, параллельный голос того же адреса)
+	if status, err := checkOpenVote(req); err != nil {
+		http.Error(w, err.Error(), status)
+		slog.Warn("SubmitVote: Vote rejected after sending transaction", sl.Err(err),
+			slog.String("voting_id", req.VotingID),
+			slog.String("user_address", req.UserAddress))
 		return
 	}
+	voting := votings[req.VotingID]
 
 	// --- ЛОГИКА ОБНОВЛЕНИЯ СОСТОЯНИЯ В ПАМЯТИ (ИЗ СТАРОГО КОДА) ---
-	// Регистрируем голос в UserActivity
-	activity.ParticipatedVotings[req.VotingID] = req.SelectedOptionIndex
-	userActivities[userAddressLower] = activity
-
-	// Регистрируем голос в VoteSession.Voters
-	voting.Voters[userAddressLower] = models.Voter{
-		Address: req.UserAddress,
-		IsVoted: true,
-		Choice:  req.SelectedOptionIndex,
-		CanVote: true, // Это поле здесь не играет роли, но сохраним для структуры
-	}
-
-	// Увеличиваем счетчик голосов для выбранной опции
-	voting.Choices[req.SelectedOptionIndex].CountVotes++
-
-	// Увеличиваем общий счетчик голосов для голосования
-	voting.TempNumberVotes++
+	// Регистрируем голос в UserActivity, VoteSession.Voters и счетчиках с учетом делегированного веса
+	weight, represented := recordVote(&voting, req.UserAddress, req.SelectedOptionIndex)
 	votings[req.VotingID] = voting // Убедитесь, что голосование сохраняется обратно в map
 
 	// Обновляем статус голосования сразу после голосования (опционально, но полезно)
@@ -645,9 +1052,11 @@ func SubmitVote(w http.ResponseWriter, r *http.Request) {
 
 	// Подготовка данных для Kafka в точном формате dto.VoteCast
 	voteEvent := dto.VoteCast{
-		VotingID: req.VotingID,
-		VoterID:  req.UserAddress,
-		OptionID: optionIDToKafka, // Используем полученный/сгенерированный ID
+		VotingID:   req.VotingID,
+		VoterID:    req.UserAddress,
+		OptionID:   optionIDToKafka, // Используем полученный/сгенерированный ID
+		Weight:     weight,
+		Delegators: represented,
 	}
 
 	// Отправка сообщения в Kafka
@@ -688,22 +1097,10 @@ func GetVotingByID(w http.ResponseWriter, r *http.Request) {
 
 	log.Info("Accessed GetVotingByID endpoint", slog.String("voting_id", votingID))
 
-	// --- НОВОЕ: Отправка запроса деталей голосования в Kafka ---
-	err := kafkaProducer.VotingRequestProduce(r.Context(), votingID)
-	if err != nil {
-		log.Error("Failed to send voting details request to Kafka", sl.Err(err), slog.String("voting_id", votingID))
-		// Можно не возвращать ошибку фронтенду, так как это не критично для отображения голосования.
-		// Просто логируем и продолжаем.
-	} else {
-		log.Info("Voting details request event sent to Kafka", slog.String("voting_id", votingID))
-	}
-	// --- КОНЕЦ НОВОГО БЛОКА ---
-
 	mu.Lock()
-	defer mu.Unlock()
-
 	_, ok := votings[votingID]
 	if !ok {
+		mu.Unlock()
 		http.Error(w, "VoteSession not found", http.StatusNotFound)
 		slog.Warn("GetVotingByID: VoteSession not found", slog.String("voting_id", votingID))
 		return
@@ -711,10 +1108,17 @@ func GetVotingByID(w http.ResponseWriter, r *http.Request) {
 
 	// Обновляем статус голосования перед отправкой
 	UpdateVotingStatusAndWinner(votingID)
-	updatedVoting := votings[votingID] // Получаем обновленную версию
+	updatedVoting := ballot.PublicView(votings[votingID]) // Получаем обновленную версию без скрытых данных
+	mu.Unlock()
+
+	// Отвечаем из кэша; запрос деталей в Kafka уходит в фоне, только если данные устарели.
+	// Только для известных голосований: иначе произвольные ID порождали бы ключи и запросы в Kafka,
+	// а новые голосования и так приходят со снимком всех голосований
+	refresher.Touch(votingID)
 
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(updatedVoting)
+	setDataAge(w, votingID)
+	err := json.NewEncoder(w).Encode(updatedVoting)
 	if err != nil {
 		slog.Error("Failed to encode response for GetVotingByID", sl.Err(err), slog.String("voting_id", votingID))
 		return
@@ -722,19 +1126,18 @@ func GetVotingByID(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetAllVotings - ОБНОВЛЕНО для отправки триггера в Kafka
+// setDataAge сообщает клиенту, сколько секунд назад данные пришли из Kafka
+func setDataAge(w http.ResponseWriter, key string) {
+	if age, ok := refresher.Age(key); ok {
+		w.Header().Set("X-Data-Age", strconv.Itoa(int(age.Seconds())))
+	}
+}
+
 func GetAllVotings(w http.ResponseWriter, r *http.Request) {
 	log.Info("Accessed GetAllVotings endpoint")
 
-	// --- НОВОЕ: Отправка триггера в Kafka ---
-	err := kafkaProducer.TriggerAllVotingsProduce(r.Context())
-	if err != nil {
-		log.Error("Failed to send trigger all votings event to Kafka from GetAllVotings", sl.Err(err))
-		// Можно не возвращать ошибку фронтенду, так как это не критично для отображения списка голосований.
-		// Просто логируем и продолжаем.
-	} else {
-		log.Info("All votings trigger event sent to Kafka from GetAllVotings")
-	}
-	// --- КОНЕЦ НОВОГО БЛОКА ---
+	// Отвечаем из кэша; триггер в Kafka уходит в фоне, только если список устарел
+	refresher.Touch(refresh.AllVotings)
 
 	var filteredVotings []models.VoteSession
 	showAll := r.URL.Query().Get("type") == "all" // Используется для отображения приватных голосований
@@ -742,8 +1145,8 @@ func GetAllVotings(w http.ResponseWriter, r *http.Request) {
 	mu.Lock()
 	for _, v := range votings {
 		// Обновляем статус голосования перед добавлением в список
-		UpdateVotingStatusAndWinner(v.ID) // Обновляем в цикле
-		updatedVoting := votings[v.ID]    // Получаем обновленную версию
+		UpdateVotingStatusAndWinner(v.ID)                 // Обновляем в цикле
+		updatedVoting := ballot.PublicView(votings[v.ID]) // Получаем обновленную версию без скрытых данных
 
 		// Фильтруем приватные голосования, если showAll не установлен
 		if showAll || !updatedVoting.IsPrivate {
@@ -753,13 +1156,17 @@ func GetAllVotings(w http.ResponseWriter, r *http.Request) {
 	mu.Unlock()
 
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(filteredVotings)
+	setDataAge(w, refresh.AllVotings)
+	err := json.NewEncoder(w).Encode(filteredVotings)
 	if err != nil {
 		slog.Error("Failed to encode response for GetAllVotings", sl.Err(err))
 		return
 	}
 }
 
+// profileStakeEvents - сколько последних событий стейкинга попадает в профиль
+const profileStakeEvents = 20
+
 // GetUserData теперь является функцией, которая возвращает http.HandlerFunc.
 // Она принимает все необходимые зависимости как аргументы.
 func GetUserData(
@@ -769,6 +1176,7 @@ func GetUserData(
 	userActivities map[string]models.UserActivity, // Глобальная мапа userActivities
 	votings map[string]models.VoteSession, // Глобальная мапа votings
 	mu *sync.RWMutex, // Глобальный мьютекс для votings и userActivities
+	history *stakehistory.Indexer, // История стейкинга из логов стейк-менеджера
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var requestPayload struct {
@@ -822,20 +1230,18 @@ func GetUserData(
 		for _, voting := range votings {
 			if strings.EqualFold(voting.CreatorAddr, userAddress) {
 				createdCount++
-				userVotings = append(userVotings, voting)
+				userVotings = append(userVotings, ballot.PublicView(voting))
 			} else if _, ok := activity.ParticipatedVotings[voting.ID]; ok {
-				userVotings = append(userVotings, voting)
+				userVotings = append(userVotings, ballot.PublicView(voting))
 			}
 		}
 
 		// --- ПОЛУЧАЕМ ИСТОРИЮ ИЗ ПАМЯТИ ---
-		consumerInstance.Mu.Lock() // Блокируем consumerInstance.UserProfilesHistory
-		historyData, found := consumerInstance.UserProfilesHistory[userAddress]
+		historyData, found := consumerInstance.History(userAddress)
 		if !found {
 			historyData = []dto.History{} // Если истории нет, возвращаем пустой слайс
 			log.Info("GetUserData: No history found in memory for user", slog.String("user_address", userAddress))
 		}
-		consumerInstance.Mu.Unlock()
 
 		// --- ФОРМИРУЕМ ОТВЕТ ДЛЯ ФРОНТЕНДА ---
 		type UserProfileResponse struct {
@@ -843,15 +1249,21 @@ func GetUserData(
 			CreatedVotingsCount      int                  `json:"created_votings_count"`
 			ParticipatedVotingsCount int                  `json:"participated_votings_count"`
 			Votings                  []models.VoteSession `json:"votings"`
-			History                  []dto.History        `json:"history"` // Добавляем историю из памяти
+			History                  []dto.History        `json:"history"`               // Добавляем историю из памяти
+			StakingHistory           []client.StakeEvent  `json:"staking_history"`       // Последние события стейкинга
+			StakingHistoryTotal      int                  `json:"staking_history_total"` // Все события; полный список - /staking/{address}/history
 		}
 
+		stakingHistory, stakingTotal := history.Events(userAddress, 0, profileStakeEvents)
+
 		responsePayload := UserProfileResponse{
 			UserAddress:              requestPayload.UserAddress,
 			CreatedVotingsCount:      createdCount,
 			ParticipatedVotingsCount: participatedCount,
 			Votings:                  userVotings,
 			History:                  historyData, // <-- Прямое использование данных из памяти
+			StakingHistory:           stakingHistory,
+			StakingHistoryTotal:      stakingTotal,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -877,29 +1289,18 @@ func GetTokensHandler(log *slog.Logger, sc *client.StakeClient) http.HandlerFunc
 
 		log.Info("Received request to get tokens")
 
+		if isDryRun(r) {
+			sim, err := sc.SimulateGetTokens(r.Context())
+			writeSimulation(w, r, log, sim, err, "Claim would be rejected by stake manager")
+			return
+		}
+
 		// Вызов метода GetTokens клиента блокчейна
 		// Здесь не нужно передавать stakerAddress, так как он берется из PrivateKey
 		tx, err := sc.GetTokens(r.Context())
 		if err != nil {
-			log.Error("Failed to send GetTokens transaction to blockchain", "error", err)
-			// Более умная обработка ошибок контракта по строковому совпадению (или через парсинг)
-			errMsg := err.Error()
-			if strings.Contains(errMsg, "CooldownClaimNotReached") {
-				render.Status(r, http.StatusTooManyRequests) // 429 Too Many Requests
-				render.JSON(w, r, resp.Error("Claim cooldown period not reached yet"))
-			} else if strings.Contains(errMsg, "NothingToClaim") {
-				render.Status(r, http.StatusNotFound) // 404 Not Found, т.к. нет ничего для клейма
-				render.JSON(w, r, resp.Error("Nothing to claim"))
-			} else if strings.Contains(errMsg, "NotEnoughBalanceOnContract") {
-				render.Status(r, http.StatusInternalServerError) // Это проблема контракта/пула
-				render.JSON(w, r, resp.Error("Contract does not have enough tokens to fulfill claim"))
-			} else if strings.Contains(errMsg, "TransferFailed") {
-				render.Status(r, http.StatusInternalServerError)
-				render.JSON(w, r, resp.Error("Token transfer failed on contract"))
-			} else {
-				render.Status(r, http.StatusInternalServerError)
-				render.JSON(w, r, resp.Error("Failed to get tokens: "+errMsg))
-			}
+			// Ошибки контракта (CooldownClaimNotReached, NothingToClaim, ...) переводятся в статусы в chainerr
+			chainerr.Write(w, r, log, err, "Failed to get tokens")
 			return
 		}
 
@@ -918,47 +1319,26 @@ func UpdateVotingStatusAndWinner(votingID string) {
 		return // Голосование не найдено
 	}
 
-	now := time.Now()
-	startDate := voting.StartTime
-	endDate := voting.EndTime
-
-	if now.Before(startDate) {
-		voting.Status = "Upcoming"
-	} else if now.After(endDate) {
-		// Голосование завершено
-		if voting.TempNumberVotes < voting.MinNumberVotes {
-			voting.Status = "Rejected" // Отклонено, если не набрано мин.голосов
-			voting.Winner = []string{}
-		} else {
-			voting.Status = "Finished" // Закончено, если набрано
-
-			maxVotes := int64(-1)
-			var winners []string
-
-			for _, choice := range voting.Choices {
-				if choice.CountVotes > maxVotes {
-					maxVotes = choice.CountVotes
-					winners = []string{choice.Title}
-				} else if choice.CountVotes == maxVotes && maxVotes != -1 {
-					winners = append(winners, choice.Title)
-				}
-			}
-			voting.Winner = winners
-		}
-	} else {
-		voting.Status = "Active" // Активное
-	}
-
+	prevStatus := voting.Status
+	results.Refresh(&voting, time.Now())
 	votings[votingID] = voting // Сохраняем обновленное голосование
-}
 
-// UpdateAllVotingStatuses проходит по всем голосованиям и обновляет их статус
-func UpdateAllVotingStatuses() {
-	mu.Lock()
-	defer mu.Unlock()
-	slog.Debug("Updating all voting statuses...")
-	for id := range votings {
-		UpdateVotingStatusAndWinner(id)
+	// Пустой prevStatus - голосование только что создано, это не смена статуса
+	if prevStatus != "" && prevStatus != voting.Status && eventBus != nil {
+		log.Info("Voting status changed",
+			slog.String("voting_id", votingID),
+			slog.String("from", prevStatus),
+			slog.String("to", voting.Status))
+		eventBus.Publish(events.Event{
+			Type:     events.VotingStatusChanged,
+			VotingID: votingID,
+			Payload: events.StatusChange{
+				VotingID: votingID,
+				From:     prevStatus,
+				To:       voting.Status,
+				Winners:  voting.Winner,
+				Result:   voting.Result,
+			},
+		})
 	}
-	slog.Debug("All voting statuses updated.")
 }
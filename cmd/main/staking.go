@@ -0,0 +1,233 @@
+package main
+
+import (
+	"apiGateway/internal/client"
+	"apiGateway/internal/http-server/chainerr"
+	"apiGateway/internal/lib/amount"
+	"apiGateway/internal/lib/logger/sl"
+	"apiGateway/internal/rewards"
+	"apiGateway/internal/stakehistory"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-chi/chi"
+)
+
+// GetStakingPositionHandler - стейк адреса: сумма, начало стейка, накопленные награды и время следующего клейма
+func GetStakingPositionHandler(log *slog.Logger, sc *client.StakeClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if sc == nil {
+			http.Error(w, "Stake client is not initialized", http.StatusServiceUnavailable)
+			return
+		}
+
+		address := chi.URLParam(r, "address")
+		if !common.IsHexAddress(address) {
+			http.Error(w, "Invalid address", http.StatusBadRequest)
+			return
+		}
+
+		position, err := sc.StakePosition(r.Context(), common.HexToAddress(address))
+		if err != nil {
+			chainerr.Write(w, r, log.With(slog.String("address", address)), err, "Failed to read staking position from blockchain")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(position); err != nil {
+			log.Error("GetStakingPositionHandler: Failed to encode response", sl.Err(err))
+		}
+	}
+}
+
+// ProjectionResponse - прогноз наград; суммы токена наград в минимальных единицах и с учетом decimals
+type ProjectionResponse struct {
+	Amount   amount.Amount `json:"amount"`
+	Duration string        `json:"duration"`
+	NewStake bool          `json:"new_stake"`
+
+	RewardRate      client.TokenAmount `json:"reward_rate"` // Скорость наград всего пула в секунду
+	TotalStaked     amount.Amount      `json:"total_staked"`
+	Cooldown        string             `json:"claim_cooldown"`
+	RewardPerSecond client.TokenAmount `json:"reward_per_second"`
+	TotalRewards    client.TokenAmount `json:"total_rewards"`
+
+	Claims    int64              `json:"claims"`
+	Claimable client.TokenAmount `json:"claimable"`
+	Pending   client.TokenAmount `json:"pending"`
+
+	TokensPerETHYear string `json:"tokens_per_eth_year"`
+	TokenPriceETH    string `json:"token_price_eth,omitempty"`
+	APR              string `json:"apr_percent,omitempty"`
+	EffectiveAPR     string `json:"effective_apr_percent,omitempty"`
+}
+
+// GetStakingProjectionHandler - прогноз наград за стейк amount на срок duration по текущим параметрам контракта.
+// Параметры запроса: amount (как в /staking), duration (Go duration или дни "30d", по умолчанию 365d),
+// price - цена токена наград в ETH для расчета APR, new_stake=false - amount уже в пуле
+func GetStakingProjectionHandler(log *slog.Logger, sc *client.StakeClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if sc == nil {
+			http.Error(w, "Stake client is not initialized", http.StatusServiceUnavailable)
+			return
+		}
+
+		query := r.URL.Query()
+		stake, err := amount.Parse(query.Get("amount"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid amount: %v", err), http.StatusBadRequest)
+			return
+		}
+		duration := rewards.Year
+		if v := query.Get("duration"); v != "" {
+			if duration, err = parseDuration(v); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid duration: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+		in := rewards.Input{Amount: stake.Wei(), Duration: duration, NewStake: true}
+		if v := query.Get("new_stake"); v != "" {
+			if in.NewStake, err = strconv.ParseBool(v); err != nil {
+				http.Error(w, "Invalid new_stake", http.StatusBadRequest)
+				return
+			}
+		}
+		if v := query.Get("price"); v != "" {
+			price, ok := new(big.Rat).SetString(v)
+			if !ok || price.Sign() < 0 {
+				http.Error(w, "Invalid price", http.StatusBadRequest)
+				return
+			}
+			in.TokenPriceETH = price
+		}
+
+		params, err := sc.RewardParams(r.Context())
+		if errors.Is(err, rewards.ErrNoRewardRate) {
+			log.Warn("Reward projection is unavailable", sl.Err(err))
+			http.Error(w, "Stake manager does not expose a reward rate", http.StatusNotImplemented)
+			return
+		}
+		if err != nil {
+			chainerr.Write(w, r, log, err, "Failed to read reward parameters from blockchain")
+			return
+		}
+
+		proj, err := rewards.Project(params, in)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		token := func(v *big.Int) client.TokenAmount {
+			return client.TokenAmount{Raw: v.String(), Formatted: amount.Format(v, int(params.RewardDecimals))}
+		}
+		resp := ProjectionResponse{
+			Amount:           stake,
+			Duration:         duration.String(),
+			NewStake:         in.NewStake,
+			RewardRate:       token(params.RewardRate),
+			TotalStaked:      amount.FromWei(params.TotalStaked),
+			Cooldown:         params.Cooldown.String(),
+			RewardPerSecond:  token(proj.RewardPerSecond),
+			TotalRewards:     token(proj.Total),
+			Claims:           proj.Claims,
+			Claimable:        token(proj.Claimable),
+			Pending:          token(proj.Pending),
+			TokensPerETHYear: proj.TokensPerETHYear.FloatString(6),
+		}
+		if in.TokenPriceETH != nil {
+			resp.TokenPriceETH = in.TokenPriceETH.FloatString(18)
+			resp.APR = proj.APR.FloatString(4)
+			resp.EffectiveAPR = proj.EffectiveAPR.FloatString(4)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Error("GetStakingProjectionHandler: Failed to encode response", sl.Err(err))
+		}
+	}
+}
+
+// parseDuration принимает Go duration ("720h") или целое число дней ("30d")
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid number of days %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// Размер страницы истории стейкинга
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// StakingHistoryResponse - страница событий стейкинга адреса, от новых к старым
+type StakingHistoryResponse struct {
+	Address   string              `json:"address"`
+	Events    []client.StakeEvent `json:"events"`
+	Total     int                 `json:"total"`
+	Offset    int                 `json:"offset"`
+	Limit     int                 `json:"limit"`
+	IndexedTo *uint64             `json:"indexed_to_block,omitempty"` // До какого блока прочитаны логи; нет, пока индексация не началась
+}
+
+// GetStakingHistoryHandler - стейки, выводы и клеймы адреса из логов стейк-менеджера. Параметры: offset, limit
+func GetStakingHistoryHandler(log *slog.Logger, ix *stakehistory.Indexer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ix == nil {
+			http.Error(w, "Staking history is not available", http.StatusServiceUnavailable)
+			return
+		}
+
+		address := chi.URLParam(r, "address")
+		if !common.IsHexAddress(address) {
+			http.Error(w, "Invalid address", http.StatusBadRequest)
+			return
+		}
+
+		offset, limit := 0, defaultHistoryLimit
+		var err error
+		if v := r.URL.Query().Get("offset"); v != "" {
+			if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+				http.Error(w, "Invalid offset", http.StatusBadRequest)
+				return
+			}
+		}
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxHistoryLimit {
+				http.Error(w, fmt.Sprintf("Invalid limit: expected 1-%d", maxHistoryLimit), http.StatusBadRequest)
+				return
+			}
+		}
+
+		events, total := ix.Events(address, offset, limit)
+		resp := StakingHistoryResponse{
+			Address: strings.ToLower(address),
+			Events:  events,
+			Total:   total,
+			Offset:  offset,
+			Limit:   limit,
+		}
+		if block, ok := ix.IndexedTo(); ok {
+			resp.IndexedTo = &block
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Error("GetStakingHistoryHandler: Failed to encode response", sl.Err(err))
+		}
+	}
+}
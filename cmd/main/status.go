@@ -0,0 +1,96 @@
+package main
+
+import (
+	"apiGateway/internal/dto"
+	"apiGateway/internal/events"
+	"apiGateway/internal/lib/logger/sl"
+	"apiGateway/internal/results"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// scheduleVoting взводит таймеры планировщика на моменты смены статуса голосования
+func scheduleVoting(votingID string) {
+	mu.RLock()
+	voting, ok := votings[votingID]
+	mu.RUnlock()
+
+	if !ok {
+		statusScheduler.Cancel(votingID)
+		return
+	}
+	statusScheduler.Schedule(votingID, results.Transitions(voting)...)
+}
+
+// scheduleAllVotings перевзводит таймеры всех известных голосований
+func scheduleAllVotings() {
+	mu.RLock()
+	ids := make([]string, 0, len(votings))
+	for id := range votings {
+		ids = append(ids, id)
+	}
+	mu.RUnlock()
+
+	for _, id := range ids {
+		scheduleVoting(id)
+	}
+}
+
+// onVotingTimer срабатывает в момент начала/окончания голосования и переводит его в новый статус
+func onVotingTimer(votingID string) {
+	mu.Lock()
+	UpdateVotingStatusAndWinner(votingID)
+	mu.Unlock()
+}
+
+// runStatusEvents связывает шину событий с планировщиком и Kafka:
+// обновленные голосования перепланируются, смены статуса публикуются в voting-status-changed.
+func runStatusEvents(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	updates, unsubscribeUpdates := eventBus.Subscribe(1024, events.VotingUpdated)
+	defer unsubscribeUpdates()
+	// Смены статуса уходят в Kafka, и Java-сервис не должен пропускать ни одной: эта подписка без потерь
+	changes, unsubscribeChanges := eventBus.SubscribeUnbounded(events.VotingStatusChanged)
+	defer unsubscribeChanges()
+
+	scheduleAllVotings()
+
+	for {
+		select {
+		case <-ctx.Done():
+			statusScheduler.Stop()
+			log.Info("Voting status scheduler stopped")
+			return
+
+		case e := <-updates:
+			if e.VotingID == "" {
+				scheduleAllVotings()
+			} else {
+				scheduleVoting(e.VotingID)
+			}
+
+		case e := <-changes:
+			change, ok := e.Payload.(events.StatusChange)
+			if !ok {
+				continue
+			}
+			statusEvent := dto.VotingStatusChanged{
+				VotingID:       change.VotingID,
+				PreviousStatus: change.From,
+				Status:         change.To,
+				Winners:        change.Winners,
+				ChangedAt:      e.At.Format(time.RFC3339),
+			}
+			if change.Result != nil {
+				statusEvent.Explanation = change.Result.Explanation
+			}
+			if err := kafkaProducer.VotingStatusChangedProduce(ctx, statusEvent); err != nil {
+				log.Error("Failed to send voting status changed event to Kafka", sl.Err(err),
+					slog.String("voting_id", change.VotingID))
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"apiGateway/internal/client"
+	"apiGateway/internal/http-server/chainerr"
+	"apiGateway/internal/lib/logger/sl"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-chi/chi"
+)
+
+// TokenBalanceResponse - баланс токена наград и выплаты наград адресу
+type TokenBalanceResponse struct {
+	Address string                 `json:"address"`
+	Token   client.TokenInfo       `json:"token"`
+	Balance client.TokenAmount     `json:"balance"`
+	Rewards []client.TokenTransfer `json:"rewards,omitempty"` // Переводы со стейк-менеджера (выплаты GetTokens)
+}
+
+// GetTokenInfoHandler - название, символ, decimals и общее предложение токена наград
+func GetTokenInfoHandler(log *slog.Logger, tc *client.TokenClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if tc == nil {
+			http.Error(w, "Token client is not initialized", http.StatusServiceUnavailable)
+			return
+		}
+
+		info, err := tc.Info(r.Context())
+		if err != nil {
+			chainerr.Write(w, r, log, err, "Failed to read token info")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(info); err != nil {
+			log.Error("GetTokenInfoHandler: Failed to encode response", sl.Err(err))
+		}
+	}
+}
+
+// GetTokenBalanceHandler - баланс токена наград адреса. С ?rewards=true добавляет выплаты наград из логов Transfer
+func GetTokenBalanceHandler(log *slog.Logger, tc *client.TokenClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if tc == nil {
+			http.Error(w, "Token client is not initialized", http.StatusServiceUnavailable)
+			return
+		}
+
+		address := chi.URLParam(r, "address")
+		if !common.IsHexAddress(address) {
+			http.Error(w, "Invalid address", http.StatusBadRequest)
+			return
+		}
+		addr := common.HexToAddress(address)
+		log := log.With(slog.String("address", address))
+
+		info, err := tc.Info(r.Context())
+		if err != nil {
+			chainerr.Write(w, r, log, err, "Failed to read token info")
+			return
+		}
+		balance, err := tc.BalanceOf(r.Context(), addr)
+		if err != nil {
+			chainerr.Write(w, r, log, err, "Failed to read token balance")
+			return
+		}
+
+		response := TokenBalanceResponse{
+			Address: strings.ToLower(addr.Hex()),
+			Token:   info,
+			Balance: balance,
+		}
+		if r.URL.Query().Get("rewards") == "true" {
+			if response.Rewards, err = tc.RewardTransfers(r.Context(), addr); err != nil {
+				chainerr.Write(w, r, log, err, "Failed to read reward transfers")
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error("GetTokenBalanceHandler: Failed to encode response", sl.Err(err))
+		}
+	}
+}
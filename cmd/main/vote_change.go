@@ -0,0 +1,263 @@
+package main
+
+import (
+	"apiGateway/internal/dto"
+	"apiGateway/internal/lib/logger/sl"
+	"apiGateway/internal/models"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	voteActionChanged = "changed"
+	voteActionRevoked = "revoked"
+)
+
+// hasChangeableVote проверяет, что пользователь уже голосовал (в том числе отозвал голос)
+// и голосование разрешает менять голос
+func hasChangeableVote(votingID, userAddress string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	voting, ok := votings[votingID]
+	if !ok || !voting.AllowVoteChange {
+		return false
+	}
+	userAddressLower := strings.ToLower(userAddress)
+	voter, exists := voting.Voters[userAddressLower]
+	return (exists && voter.IsVoted) || hasRevokedVote(voting, userAddressLower)
+}
+
+// hasRevokedVote проверяет, что последнее изменение голоса пользователя - отзыв.
+// Отзыв не отправляется в контракт, поэтому там голос пользователя по-прежнему учтен
+func hasRevokedVote(voting models.VoteSession, userAddressLower string) bool {
+	for i := len(voting.VoteChanges) - 1; i >= 0; i-- {
+		if strings.EqualFold(voting.VoteChanges[i].VoterAddress, userAddressLower) {
+			return voting.VoteChanges[i].ToChoice == -1
+		}
+	}
+	return false
+}
+
+// applyVoteChange меняет (newChoice >= 0) или отзывает (newChoice == -1) голос пользователя.
+// После отзыва newChoice >= 0 снова учитывает голос пользователя.
+// Возвращает запись истории и HTTP-статус для ответа при ошибке.
+// Должен вызываться под мьютексом
+func applyVoteChange(votingID, userAddress string, newChoice int) (models.VoteChange, int, error) {
+	voting, ok := votings[votingID]
+	if !ok {
+		return models.VoteChange{}, http.StatusNotFound, fmt.Errorf("VoteSession not found")
+	}
+
+	if !voting.AllowVoteChange {
+		return models.VoteChange{}, http.StatusConflict, fmt.Errorf("changing votes is not allowed in this poll")
+	}
+
+	now := time.Now()
+	if now.Before(voting.StartTime) || now.After(voting.EndTime) {
+		return models.VoteChange{}, http.StatusForbidden, fmt.Errorf("votes can only be changed while the poll is active")
+	}
+
+	userAddressLower := strings.ToLower(userAddress)
+	voter, exists := voting.Voters[userAddressLower]
+	revoked := !voter.IsVoted && hasRevokedVote(voting, userAddressLower)
+	if (!exists || !voter.IsVoted) && !revoked {
+		return models.VoteChange{}, http.StatusNotFound, fmt.Errorf("you have not voted in this poll")
+	}
+
+	if newChoice < -1 || newChoice >= len(voting.Choices) {
+		return models.VoteChange{}, http.StatusBadRequest, fmt.Errorf("invalid option selected")
+	}
+
+	prevChoice := voter.Choice
+	if revoked {
+		prevChoice = -1
+	}
+	if prevChoice == newChoice {
+		if revoked {
+			return models.VoteChange{}, http.StatusConflict, fmt.Errorf("vote is already revoked")
+		}
+		return models.VoteChange{}, http.StatusConflict, fmt.Errorf("vote is already cast for this option")
+	}
+
+	change := models.VoteChange{
+		VoterAddress: userAddress,
+		FromChoice:   prevChoice,
+		ToChoice:     newChoice,
+		ChangedAt:    now,
+	}
+
+	if revoked {
+		// Голос после отзыва учитывается заново, вместе с делегаторами, которые снова представлены пользователем
+		change.Weight, change.Delegators = recordVote(&voting, userAddress, newChoice)
+		return finishVoteChange(votingID, voting, change)
+	}
+
+	// Снимаем голос (вместе с делегированным весом) с предыдущего варианта
+	weight := voteWeight(voter)
+	change.Weight, change.Delegators = weight, voter.Represents
+	if prevChoice >= 0 && prevChoice < len(voting.Choices) {
+		voting.Choices[prevChoice].CountVotes = max(voting.Choices[prevChoice].CountVotes-weight, 0)
+	}
+
+	activity := userActivities[userAddressLower]
+	if activity.ParticipatedVotings == nil {
+		activity.ParticipatedVotings = make(map[string]int)
+	}
+
+	if newChoice == -1 {
+		represents := voter.Represents
+		voter.IsVoted = false
+		voter.Choice = -1
+		voter.Weight = 0
+		voter.Represents = nil
+		voting.TempNumberVotes = max(voting.TempNumberVotes-weight, 0)
+		delete(activity.ParticipatedVotings, votingID)
+		voting.Voters[userAddressLower] = voter
+		// Делегаторы отозванного голоса переходят к следующему проголосовавшему в их цепочке
+		releaseRepresented(&voting, represents)
+	} else {
+		voter.Choice = newChoice
+		voting.Choices[newChoice].CountVotes += weight
+		activity.ParticipatedVotings[votingID] = newChoice
+		voting.Voters[userAddressLower] = voter
+	}
+	userActivities[userAddressLower] = activity
+
+	return finishVoteChange(votingID, voting, change)
+}
+
+// finishVoteChange записывает изменение в историю голосования, сохраняет его и пересчитывает статус.
+// Должен вызываться под мьютексом
+func finishVoteChange(votingID string, voting models.VoteSession, change models.VoteChange) (models.VoteChange, int, error) {
+	voting.VoteChanges = append(voting.VoteChanges, change)
+	votings[votingID] = voting
+
+	UpdateVotingStatusAndWinner(votingID)
+
+	return change, http.StatusOK, nil
+}
+
+// produceVoteChanged отправляет событие vote-changed; ошибка Kafka не отменяет изменение в памяти
+func produceVoteChanged(r *http.Request, votingID string, change models.VoteChange) {
+	event := dto.VoteChanged{
+		VotingID:         votingID,
+		VoterID:          change.VoterAddress,
+		PreviousOptionID: fmt.Sprintf("%d", change.FromChoice),
+		Action:           voteActionChanged,
+		Weight:           change.Weight,
+		Delegators:       change.Delegators,
+		ChangedAt:        change.ChangedAt.Format(time.RFC3339),
+	}
+	if change.ToChoice == -1 {
+		event.Action = voteActionRevoked
+	} else {
+		event.OptionID = fmt.Sprintf("%d", change.ToChoice)
+	}
+
+	if err := kafkaProducer.VoteChangedProduce(r.Context(), event); err != nil {
+		log.Error("Failed to send vote changed event to Kafka",
+			sl.Err(err),
+			slog.String("voting_id", votingID),
+			slog.String("voter_id", change.VoterAddress))
+	}
+}
+
+// voteChangeMessage - текст, который избиратель подписывает для изменения (option >= 0) или отзыва (option == -1) голоса
+func voteChangeMessage(votingID string, voter common.Address, option int, signedAt int64) string {
+	if option == -1 {
+		return fmt.Sprintf("TrustVote revoke vote\nvoter: %s\nvoting_id: %s\nsigned_at: %d",
+			strings.ToLower(voter.Hex()), votingID, signedAt)
+	}
+	return fmt.Sprintf("TrustVote change vote\nvoter: %s\nvoting_id: %s\noption: %d\nsigned_at: %d",
+		strings.ToLower(voter.Hex()), votingID, option, signedAt)
+}
+
+// changeVote - ветка SubmitVote для повторного голоса в голосовании с AllowVoteChange,
+// в том числе голоса после отзыва. Запрос должен быть подписан самим избирателем
+func changeVote(w http.ResponseWriter, r *http.Request, req models.VoteRequest) {
+	if !common.IsHexAddress(req.UserAddress) {
+		http.Error(w, "Invalid user_address", http.StatusBadRequest)
+		return
+	}
+	voter := common.HexToAddress(req.UserAddress)
+	if !verifySignedRequest(w, log, voteChangeMessage(req.VotingID, voter, req.SelectedOptionIndex, req.SignedAt), req.Signature, req.SignedAt, voter) {
+		return
+	}
+
+	mu.Lock()
+	change, status, err := applyVoteChange(req.VotingID, req.UserAddress, req.SelectedOptionIndex)
+	mu.Unlock()
+	if err != nil {
+		log.Warn("SubmitVote: Failed to change vote", sl.Err(err),
+			slog.String("voting_id", req.VotingID),
+			slog.String("user_address", req.UserAddress))
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	produceVoteChanged(r, req.VotingID, change)
+
+	log.Info("Vote changed",
+		slog.String("voting_id", req.VotingID),
+		slog.String("user_address", req.UserAddress),
+		slog.Int("from_option", change.FromChoice),
+		slog.Int("to_option", change.ToChoice))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Vote successfully changed"}); err != nil {
+		log.Error("SubmitVote: Failed to encode response", sl.Err(err))
+	}
+}
+
+// RevokeVoteHandler - отзывает голос пользователя, пока голосование активно
+func RevokeVoteHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.RevokeVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("RevokeVoteHandler: Invalid request payload", sl.Err(err))
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if req.VotingID == "" || !common.IsHexAddress(req.UserAddress) {
+		http.Error(w, "voting_id and a valid user_address are required", http.StatusBadRequest)
+		return
+	}
+
+	// Отозвать голос может только сам избиратель
+	voter := common.HexToAddress(req.UserAddress)
+	if !verifySignedRequest(w, log, voteChangeMessage(req.VotingID, voter, -1, req.SignedAt), req.Signature, req.SignedAt, voter) {
+		return
+	}
+
+	mu.Lock()
+	change, status, err := applyVoteChange(req.VotingID, req.UserAddress, -1)
+	mu.Unlock()
+	if err != nil {
+		log.Warn("RevokeVoteHandler: Failed to revoke vote", sl.Err(err),
+			slog.String("voting_id", req.VotingID),
+			slog.String("user_address", req.UserAddress))
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	produceVoteChanged(r, req.VotingID, change)
+
+	log.Info("Vote revoked",
+		slog.String("voting_id", req.VotingID),
+		slog.String("user_address", req.UserAddress),
+		slog.Int("from_option", change.FromChoice))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Vote successfully revoked"}); err != nil {
+		log.Error("RevokeVoteHandler: Failed to encode response", sl.Err(err))
+	}
+}
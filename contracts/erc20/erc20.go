@@ -0,0 +1,18 @@
+package erc20
+
+import (
+	"bytes"
+	_ "embed"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// abiJSON - стандартный интерфейс ERC-20 (только то, что использует шлюз). В отличие от ABI наших
+// контрактов он не меняется, поэтому встраивается в бинарник, а не читается из артефактов сборки
+//
+//go:embed ERC20.abi.json
+var abiJSON []byte
+
+func GetABI() (abi.ABI, error) {
+	return abi.JSON(bytes.NewReader(abiJSON))
+}
@@ -0,0 +1,85 @@
+package ballot
+
+import (
+	"apiGateway/internal/models"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// CommitmentHash считает хеш скрытого голоса:
+// keccak256("<votingID>:<адрес в нижнем регистре>:<индекс варианта>:<соль>").
+// Фронтенд должен считать его так же и хранить соль до фазы раскрытия.
+func CommitmentHash(votingID, voter string, choice int, salt string) common.Hash {
+	preimage := fmt.Sprintf("%s:%s:%d:%s", votingID, strings.ToLower(voter), choice, salt)
+	return crypto.Keccak256Hash([]byte(preimage))
+}
+
+// ParseCommitment проверяет, что строка - 32-байтовый 0x-хеш
+func ParseCommitment(s string) (common.Hash, error) {
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("commitment must be 0x-prefixed hex: %w", err)
+	}
+	if len(b) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("commitment must be %d bytes, got %d", common.HashLength, len(b))
+	}
+	return common.BytesToHash(b), nil
+}
+
+// Verify проверяет, что раскрытие совпадает с записанным хешем
+func Verify(c models.Commitment, votingID, voter string, choice int, salt string) bool {
+	if c.Hash == "" || salt == "" {
+		return false
+	}
+	return strings.EqualFold(c.Hash, CommitmentHash(votingID, voter, choice, salt).Hex())
+}
+
+// talliesHidden - пока идет голосование, промежуточные итоги приватных и commit-reveal голосований скрыты
+func talliesHidden(v models.VoteSession) bool {
+	if !v.IsPrivate && !v.IsCommitReveal() {
+		return false
+	}
+	switch v.Status {
+	case models.StatusUpcoming, models.StatusActive, models.StatusReveal:
+		return true
+	}
+	return false
+}
+
+// PublicView возвращает копию голосования для отдачи наружу.
+// Хеши скрытых голосов не отдаются никогда. У приватных и commit-reveal голосований скрывается выбор каждого участника,
+// а до завершения - еще и счетчики голосов.
+func PublicView(v models.VoteSession) models.VoteSession {
+	// По хешам видно, кто уже проголосовал, а по слабой соли можно подобрать выбор
+	v.Commitments = nil
+	if !v.IsPrivate && !v.IsCommitReveal() {
+		return v
+	}
+
+	voters := make(map[string]models.Voter, len(v.Voters))
+	for addr, voter := range v.Voters {
+		voter.Choice = -1
+		voter.Represents = nil
+		voters[addr] = voter
+	}
+	v.Voters = voters
+
+	if talliesHidden(v) {
+		choices := make([]models.Choice, len(v.Choices))
+		for i, c := range v.Choices {
+			choices[i] = models.Choice{Title: c.Title}
+		}
+		v.Choices = choices
+		v.TempNumberVotes = 0
+		v.Winner = []string{}
+		v.Result = nil
+	}
+
+	// Перечень изменений раскрывает, кто и как голосовал
+	v.VoteChanges = nil
+	return v
+}
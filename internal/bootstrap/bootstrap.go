@@ -0,0 +1,239 @@
+package bootstrap
+
+import (
+	"apiGateway/internal/config"
+	"apiGateway/internal/kafka/consumer"
+	"apiGateway/internal/lib/logger/sl"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Режимы восстановления состояния при старте (kafka.bootstrap.mode)
+const (
+	ModeSnapshot = "snapshot" // Запросить снимок всех голосований через trigger-all-votings и дождаться ответа
+	ModeReplay   = "replay"   // Перечитать compacted-топики состояния с начала
+	ModeNone     = "none"     // Сразу считать сервис готовым
+)
+
+// Фазы загрузки
+const (
+	PhasePending = "pending"
+	PhaseLoading = "loading"
+	PhaseReady   = "ready"
+)
+
+// retryDelay - пауза перед повторной попыткой загрузки после ошибки
+const retryDelay = 5 * time.Second
+
+// replayTopics - топики, из которых собирается состояние в режиме replay
+var replayTopics = []string{config.TopicAllVotingsResponse, config.TopicVoteHistoryResponse, config.TopicVoteCommit, config.TopicVoteDelegation}
+
+// gatewayTopics - состояние, которое есть только у шлюза и которого нет в снимке Java-сервиса.
+// Эти топики перечитываются с начала и в режиме snapshot
+var gatewayTopics = []string{config.TopicVoteDelegation}
+
+// Status - состояние загрузки для эндпоинта готовности
+type Status struct {
+	Phase     string     `json:"phase"`
+	Mode      string     `json:"mode"`
+	Attempts  int        `json:"attempts"`
+	Applied   int        `json:"applied"` // Применено сообщений при replay
+	LastError string     `json:"last_error,omitempty"`
+	StartedAt time.Time  `json:"started_at"`
+	ReadyAt   *time.Time `json:"ready_at,omitempty"`
+}
+
+// Bootstrapper загружает состояние из Kafka при старте и запускает рантайм консюмеров.
+// Пока загрузка не завершена, Ready возвращает false.
+type Bootstrapper struct {
+	cfg             config.Bootstrap
+	log             *slog.Logger
+	runtime         *consumer.Runtime
+	snapshotApplied <-chan struct{}                 // Закрывается, когда консюмер применил полный снимок голосований
+	trigger         func(ctx context.Context) error // Запрос снимка всех голосований
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func New(cfg config.Bootstrap, log *slog.Logger, runtime *consumer.Runtime, snapshotApplied <-chan struct{}, trigger func(ctx context.Context) error) *Bootstrapper {
+	mode := cfg.Mode
+	if mode == "" {
+		mode = ModeSnapshot
+	}
+	cfg.Mode = mode
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.ReplayAttempts < 1 {
+		cfg.ReplayAttempts = 5
+	}
+
+	return &Bootstrapper{
+		cfg:             cfg,
+		log:             log.With(slog.String("component", "bootstrap")),
+		runtime:         runtime,
+		snapshotApplied: snapshotApplied,
+		trigger:         trigger,
+		status:          Status{Phase: PhasePending, Mode: mode},
+	}
+}
+
+// Status возвращает текущее состояние загрузки
+func (b *Bootstrapper) Status() Status {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.status
+}
+
+// Ready сообщает, что состояние загружено
+func (b *Bootstrapper) Ready() bool {
+	return b.Status().Phase == PhaseReady
+}
+
+// Run восстанавливает состояние и запускает рантайм консюмеров. В режиме replay рантайм
+// стартует после перечитывания топиков, в режиме snapshot - после перечитывания gatewayTopics
+// и до запроса снимка, чтобы получить ответ.
+// Перечитывание топиков повторяется не больше ReplayAttempts раз, чтобы одна сломанная загрузка
+// не оставила шлюз без консюмеров: после этого replay переходит на снимок, как в режиме snapshot.
+// Запрос снимка повторяется, пока не отменен ctx.
+func (b *Bootstrapper) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	b.update(func(s *Status) {
+		s.Phase = PhaseLoading
+		s.StartedAt = time.Now()
+	})
+	b.log.Info("Loading state from Kafka", slog.String("mode", b.cfg.Mode))
+
+	loadSnapshot := false
+	switch b.cfg.Mode {
+	case ModeReplay:
+		if !b.retry(ctx, b.cfg.ReplayAttempts, b.replay(replayTopics)) && ctx.Err() == nil {
+			b.log.Error("Failed to replay state topics, falling back to votings snapshot",
+				slog.Int("attempts", b.cfg.ReplayAttempts))
+			loadSnapshot = true
+		}
+	case ModeSnapshot:
+		if !b.retry(ctx, b.cfg.ReplayAttempts, b.replay(gatewayTopics)) && ctx.Err() == nil {
+			b.log.Error("Failed to replay gateway topics, delegations made before the restart are lost",
+				slog.Any("topics", gatewayTopics),
+				slog.Int("attempts", b.cfg.ReplayAttempts))
+		}
+		loadSnapshot = true
+	case ModeNone:
+		b.log.Warn("State is not restored from Kafka, delegations made before the restart are lost",
+			slog.Any("topics", gatewayTopics))
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	wg.Add(1)
+	go b.runtime.Run(ctx, wg)
+
+	if loadSnapshot {
+		b.retry(ctx, 0, b.snapshot)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	b.update(func(s *Status) {
+		s.Phase = PhaseReady
+		s.ReadyAt = &now
+		s.LastError = ""
+	})
+	b.log.Info("State loaded, service is ready",
+		slog.String("mode", b.cfg.Mode),
+		slog.String("duration", now.Sub(b.Status().StartedAt).String()))
+}
+
+// retry повторяет load, пока он не завершится успешно, не кончатся attempts попыток (0 - без ограничения)
+// или не будет отменен ctx. Возвращает true, если load завершился успешно
+func (b *Bootstrapper) retry(ctx context.Context, attempts int, load func(ctx context.Context) error) bool {
+	for attempt := 1; ctx.Err() == nil; attempt++ {
+		b.update(func(s *Status) { s.Attempts++ })
+
+		err := load(ctx)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		b.update(func(s *Status) { s.LastError = err.Error() })
+		if attempts > 0 && attempt >= attempts {
+			b.log.Error("Failed to load state from Kafka, giving up", sl.Err(err), slog.Int("attempt", attempt))
+			return false
+		}
+		b.log.Error("Failed to load state from Kafka, retrying", sl.Err(err),
+			slog.Int("attempt", attempt),
+			slog.String("retry_in", retryDelay.String()))
+
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+		}
+	}
+	return false
+}
+
+// replay возвращает загрузку, которая перечитывает topics с начала.
+// Топики без зарегистрированного обработчика пропускаются
+func (b *Bootstrapper) replay(topics []string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for _, key := range topics {
+			if !b.runtime.Handles(key) {
+				continue
+			}
+			b.log.Info("Replaying state topic", slog.String("topic", key))
+
+			n, err := b.runtime.Replay(ctx, key, func(applied int) {
+				b.update(func(s *Status) { s.Applied++ })
+				if applied%1000 == 0 {
+					b.log.Info("Replay progress", slog.String("topic", key), slog.Int("applied", applied))
+				}
+			})
+			if err != nil {
+				return fmt.Errorf("replay of %s failed after %d messages: %w", key, n, err)
+			}
+
+			b.log.Info("State topic replayed", slog.String("topic", key), slog.Int("applied", n))
+		}
+		return nil
+	}
+}
+
+// snapshot запрашивает снимок всех голосований и ждет, пока консюмер его применит.
+// Сигнал о применении приходит через отдельный канал, а не через шину событий,
+// чтобы его нельзя было потерять при всплеске VotingUpdated
+func (b *Bootstrapper) snapshot(ctx context.Context) error {
+	if err := b.trigger(ctx); err != nil {
+		return fmt.Errorf("failed to request votings snapshot: %w", err)
+	}
+	b.log.Info("Votings snapshot requested, waiting for response", slog.String("timeout", b.cfg.Timeout.String()))
+
+	timeout := time.NewTimer(b.cfg.Timeout)
+	defer timeout.Stop()
+
+	select {
+	case <-b.snapshotApplied:
+		return nil
+	case <-timeout.C:
+		return fmt.Errorf("no votings snapshot received within %s", b.cfg.Timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bootstrapper) update(fn func(s *Status)) {
+	b.mu.Lock()
+	fn(&b.status)
+	b.mu.Unlock()
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errorStringSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	panicSelector       = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// ContractError - custom error контракта (revert SomeError(args)), найденный в ABI.
+// Проверяется через errors.As; имя ошибки совпадает с именем в Solidity
+type ContractError struct {
+	Name string
+	Args map[string]interface{} // Аргументы по именам из ABI; безымянные - arg0, arg1, ...
+	err  error
+}
+
+func (e *ContractError) Error() string {
+	if len(e.Args) == 0 {
+		return "execution reverted: " + e.Name
+	}
+	parts := make([]string, 0, len(e.Args))
+	for _, k := range slices.Sorted(maps.Keys(e.Args)) {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, e.Args[k]))
+	}
+	return fmt.Sprintf("execution reverted: %s(%s)", e.Name, strings.Join(parts, ", "))
+}
+
+func (e *ContractError) Unwrap() error { return e.err }
+
+// RevertError - revert со строкой причины (require(cond, "reason") или Error(string)).
+// Пустой Reason - revert без данных или с данными, которых нет в ABI (сырые данные в Data)
+type RevertError struct {
+	Reason string
+	Data   []byte
+	err    error
+}
+
+func (e *RevertError) Error() string {
+	switch {
+	case e.Reason != "":
+		return "execution reverted: " + e.Reason
+	case len(e.Data) > 0:
+		return "execution reverted with unknown data " + hexutil.Encode(e.Data)
+	default:
+		return "execution reverted"
+	}
+}
+
+func (e *RevertError) Unwrap() error { return e.err }
+
+// PanicError - Panic(uint256): assert, переполнение, деление на ноль и т.п. Обычно это ошибка в контракте
+type PanicError struct {
+	Code        *big.Int
+	Description string
+	err         error
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("execution panicked: %s (code %#x)", e.Description, e.Code)
+}
+
+func (e *PanicError) Unwrap() error { return e.err }
+
+// decodeRevert разбирает данные revert из ошибки RPC по ABI контрактов.
+// Если ошибка не содержит данных revert, возвращается как есть
+func decodeRevert(err error, abis ...abi.ABI) error {
+	if err == nil {
+		return nil
+	}
+
+	data, ok := revertData(err)
+	if !ok {
+		if strings.Contains(err.Error(), "execution reverted") {
+			return &RevertError{err: err}
+		}
+		return err
+	}
+	if len(data) < 4 {
+		return &RevertError{Data: data, err: err}
+	}
+
+	switch {
+	case bytes.Equal(data[:4], errorStringSelector):
+		reason, unpackErr := abi.UnpackRevert(data)
+		if unpackErr != nil {
+			return &RevertError{Data: data, err: err}
+		}
+		return &RevertError{Reason: reason, Data: data, err: err}
+	case bytes.Equal(data[:4], panicSelector):
+		code := new(big.Int).SetBytes(data[4:])
+		description, _ := abi.UnpackRevert(data)
+		return &PanicError{Code: code, Description: description, err: err}
+	}
+
+	var selector [4]byte
+	copy(selector[:], data[:4])
+	for _, contractABI := range abis {
+		abiErr, lookupErr := contractABI.ErrorByID(selector)
+		if lookupErr != nil {
+			continue
+		}
+		values, unpackErr := abiErr.Inputs.Unpack(data[4:])
+		if unpackErr != nil {
+			return &ContractError{Name: abiErr.Name, err: err}
+		}
+		args := make(map[string]interface{}, len(values))
+		for i, v := range values {
+			name := abiErr.Inputs[i].Name
+			if name == "" {
+				name = fmt.Sprintf("arg%d", i)
+			}
+			args[name] = v
+		}
+		return &ContractError{Name: abiErr.Name, Args: args, err: err}
+	}
+	return &RevertError{Data: data, err: err}
+}
+
+// revertData достает данные revert из ошибки JSON-RPC (eth_call и eth_estimateGas отдают их в поле data)
+func revertData(err error) ([]byte, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+	switch v := dataErr.ErrorData().(type) {
+	case string:
+		data, decodeErr := hexutil.Decode(v)
+		return data, decodeErr == nil
+	case []byte:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+// IsContractError сообщает, что err - custom error контракта с именем name
+func IsContractError(err error, name string) bool {
+	var contractErr *ContractError
+	return errors.As(err, &contractErr) && contractErr.Name == name
+}
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"apiGateway/internal/config"
+	"apiGateway/internal/lib/logger/sl"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ErrReorged - транзакция была в блоке, но после реорганизации цепи ее квитанции больше нет
+var ErrReorged = errors.New("transaction was removed from the chain by a reorg")
+
+// ReceiptWatcher ждет квитанции транзакций с нужным числом подтверждений.
+// Все ожидающие обслуживаются одним потоком новых блоков: подпиской SubscribeNewHead, если RPC URL - WebSocket,
+// иначе опросом номера блока. Поток запускается с первым ожидающим и останавливается, когда ждать больше некого
+type ReceiptWatcher struct {
+	client        *ethclient.Client
+	websocket     bool
+	confirmations uint64
+	pollInterval  time.Duration
+	timeout       time.Duration
+	log           *slog.Logger
+
+	mu      sync.Mutex
+	waiters map[*receiptWaiter]struct{}
+	running bool
+	kick    chan struct{} // Новый ожидающий: проверить квитанции, не дожидаясь следующего блока
+}
+
+type receiptWaiter struct {
+	hash    common.Hash
+	receipt *types.Receipt // Последняя увиденная квитанция; трогает только поток блоков
+	done    chan receiptResult
+}
+
+type receiptResult struct {
+	receipt *types.Receipt
+	err     error
+}
+
+func NewReceiptWatcher(cfg *config.Config, log *slog.Logger) (*ReceiptWatcher, error) {
+	if cfg == nil || cfg.Blockchain.RpcUrl == "" {
+		return nil, fmt.Errorf("invalid configuration: RPC URL is required")
+	}
+
+	rpcURL := cfg.Blockchain.RpcUrl
+	if !strings.Contains(rpcURL, "://") {
+		rpcURL = "http://" + rpcURL
+	}
+	client, err := ethclient.Dial(rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Ethereum node at %s: %w", rpcURL, err)
+	}
+
+	return &ReceiptWatcher{
+		client:        client,
+		websocket:     strings.HasPrefix(rpcURL, "ws://") || strings.HasPrefix(rpcURL, "wss://"),
+		confirmations: max(cfg.Blockchain.Confirmations, 1),
+		pollInterval:  max(cfg.Blockchain.ReceiptPollInterval, 100*time.Millisecond),
+		timeout:       cfg.Blockchain.ReceiptTimeout,
+		log:           log.With(slog.String("component", "receipts")),
+		waiters:       make(map[*receiptWaiter]struct{}),
+		kick:          make(chan struct{}, 1),
+	}, nil
+}
+
+// Wait ждет квитанцию txHash с blockchain.confirmations подтверждениями, но не дольше blockchain.receipt_timeout.
+// Если транзакция пропала из цепи после реорганизации, возвращается ErrReorged
+func (rw *ReceiptWatcher) Wait(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if rw.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rw.timeout)
+		defer cancel()
+	}
+
+	w := &receiptWaiter{hash: txHash, done: make(chan receiptResult, 1)}
+	rw.mu.Lock()
+	rw.waiters[w] = struct{}{}
+	if !rw.running {
+		rw.running = true
+		go rw.run()
+	}
+	rw.mu.Unlock()
+
+	select {
+	case rw.kick <- struct{}{}:
+	default:
+	}
+
+	select {
+	case res := <-w.done:
+		return res.receipt, res.err
+	case <-ctx.Done():
+		rw.mu.Lock()
+		delete(rw.waiters, w)
+		rw.mu.Unlock()
+		return nil, fmt.Errorf("waiting for receipt of %s: %w", txHash.Hex(), ctx.Err())
+	}
+}
+
+// run проверяет квитанции на каждом новом блоке, пока есть ожидающие
+func (rw *ReceiptWatcher) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	heads := make(chan uint64)
+	go rw.follow(ctx, heads)
+
+	for {
+		var head uint64
+		select {
+		case head = <-heads:
+		case <-rw.kick:
+			var err error
+			if head, err = rw.client.BlockNumber(ctx); err != nil {
+				rw.log.Warn("Failed to get block number", sl.Err(err))
+				continue
+			}
+		}
+		rw.check(ctx, head)
+
+		rw.mu.Lock()
+		if len(rw.waiters) == 0 {
+			rw.running = false
+			rw.mu.Unlock()
+			return
+		}
+		rw.mu.Unlock()
+	}
+}
+
+// follow отправляет номера новых блоков в heads. Если подписка недоступна или оборвалась, переходит на опрос
+func (rw *ReceiptWatcher) follow(ctx context.Context, heads chan<- uint64) {
+	if rw.websocket {
+		if err := rw.subscribe(ctx, heads); err != nil && ctx.Err() == nil {
+			rw.log.Warn("New head subscription failed, falling back to polling", sl.Err(err))
+		}
+	}
+
+	ticker := time.NewTicker(rw.pollInterval)
+	defer ticker.Stop()
+
+	var last uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		head, err := rw.client.BlockNumber(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				rw.log.Warn("Failed to get block number", sl.Err(err))
+			}
+			continue
+		}
+		if head == last {
+			continue
+		}
+		last = head
+		select {
+		case heads <- head:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (rw *ReceiptWatcher) subscribe(ctx context.Context, heads chan<- uint64) error {
+	headers := make(chan *types.Header, 16)
+	sub, err := rw.client.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-sub.Err():
+			return err
+		case header := <-headers:
+			select {
+			case heads <- header.Number.Uint64():
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// check сверяет квитанции всех ожидающих с блоком head
+func (rw *ReceiptWatcher) check(ctx context.Context, head uint64) {
+	rw.mu.Lock()
+	waiters := make([]*receiptWaiter, 0, len(rw.waiters))
+	for w := range rw.waiters {
+		waiters = append(waiters, w)
+	}
+	rw.mu.Unlock()
+
+	for _, w := range waiters {
+		receipt, err := rw.client.TransactionReceipt(ctx, w.hash)
+		switch {
+		case errors.Is(err, ethereum.NotFound):
+			if w.receipt != nil {
+				rw.finish(w, nil, fmt.Errorf("%w: %s was in block %d", ErrReorged, w.hash.Hex(), w.receipt.BlockNumber.Uint64()))
+			}
+			continue
+		case err != nil:
+			rw.log.Warn("Failed to get transaction receipt", slog.String("tx_hash", w.hash.Hex()), sl.Err(err))
+			continue
+		}
+
+		if w.receipt != nil && w.receipt.BlockHash != receipt.BlockHash {
+			rw.log.Warn("Transaction moved to another block after reorg",
+				slog.String("tx_hash", w.hash.Hex()),
+				slog.Uint64("old_block", w.receipt.BlockNumber.Uint64()),
+				slog.Uint64("new_block", receipt.BlockNumber.Uint64()))
+		}
+		w.receipt = receipt
+
+		block := receipt.BlockNumber.Uint64()
+		if head >= block && head-block+1 >= rw.confirmations {
+			rw.finish(w, receipt, nil)
+		}
+	}
+}
+
+func (rw *ReceiptWatcher) finish(w *receiptWaiter, receipt *types.Receipt, err error) {
+	rw.mu.Lock()
+	delete(rw.waiters, w)
+	rw.mu.Unlock()
+	w.done <- receiptResult{receipt: receipt, err: err}
+}
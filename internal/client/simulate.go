@@ -0,0 +1,155 @@
+package client
+
+import (
+	"apiGateway/internal/lib/amount"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Simulation - результат eth_call транзакции без отправки в сеть и оценка ее стоимости
+type Simulation struct {
+	Method    string                 `json:"method"`
+	From      string                 `json:"from"`
+	To        string                 `json:"to"`
+	Value     amount.Amount          `json:"value"`
+	Result    map[string]interface{} `json:"result,omitempty"` // Возвращаемые значения функции по именам из ABI
+	Gas       uint64                 `json:"gas"`
+	GasPrice  amount.Amount          `json:"gas_price"`
+	Cost      amount.Amount          `json:"cost"`       // gas * gas_price
+	TotalCost amount.Amount          `json:"total_cost"` // cost + value
+}
+
+// simulate выполняет вызов method через eth_call от имени from с теми же аргументами и value, что и транзакция,
+// и оценивает газ. Revert возвращается разобранным по ABI (ContractError, RevertError, PanicError)
+func simulate(ctx context.Context, c *ethclient.Client, contractABI abi.ABI, from, to common.Address, value *big.Int, method string, args ...interface{}) (Simulation, error) {
+	input, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return Simulation{}, fmt.Errorf("failed to pack %s call: %w", method, err)
+	}
+	if value == nil {
+		value = new(big.Int)
+	}
+	msg := ethereum.CallMsg{From: from, To: &to, Value: value, Data: input}
+
+	output, err := c.CallContract(ctx, msg, nil)
+	if err != nil {
+		return Simulation{}, fmt.Errorf("simulation of %s failed: %w", method, decodeRevert(err, contractABI))
+	}
+	gas, err := c.EstimateGas(ctx, msg)
+	if err != nil {
+		return Simulation{}, fmt.Errorf("failed to estimate gas for %s: %w", method, decodeRevert(err, contractABI))
+	}
+	gasPrice, err := c.SuggestGasPrice(ctx)
+	if err != nil {
+		return Simulation{}, fmt.Errorf("failed to suggest gas price: %w", err)
+	}
+
+	cost := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gas))
+	sim := Simulation{
+		Method:    method,
+		From:      strings.ToLower(from.Hex()),
+		To:        strings.ToLower(to.Hex()),
+		Value:     amount.FromWei(value),
+		Gas:       gas,
+		GasPrice:  amount.FromWei(gasPrice),
+		Cost:      amount.FromWei(cost),
+		TotalCost: amount.FromWei(new(big.Int).Add(cost, value)),
+	}
+
+	outputs := contractABI.Methods[method].Outputs
+	if len(outputs) > 0 && len(output) > 0 {
+		values, err := outputs.Unpack(output)
+		if err != nil {
+			return Simulation{}, fmt.Errorf("failed to unpack %s result: %w", method, err)
+		}
+		sim.Result = make(map[string]interface{}, len(values))
+		for i, v := range values {
+			name := outputs[i].Name
+			if name == "" {
+				name = fmt.Sprintf("out%d", i)
+			}
+			sim.Result[name] = resultValue(v)
+		}
+	}
+	return sim, nil
+}
+
+// resultValue приводит значения ABI к виду для JSON: числа - десятичными строками, адреса - hex
+func resultValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *big.Int:
+		return v.String()
+	case []*big.Int:
+		out := make([]string, len(v))
+		for i, n := range v {
+			out[i] = n.String()
+		}
+		return out
+	case common.Address:
+		return strings.ToLower(v.Hex())
+	default:
+		return v
+	}
+}
+
+// SimulateVote проверяет голос через eth_call, не отправляя транзакцию
+func (vc *VotingClient) SimulateVote(ctx context.Context, voteSessionID *big.Int, indChoice *big.Int) (Simulation, error) {
+	if vc == nil || vc.contract == nil {
+		return Simulation{}, fmt.Errorf("voting client is not properly initialized")
+	}
+	return simulate(ctx, vc.Client, vc.contractABI, vc.FromAddress, vc.contractAddr, nil, "vote", voteSessionID, indChoice)
+}
+
+// SimulateAddVoteSession проверяет создание голосования через eth_call, не отправляя транзакцию
+func (vc *VotingClient) SimulateAddVoteSession(
+	ctx context.Context,
+	title string,
+	description string,
+	startTime *big.Int,
+	endTime *big.Int,
+	minNumberVotes *big.Int,
+	isPrivate bool,
+	voters []Voter,
+	choices []string,
+) (Simulation, error) {
+	if vc == nil || vc.contract == nil {
+		return Simulation{}, fmt.Errorf("voting client is not properly initialized")
+	}
+	return simulate(ctx, vc.Client, vc.contractABI, vc.FromAddress, vc.contractAddr, nil, "addVoteSession",
+		title, description, startTime, endTime, minNumberVotes, isPrivate, voters, choices)
+}
+
+// SimulateStake проверяет стейк value wei через eth_call, не отправляя транзакцию
+func (sc *StakeClient) SimulateStake(ctx context.Context, value *big.Int) (Simulation, error) {
+	if sc == nil || sc.contract == nil {
+		return Simulation{}, fmt.Errorf("stake client is not properly initialized")
+	}
+	return simulate(ctx, sc.Client, sc.contractABI, sc.FromAddress, sc.contractAddr, value, "stake")
+}
+
+// SimulateUnstake проверяет вывод стейка через eth_call, не отправляя транзакцию (value = nil - весь стейк)
+func (sc *StakeClient) SimulateUnstake(ctx context.Context, value *big.Int) (Simulation, error) {
+	if sc == nil || sc.contract == nil {
+		return Simulation{}, fmt.Errorf("stake client is not properly initialized")
+	}
+	args, err := sc.unstakeArgs(value)
+	if err != nil {
+		return Simulation{}, err
+	}
+	return simulate(ctx, sc.Client, sc.contractABI, sc.FromAddress, sc.contractAddr, nil, "unstake", args...)
+}
+
+// SimulateGetTokens проверяет клейм наград через eth_call, не отправляя транзакцию
+func (sc *StakeClient) SimulateGetTokens(ctx context.Context) (Simulation, error) {
+	if sc == nil || sc.contract == nil {
+		return Simulation{}, fmt.Errorf("stake client is not properly initialized")
+	}
+	return simulate(ctx, sc.Client, sc.contractABI, sc.FromAddress, sc.contractAddr, nil, "getTokens")
+}
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"apiGateway/internal/lib/amount"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Типы событий стейкинга
+const (
+	StakeEventStake   = "stake"
+	StakeEventUnstake = "unstake"
+	StakeEventClaim   = "claim"
+)
+
+// События стейк-менеджера, как и view-функции, ищутся в ABI по именам
+var stakeEventNames = map[string][]string{
+	StakeEventStake:   {"Staked", "Stake", "Deposited"},
+	StakeEventUnstake: {"Unstaked", "Unstake", "Withdrawn"},
+	StakeEventClaim:   {"RewardsClaimed", "RewardClaimed", "Claimed", "TokensClaimed"},
+}
+
+// StakeEvent - стейк, вывод стейка или клейм наград из лога стейк-менеджера
+type StakeEvent struct {
+	Type        string         `json:"type"`  // stake, unstake или claim
+	Event       string         `json:"event"` // Имя события в ABI
+	Address     string         `json:"address"`
+	Amount      *amount.Amount `json:"amount,omitempty"` // Сумма стейка или вывода
+	Reward      string         `json:"reward,omitempty"` // Награда клейма в минимальных единицах токена
+	TxHash      string         `json:"tx_hash"`
+	LogIndex    uint           `json:"log_index"`
+	BlockNumber uint64         `json:"block_number"`
+	BlockTime   time.Time      `json:"block_time"`
+}
+
+// stakeEventDefs возвращает найденные в ABI события по их ID
+func (sc *StakeClient) stakeEventDefs() map[common.Hash]stakeEventDef {
+	defs := make(map[common.Hash]stakeEventDef)
+	for kind, names := range stakeEventNames {
+		for _, name := range names {
+			if event, ok := sc.contractABI.Events[name]; ok {
+				defs[event.ID] = stakeEventDef{kind: kind, event: event}
+				break
+			}
+		}
+	}
+	return defs
+}
+
+type stakeEventDef struct {
+	kind  string
+	event abi.Event
+}
+
+// HeadBlock возвращает номер последнего блока
+func (sc *StakeClient) HeadBlock(ctx context.Context) (uint64, error) {
+	block, err := sc.Client.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get block number: %w", err)
+	}
+	return block, nil
+}
+
+// StakeEvents читает события стейкинга в блоках from..to одним запросом eth_getLogs.
+// Окна блоков выбирает вызывающий (blockchain.logs_block_range)
+func (sc *StakeClient) StakeEvents(ctx context.Context, from, to uint64) ([]StakeEvent, error) {
+	if sc == nil || sc.contract == nil {
+		return nil, fmt.Errorf("stake client is not properly initialized")
+	}
+	defs := sc.stakeEventDefs()
+	if len(defs) == 0 {
+		return nil, fmt.Errorf("stake manager ABI has no stake, unstake or claim events")
+	}
+
+	ids := make([]common.Hash, 0, len(defs))
+	for id := range defs {
+		ids = append(ids, id)
+	}
+	logs, err := sc.Client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{sc.contractAddr},
+		Topics:    [][]common.Hash{ids},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter stake manager logs in blocks %d-%d: %w", from, to, err)
+	}
+
+	events := make([]StakeEvent, 0, len(logs))
+	blockTimes := make(map[uint64]time.Time)
+	for _, l := range logs {
+		if l.Removed || len(l.Topics) == 0 {
+			continue
+		}
+		def, ok := defs[l.Topics[0]]
+		if !ok {
+			continue
+		}
+		event, err := decodeStakeEvent(def, l)
+		if err != nil {
+			return nil, err
+		}
+
+		blockTime, ok := blockTimes[l.BlockNumber]
+		if !ok {
+			header, err := sc.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(l.BlockNumber))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get block %d header: %w", l.BlockNumber, err)
+			}
+			blockTime = time.Unix(int64(header.Time), 0).UTC()
+			blockTimes[l.BlockNumber] = blockTime
+		}
+		event.BlockTime = blockTime
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// decodeStakeEvent берет из лога первый адрес (стейкер) и первое число (сумма), индексированные или нет
+func decodeStakeEvent(def stakeEventDef, l types.Log) (StakeEvent, error) {
+	values, err := def.event.Inputs.NonIndexed().Unpack(l.Data)
+	if err != nil {
+		return StakeEvent{}, fmt.Errorf("failed to unpack %s log %s: %w", def.event.Name, l.TxHash.Hex(), err)
+	}
+
+	event := StakeEvent{
+		Type:        def.kind,
+		Event:       def.event.Name,
+		TxHash:      l.TxHash.Hex(),
+		LogIndex:    l.Index,
+		BlockNumber: l.BlockNumber,
+	}
+
+	var staker *common.Address
+	var value *big.Int
+	topic, data := 1, 0
+	for _, input := range def.event.Inputs {
+		var v interface{}
+		if input.Indexed {
+			if topic >= len(l.Topics) {
+				break
+			}
+			switch input.Type.T {
+			case abi.AddressTy:
+				v = common.BytesToAddress(l.Topics[topic].Bytes())
+			case abi.UintTy:
+				v = new(big.Int).SetBytes(l.Topics[topic].Bytes())
+			}
+			topic++
+		} else if data < len(values) {
+			v = values[data]
+			data++
+		}
+
+		switch v := v.(type) {
+		case common.Address:
+			if staker == nil {
+				staker = &v
+			}
+		case *big.Int:
+			if value == nil {
+				value = v
+			}
+		}
+	}
+
+	if staker == nil {
+		return StakeEvent{}, fmt.Errorf("%s log %s has no address", def.event.Name, l.TxHash.Hex())
+	}
+	event.Address = strings.ToLower(staker.Hex())
+	if value != nil {
+		if def.kind == StakeEventClaim {
+			event.Reward = value.String()
+		} else {
+			v := amount.FromWei(value)
+			event.Amount = &v
+		}
+	}
+	return event, nil
+}
@@ -0,0 +1,281 @@
+package client
+
+import (
+	"apiGateway/contracts/erc20"
+	"apiGateway/internal/lib/amount"
+	"apiGateway/internal/rewards"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ABI стейк-менеджера грузится из артефакта сборки контракта, поэтому view-функции ищутся по именам:
+// для каждого поля берется первое имя, которое есть в ABI. Если ни одного нет, поле в ответе пустое.
+var (
+	stakedAmountViews  = []string{"stakedAmount", "stakeOf", "getStake", "stakes"}
+	stakeStartViews    = []string{"stakeStart", "stakeTimestamp", "stakingStartTime"}
+	pendingRewardViews = []string{"pendingRewards", "earned", "calculateReward"}
+	nextClaimTimeViews = []string{"nextClaimTime", "nextClaimAt"}
+	lastClaimTimeViews = []string{"lastClaimTime", "lastClaim"}
+	claimCooldownViews = []string{"claimCooldown", "cooldown", "COOLDOWN", "CLAIM_COOLDOWN"}
+	rewardRateViews    = []string{"rewardRate", "rewardPerSecond", "rewardsPerSecond", "REWARD_RATE"}
+	totalStakedViews   = []string{"totalStaked", "totalStake", "totalStakedAmount"}
+	erc20ABI           = sync.OnceValues(erc20.GetABI)
+)
+
+// StakePosition - состояние стейка адреса на блоке Block
+type StakePosition struct {
+	Address        string         `json:"address"`
+	Block          uint64         `json:"block"`
+	Staked         *amount.Amount `json:"staked,omitempty"` // nil, если в ABI нет view-функции суммы стейка
+	StakeStart     *time.Time     `json:"stake_start,omitempty"`
+	PendingRewards string         `json:"pending_rewards,omitempty"` // В минимальных единицах токена наград
+	NextClaimAt    *time.Time     `json:"next_claim_at,omitempty"`
+	CanClaim       bool           `json:"can_claim"`
+
+	ContractTokenBalance string `json:"contract_token_balance,omitempty"` // Токены наград на контракте, в минимальных единицах
+}
+
+// stakeViewCache - позиции, прочитанные на последнем блоке. Новый блок сбрасывает кэш
+type stakeViewCache struct {
+	mu        sync.Mutex
+	block     uint64
+	positions map[common.Address]StakePosition
+}
+
+// StakePosition читает стейк address view-вызовами стейк-менеджера.
+// Все вызовы идут на одном блоке; результат кэшируется до следующего блока
+func (sc *StakeClient) StakePosition(ctx context.Context, address common.Address) (StakePosition, error) {
+	if sc == nil || sc.contract == nil {
+		return StakePosition{}, fmt.Errorf("stake client is not properly initialized")
+	}
+
+	block, err := sc.Client.BlockNumber(ctx)
+	if err != nil {
+		return StakePosition{}, fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	sc.views.mu.Lock()
+	if sc.views.block == block {
+		if pos, ok := sc.views.positions[address]; ok {
+			sc.views.mu.Unlock()
+			return pos, nil
+		}
+	}
+	sc.views.mu.Unlock()
+
+	pos, err := sc.readStakePosition(ctx, address, block)
+	if err != nil {
+		return StakePosition{}, err
+	}
+
+	sc.views.mu.Lock()
+	if sc.views.block != block || sc.views.positions == nil {
+		sc.views.block = block
+		sc.views.positions = make(map[common.Address]StakePosition)
+	}
+	sc.views.positions[address] = pos
+	sc.views.mu.Unlock()
+	return pos, nil
+}
+
+func (sc *StakeClient) readStakePosition(ctx context.Context, address common.Address, block uint64) (StakePosition, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(block)}
+	pos := StakePosition{Address: address.Hex(), Block: block}
+
+	staked, err := sc.callUint(opts, stakedAmountViews, address)
+	if err != nil {
+		return StakePosition{}, err
+	}
+	if staked != nil {
+		v := amount.FromWei(staked)
+		pos.Staked = &v
+	}
+
+	if start, err := sc.callUint(opts, stakeStartViews, address); err != nil {
+		return StakePosition{}, err
+	} else if start != nil && start.Sign() > 0 {
+		t := time.Unix(start.Int64(), 0).UTC()
+		pos.StakeStart = &t
+	}
+
+	if rewards, err := sc.callUint(opts, pendingRewardViews, address); err != nil {
+		return StakePosition{}, err
+	} else if rewards != nil {
+		pos.PendingRewards = rewards.String()
+	}
+
+	nextClaim, err := sc.nextClaimTime(opts, address)
+	if err != nil {
+		return StakePosition{}, err
+	}
+	pos.NextClaimAt = nextClaim
+
+	header, err := sc.Client.HeaderByNumber(ctx, opts.BlockNumber)
+	if err != nil {
+		return StakePosition{}, fmt.Errorf("failed to get block %d header: %w", block, err)
+	}
+	blockTime := time.Unix(int64(header.Time), 0).UTC()
+	pos.CanClaim = (pos.Staked == nil || pos.Staked.Sign() > 0) && (pos.NextClaimAt == nil || !blockTime.Before(*pos.NextClaimAt))
+
+	if balance, err := sc.contractTokenBalance(opts); err != nil {
+		sc.log.Warn("Failed to read reward token balance of stake manager", slog.Any("error", err))
+	} else {
+		pos.ContractTokenBalance = balance.String()
+	}
+	return pos, nil
+}
+
+// RewardParams читает параметры наград стейк-менеджера и общий стейк для расчета прогноза.
+// Без view-функции скорости наград прогноз невозможен - возвращается rewards.ErrNoRewardRate
+func (sc *StakeClient) RewardParams(ctx context.Context) (rewards.Params, error) {
+	if sc == nil || sc.contract == nil {
+		return rewards.Params{}, fmt.Errorf("stake client is not properly initialized")
+	}
+
+	block, err := sc.Client.BlockNumber(ctx)
+	if err != nil {
+		return rewards.Params{}, fmt.Errorf("failed to get block number: %w", err)
+	}
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(block)}
+
+	rate, err := sc.callUint(opts, rewardRateViews)
+	if err != nil {
+		return rewards.Params{}, err
+	}
+	if rate == nil {
+		return rewards.Params{}, fmt.Errorf("%w: stake manager ABI has none of %v", rewards.ErrNoRewardRate, rewardRateViews)
+	}
+	params := rewards.Params{RewardRate: rate}
+
+	// Если общего стейка нет среди view-функций, им считается баланс ETH контракта
+	if params.TotalStaked, err = sc.callUint(opts, totalStakedViews); err != nil {
+		return rewards.Params{}, err
+	}
+	if params.TotalStaked == nil {
+		if params.TotalStaked, err = sc.Client.BalanceAt(ctx, sc.contractAddr, opts.BlockNumber); err != nil {
+			return rewards.Params{}, fmt.Errorf("failed to get stake manager balance: %w", err)
+		}
+	}
+
+	if cooldown, err := sc.callUint(opts, claimCooldownViews); err != nil {
+		return rewards.Params{}, err
+	} else if cooldown != nil {
+		params.Cooldown = time.Duration(cooldown.Int64()) * time.Second
+	}
+
+	if params.RewardDecimals, err = sc.rewardTokenDecimals(opts); err != nil {
+		return rewards.Params{}, err
+	}
+	return params, nil
+}
+
+// rewardTokenDecimals - decimals токена наград
+func (sc *StakeClient) rewardTokenDecimals(opts *bind.CallOpts) (uint8, error) {
+	token, err := sc.rewardToken()
+	if err != nil {
+		return 0, err
+	}
+	var out []interface{}
+	if err := token.Call(opts, &out, "decimals"); err != nil {
+		return 0, fmt.Errorf("contract call 'decimals' failed: %w", err)
+	}
+	if len(out) == 0 {
+		return 0, fmt.Errorf("empty result for decimals")
+	}
+	decimals, ok := out[0].(uint8)
+	if !ok {
+		return 0, fmt.Errorf("unexpected type in result for decimals: %T", out[0])
+	}
+	return decimals, nil
+}
+
+// nextClaimTime берет время следующего клейма из контракта, а если такой view-функции нет,
+// считает его как время последнего клейма плюс кулдаун
+func (sc *StakeClient) nextClaimTime(opts *bind.CallOpts, address common.Address) (*time.Time, error) {
+	next, err := sc.callUint(opts, nextClaimTimeViews, address)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		last, err := sc.callUint(opts, lastClaimTimeViews, address)
+		if err != nil || last == nil {
+			return nil, err
+		}
+		cooldown, err := sc.callUint(opts, claimCooldownViews)
+		if err != nil || cooldown == nil {
+			return nil, err
+		}
+		next = new(big.Int).Add(last, cooldown)
+	}
+	if next.Sign() <= 0 {
+		return nil, nil
+	}
+	t := time.Unix(next.Int64(), 0).UTC()
+	return &t, nil
+}
+
+// contractTokenBalance - баланс токена наград (blockchain.token_contract_address) на стейк-менеджере
+func (sc *StakeClient) contractTokenBalance(opts *bind.CallOpts) (*big.Int, error) {
+	token, err := sc.rewardToken()
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	if err := token.Call(opts, &out, "balanceOf", sc.contractAddr); err != nil {
+		return nil, fmt.Errorf("contract call 'balanceOf' failed: %w", err)
+	}
+	return firstUint(out, "balanceOf")
+}
+
+func (sc *StakeClient) rewardToken() (*bind.BoundContract, error) {
+	if !common.IsHexAddress(sc.cfg.Blockchain.TokenContractAddress) {
+		return nil, fmt.Errorf("invalid token contract address: %s", sc.cfg.Blockchain.TokenContractAddress)
+	}
+	tokenABI, err := erc20ABI()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(common.HexToAddress(sc.cfg.Blockchain.TokenContractAddress), tokenABI, sc.Client, sc.Client, sc.Client), nil
+}
+
+// callUint вызывает первую из candidates, которая есть в ABI и принимает столько же аргументов.
+// Возвращает nil без ошибки, если подходящей функции нет
+func (sc *StakeClient) callUint(opts *bind.CallOpts, candidates []string, args ...interface{}) (*big.Int, error) {
+	for _, name := range candidates {
+		method, ok := sc.contractABI.Methods[name]
+		if !ok || len(method.Inputs) != len(args) || len(method.Outputs) == 0 {
+			continue
+		}
+
+		var out []interface{}
+		if err := sc.contract.Call(opts, &out, name, args...); err != nil {
+			return nil, fmt.Errorf("contract call '%s' failed: %w", name, decodeRevert(err, sc.contractABI))
+		}
+		return firstUint(out, name)
+	}
+	return nil, nil
+}
+
+// firstUint достает первое числовое значение результата; для геттера структуры это ее первое поле
+func firstUint(out []interface{}, method string) (*big.Int, error) {
+	if len(out) == 0 {
+		return nil, fmt.Errorf("empty result for %s", method)
+	}
+	switch v := out[0].(type) {
+	case *big.Int:
+		return v, nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case uint32:
+		return big.NewInt(int64(v)), nil
+	default:
+		return nil, fmt.Errorf("unexpected type in result for %s: %T", method, out[0])
+	}
+}
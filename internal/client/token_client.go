@@ -0,0 +1,243 @@
+package client
+
+import (
+	"apiGateway/contracts/erc20"
+	"apiGateway/internal/config"
+	"apiGateway/internal/lib/amount"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// TokenClient читает токен наград ERC-20 (blockchain.token_contract_address)
+type TokenClient struct {
+	Client       *ethclient.Client
+	contract     *bind.BoundContract
+	contractABI  abi.ABI
+	contractAddr common.Address
+	stakeManager common.Address // Награды приходят переводами с этого адреса
+	cfg          *config.Config
+	log          *slog.Logger
+
+	// Название, символ и decimals токена не меняются, поэтому читаются один раз
+	infoMu sync.Mutex
+	info   *TokenInfo
+}
+
+// TokenInfo - метаданные токена
+type TokenInfo struct {
+	Address     string      `json:"address"`
+	Name        string      `json:"name"`
+	Symbol      string      `json:"symbol"`
+	Decimals    uint8       `json:"decimals"`
+	TotalSupply TokenAmount `json:"total_supply"`
+}
+
+// TokenAmount - сумма токена в минимальных единицах и с учетом decimals
+type TokenAmount struct {
+	Raw       string `json:"raw"`
+	Formatted string `json:"formatted"`
+}
+
+// TokenTransfer - перевод токена из лога Transfer
+type TokenTransfer struct {
+	From        string      `json:"from"`
+	To          string      `json:"to"`
+	Value       TokenAmount `json:"value"`
+	TxHash      string      `json:"tx_hash"`
+	BlockNumber uint64      `json:"block_number"`
+	BlockTime   time.Time   `json:"block_time"`
+}
+
+func NewTokenClient(cfg *config.Config, log *slog.Logger) (*TokenClient, error) {
+	if cfg == nil || cfg.Blockchain.RpcUrl == "" {
+		return nil, fmt.Errorf("invalid configuration: RPC URL is required")
+	}
+	if !common.IsHexAddress(cfg.Blockchain.TokenContractAddress) {
+		return nil, fmt.Errorf("invalid token contract address format: %s", cfg.Blockchain.TokenContractAddress)
+	}
+
+	rpcURL := cfg.Blockchain.RpcUrl
+	if !strings.Contains(rpcURL, "://") {
+		rpcURL = "http://" + rpcURL
+	}
+	client, err := ethclient.Dial(rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Ethereum node at %s: %w", rpcURL, err)
+	}
+
+	contractABI, err := erc20.GetABI()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ERC-20 ABI: %w", err)
+	}
+	contractAddr := common.HexToAddress(cfg.Blockchain.TokenContractAddress)
+
+	return &TokenClient{
+		Client:       client,
+		contract:     bind.NewBoundContract(contractAddr, contractABI, client, client, client),
+		contractABI:  contractABI,
+		contractAddr: contractAddr,
+		stakeManager: common.HexToAddress(cfg.Blockchain.StakeManagerContractAddress),
+		cfg:          cfg,
+		log:          log,
+	}, nil
+}
+
+// Info возвращает метаданные токена; общее предложение читается при каждом вызове
+func (tc *TokenClient) Info(ctx context.Context) (TokenInfo, error) {
+	info, err := tc.metadata(ctx)
+	if err != nil {
+		return TokenInfo{}, err
+	}
+
+	supply, err := tc.callUint(ctx, "totalSupply")
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	info.TotalSupply = tc.amount(supply, info.Decimals)
+	return info, nil
+}
+
+// BalanceOf возвращает баланс токена address
+func (tc *TokenClient) BalanceOf(ctx context.Context, address common.Address) (TokenAmount, error) {
+	info, err := tc.metadata(ctx)
+	if err != nil {
+		return TokenAmount{}, err
+	}
+	balance, err := tc.callUint(ctx, "balanceOf", address)
+	if err != nil {
+		return TokenAmount{}, err
+	}
+	return tc.amount(balance, info.Decimals), nil
+}
+
+// RewardTransfers возвращает переводы токена со стейк-менеджера на address - то, что фактически выплатил GetTokens.
+// Логи читаются с blockchain.logs_from_block окнами по blockchain.logs_block_range блоков
+func (tc *TokenClient) RewardTransfers(ctx context.Context, address common.Address) ([]TokenTransfer, error) {
+	info, err := tc.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	head, err := tc.Client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	transferEvent := tc.contractABI.Events["Transfer"]
+	query := ethereum.FilterQuery{
+		Addresses: []common.Address{tc.contractAddr},
+		Topics: [][]common.Hash{
+			{transferEvent.ID},
+			{common.BytesToHash(tc.stakeManager.Bytes())},
+			{common.BytesToHash(address.Bytes())},
+		},
+	}
+
+	transfers := []TokenTransfer{}
+	blockTimes := make(map[uint64]time.Time)
+	step := max(tc.cfg.Blockchain.LogsBlockRange, 1)
+	for from := tc.cfg.Blockchain.LogsFromBlock; from <= head; from += step {
+		query.FromBlock = new(big.Int).SetUint64(from)
+		query.ToBlock = new(big.Int).SetUint64(min(from+step-1, head))
+
+		logs, err := tc.Client.FilterLogs(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter Transfer logs in blocks %d-%d: %w", from, query.ToBlock.Uint64(), err)
+		}
+		for _, l := range logs {
+			if l.Removed || len(l.Topics) < 3 {
+				continue
+			}
+			values, err := transferEvent.Inputs.NonIndexed().Unpack(l.Data)
+			if err != nil || len(values) == 0 {
+				return nil, fmt.Errorf("failed to unpack Transfer log %s: %v", l.TxHash.Hex(), err)
+			}
+			value, _ := values[0].(*big.Int)
+
+			blockTime, ok := blockTimes[l.BlockNumber]
+			if !ok {
+				header, err := tc.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(l.BlockNumber))
+				if err != nil {
+					return nil, fmt.Errorf("failed to get block %d header: %w", l.BlockNumber, err)
+				}
+				blockTime = time.Unix(int64(header.Time), 0).UTC()
+				blockTimes[l.BlockNumber] = blockTime
+			}
+
+			transfers = append(transfers, TokenTransfer{
+				From:        strings.ToLower(common.BytesToAddress(l.Topics[1].Bytes()).Hex()),
+				To:          strings.ToLower(common.BytesToAddress(l.Topics[2].Bytes()).Hex()),
+				Value:       tc.amount(value, info.Decimals),
+				TxHash:      l.TxHash.Hex(),
+				BlockNumber: l.BlockNumber,
+				BlockTime:   blockTime,
+			})
+		}
+	}
+	return transfers, nil
+}
+
+func (tc *TokenClient) metadata(ctx context.Context) (TokenInfo, error) {
+	tc.infoMu.Lock()
+	defer tc.infoMu.Unlock()
+	if tc.info != nil {
+		return *tc.info, nil
+	}
+
+	info := TokenInfo{Address: strings.ToLower(tc.contractAddr.Hex())}
+	for method, dst := range map[string]*string{"name": &info.Name, "symbol": &info.Symbol} {
+		out, err := tc.call(ctx, method)
+		if err != nil {
+			return TokenInfo{}, err
+		}
+		*dst, _ = out[0].(string)
+	}
+	out, err := tc.call(ctx, "decimals")
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	decimals, ok := out[0].(uint8)
+	if !ok {
+		return TokenInfo{}, fmt.Errorf("unexpected type in result for decimals: %T", out[0])
+	}
+	info.Decimals = decimals
+
+	tc.info = &info
+	return info, nil
+}
+
+func (tc *TokenClient) call(ctx context.Context, method string, args ...interface{}) ([]interface{}, error) {
+	var out []interface{}
+	if err := tc.contract.Call(&bind.CallOpts{Context: ctx}, &out, method, args...); err != nil {
+		return nil, fmt.Errorf("contract call '%s' failed: %w", method, decodeRevert(err, tc.contractABI))
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("empty result for %s", method)
+	}
+	return out, nil
+}
+
+func (tc *TokenClient) callUint(ctx context.Context, method string, args ...interface{}) (*big.Int, error) {
+	out, err := tc.call(ctx, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return firstUint(out, method)
+}
+
+func (tc *TokenClient) amount(v *big.Int, decimals uint8) TokenAmount {
+	if v == nil {
+		v = new(big.Int)
+	}
+	return TokenAmount{Raw: v.String(), Formatted: amount.Format(v, int(decimals))}
+}
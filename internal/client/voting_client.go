@@ -19,6 +19,7 @@ import (
 
 	"apiGateway/contracts/voting"
 	"apiGateway/internal/config"
+	"apiGateway/internal/lib/amount"
 )
 
 // Voter и VoteAccess - скопируйте их из вашего контракта Solidity или создайте их аналоги на Go
@@ -55,6 +56,8 @@ type VotingClient struct {
 	publicKeyECDSA *ecdsa.PublicKey
 	FromAddress    common.Address
 	log            *slog.Logger // Добавляем логгер
+
+	Receipts *ReceiptWatcher // Ожидание квитанций; без него используется bind.WaitMined
 }
 
 type StakeClient struct {
@@ -69,6 +72,7 @@ type StakeClient struct {
 	publicKey      common.Address
 	FromAddress    common.Address
 	log            *slog.Logger // Добавляем логгер
+	views          stakeViewCache
 }
 
 func NewVotingClient(cfg *config.Config, log *slog.Logger) (*VotingClient, error) {
@@ -240,7 +244,7 @@ func (vc *VotingClient) GetVotingParticipatedByAddress(address string) ([]*big.I
 	)
 	if err != nil {
 		vc.log.Error("Contract call 'getVotingParticipatedByAddress' failed", slog.String("address", address), slog.Any("error", err))
-		return nil, fmt.Errorf("contract call failed: %w", err) // Используем %w для оборачивания ошибки
+		return nil, fmt.Errorf("contract call failed: %w", decodeRevert(err, vc.contractABI)) // Используем %w для оборачивания ошибки
 	}
 
 	if len(rawResult) == 0 {
@@ -268,6 +272,11 @@ func (vc *VotingClient) AddVoteSession(
 	voters []Voter,
 	choices []string,
 ) (*big.Int, common.Address, common.Hash, error) { // Возвращаемые значения
+	// Транзакция, которую отклонит контракт, не отправляется: revert виден сразу, газ не тратится
+	if _, err := vc.SimulateAddVoteSession(context.Background(), title, description, startTime, endTime, minNumberVotes, isPrivate, voters, choices); err != nil {
+		return nil, common.Address{}, common.Hash{}, err
+	}
+
 	nonce, err := vc.Client.PendingNonceAt(context.Background(), vc.FromAddress)
 	if err != nil {
 		return nil, common.Address{}, common.Hash{}, fmt.Errorf("failed to get nonce: %w", err)
@@ -303,7 +312,7 @@ func (vc *VotingClient) AddVoteSession(
 		choices,
 	)
 	if err != nil {
-		return nil, common.Address{}, common.Hash{}, fmt.Errorf("failed to send transaction: %w", err)
+		return nil, common.Address{}, common.Hash{}, fmt.Errorf("failed to send transaction: %w", decodeRevert(err, vc.contractABI))
 	}
 
 	vc.log.Info("Waiting for AddVoteSession transaction to be mined...", slog.String("tx_hash", tx.Hash().Hex()))
@@ -311,7 +320,12 @@ func (vc *VotingClient) AddVoteSession(
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	receipt, err := bind.WaitMined(ctx, vc.Client, tx)
+	var receipt *types.Receipt
+	if vc.Receipts != nil {
+		receipt, err = vc.Receipts.Wait(ctx, tx.Hash())
+	} else {
+		receipt, err = bind.WaitMined(ctx, vc.Client, tx)
+	}
 	if err != nil {
 		return nil, common.Address{}, tx.Hash(), fmt.Errorf("failed to mine transaction %s: %w", tx.Hash().Hex(), err)
 	}
@@ -382,7 +396,7 @@ func (vc *VotingClient) GetVotingCreatedByAddress(address string) ([]*big.Int, e
 	)
 	if err != nil {
 		vc.log.Error("Contract call 'getVotingCreatedByAddress' failed", slog.String("address", address), slog.Any("error", err))
-		return nil, fmt.Errorf("contract call failed: %w", err)
+		return nil, fmt.Errorf("contract call failed: %w", decodeRevert(err, vc.contractABI))
 	}
 
 	if len(rawResult) == 0 {
@@ -400,6 +414,10 @@ func (vc *VotingClient) GetVotingCreatedByAddress(address string) ([]*big.Int, e
 
 // Vote вызывает функцию vote из контракта Voting.sol
 func (vc *VotingClient) Vote(voteSessionID *big.Int, indChoice *big.Int) (common.Hash, error) {
+	if _, err := vc.SimulateVote(context.Background(), voteSessionID, indChoice); err != nil {
+		return common.Hash{}, err
+	}
+
 	nonce, err := vc.Client.PendingNonceAt(context.Background(), vc.FromAddress)
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("failed to get nonce: %w", err)
@@ -427,7 +445,7 @@ func (vc *VotingClient) Vote(voteSessionID *big.Int, indChoice *big.Int) (common
 
 	tx, err := vc.contract.Transact(auth, "vote", voteSessionID, indChoice)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("failed to send vote transaction: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to send vote transaction: %w", decodeRevert(err, vc.contractABI))
 	}
 
 	vc.log.Info("Vote transaction sent", slog.String("tx_hash", tx.Hash().Hex()))
@@ -435,8 +453,46 @@ func (vc *VotingClient) Vote(voteSessionID *big.Int, indChoice *big.Int) (common
 	return tx.Hash(), nil
 }
 
+// stakeGasLimit - лимит газа для функции stake
+const stakeGasLimit = uint64(300000)
+
+// ErrInsufficientBalance - на кошельке шлюза не хватает ETH на сумму и газ
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// Balance возвращает баланс кошелька, от имени которого шлюз отправляет транзакции
+func (sc *StakeClient) Balance(ctx context.Context) (amount.Amount, error) {
+	wei, err := sc.Client.BalanceAt(ctx, sc.FromAddress, nil)
+	if err != nil {
+		return amount.Amount{}, fmt.Errorf("failed to get balance of %s: %w", sc.FromAddress.Hex(), err)
+	}
+	return amount.FromWei(wei), nil
+}
+
+// CheckStakeBalance проверяет, что баланса хватает на stake суммы value с учетом максимальной стоимости газа.
+// Возвращает баланс; при нехватке средств ошибка оборачивает ErrInsufficientBalance
+func (sc *StakeClient) CheckStakeBalance(ctx context.Context, value amount.Amount) (amount.Amount, error) {
+	balance, err := sc.Balance(ctx)
+	if err != nil {
+		return amount.Amount{}, err
+	}
+	gasPrice, err := sc.Client.SuggestGasPrice(ctx)
+	if err != nil {
+		return balance, fmt.Errorf("failed to suggest gas price: %w", err)
+	}
+
+	required := value.Add(amount.FromWei(new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(stakeGasLimit))))
+	if balance.Cmp(required) < 0 {
+		return balance, fmt.Errorf("%w: balance %s, required %s including gas", ErrInsufficientBalance, balance, required)
+	}
+	return balance, nil
+}
+
 // Stake вызывает функцию stake из контракта, отправляя ETH
 func (sc *StakeClient) Stake(amount *big.Int) (common.Hash, error) {
+	if _, err := sc.SimulateStake(context.Background(), amount); err != nil {
+		return common.Hash{}, err
+	}
+
 	nonce, err := sc.Client.PendingNonceAt(context.Background(), sc.FromAddress)
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("failed to get nonce: %w", err)
@@ -453,8 +509,8 @@ func (sc *StakeClient) Stake(amount *big.Int) (common.Hash, error) {
 	}
 
 	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = amount            // <--- Самое важное: прикрепляем ETH к транзакции
-	auth.GasLimit = uint64(300000) // Лимит газа для функции stake
+	auth.Value = amount // <--- Самое важное: прикрепляем ETH к транзакции
+	auth.GasLimit = stakeGasLimit
 	auth.GasPrice = gasPrice
 
 	sc.log.Info("Preparing to send stake transaction",
@@ -463,7 +519,7 @@ func (sc *StakeClient) Stake(amount *big.Int) (common.Hash, error) {
 
 	tx, err := sc.contract.Transact(auth, "stake") // Вызываем функцию stake без аргументов
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("failed to send stake transaction: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to send stake transaction: %w", decodeRevert(err, sc.contractABI))
 	}
 
 	sc.log.Info("Stake transaction sent", slog.String("tx_hash", tx.Hash().Hex()))
@@ -471,8 +527,23 @@ func (sc *StakeClient) Stake(amount *big.Int) (common.Hash, error) {
 	return tx.Hash(), nil
 }
 
-// Unstake отправляет транзакцию для вывода застейканного ETH
-func (sc *StakeClient) Unstake() (common.Hash, error) {
+// ErrPartialUnstakeUnsupported - контракт умеет выводить только весь стейк (unstake() без аргументов)
+var ErrPartialUnstakeUnsupported = errors.New("stake manager contract does not support partial unstake")
+
+// SupportsPartialUnstake сообщает, принимает ли unstake в ABI контракта сумму (unstake(uint256)).
+// После обновления контракта достаточно подложить новый ABI, код менять не нужно
+func (sc *StakeClient) SupportsPartialUnstake() bool {
+	method, ok := sc.contractABI.Methods["unstake"]
+	return ok && len(method.Inputs) == 1
+}
+
+// Unstake отправляет транзакцию для вывода застейканного ETH кошелька шлюза.
+// value = nil выводит весь стейк; сумму можно указать, только если контракт поддерживает частичный вывод
+func (sc *StakeClient) Unstake(value *big.Int) (common.Hash, error) {
+	if _, err := sc.SimulateUnstake(context.Background(), value); err != nil {
+		return common.Hash{}, err
+	}
+
 	chainID, err := sc.Client.ChainID(context.Background())
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("failed to get chain ID: %w", err)
@@ -482,19 +553,40 @@ func (sc *StakeClient) Unstake() (common.Hash, error) {
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("failed to create transactor: %w", err)
 	}
-	tx, err := sc.contract.Transact(auth, "unstake") // Используем Auth, который был инициализирован в NewVotingClient
+
+	args, _ := sc.unstakeArgs(value)
+	tx, err := sc.contract.Transact(auth, "unstake", args...)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("failed to send unstake transaction: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to send unstake transaction: %w", decodeRevert(err, sc.contractABI))
 	}
 
 	return tx.Hash(), nil
 }
 
+// unstakeArgs возвращает аргументы unstake для ABI контракта: сумму для unstake(uint256)
+// и ничего для unstake() без аргументов
+func (sc *StakeClient) unstakeArgs(value *big.Int) ([]interface{}, error) {
+	partial := sc.SupportsPartialUnstake()
+	if value != nil && !partial {
+		return nil, ErrPartialUnstakeUnsupported
+	}
+	if value == nil && partial {
+		return nil, fmt.Errorf("unstake amount is required by the stake manager contract")
+	}
+	if partial {
+		return []interface{}{value}, nil
+	}
+	return nil, nil
+}
+
 // GetTokens отправляет транзакцию для получения токенов (наград)
 func (sc *StakeClient) GetTokens(ctx context.Context) (*types.Transaction, error) { // Убрали stakerAddress, т.к. он из PrivateKey
 	if sc == nil {
 		return nil, errors.New("stake client is nil")
 	}
+	if _, err := sc.SimulateGetTokens(ctx); err != nil {
+		return nil, err
+	}
 
 	nonce, err := sc.Client.PendingNonceAt(ctx, sc.publicKey)
 	if err != nil {
@@ -519,11 +611,12 @@ func (sc *StakeClient) GetTokens(ctx context.Context) (*types.Transaction, error
 	auth.Nonce = big.NewInt(int64(nonce))
 	auth.Value = big.NewInt(0) // Эта функция не отправляет ETH
 	auth.GasLimit = 300000     // Адекватный лимит газа
+	auth.Context = ctx
 	auth.GasPrice = gasPrice
 
-	tx, err := sc.GetTokens(context.Background())
+	tx, err := sc.contract.Transact(auth, "getTokens")
 	if err != nil {
-		return nil, fmt.Errorf("failed to send getTokens transaction: %w", err)
+		return nil, fmt.Errorf("failed to send getTokens transaction: %w", decodeRevert(err, sc.contractABI))
 	}
 
 	sc.log.Info("GetTokens transaction sent", "tx_hash", tx.Hash().Hex(), "from_address", sc.publicKey.Hex())
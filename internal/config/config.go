@@ -13,18 +13,93 @@ type Config struct {
 	HTTPServer HTTPServer `yaml:"http_server"`
 	Kafka      Kafka      `yaml:"kafka"`
 	Blockchain Blockchain `yaml:"blockchain"`
+	Refresh    Refresh    `yaml:"refresh"`
+}
+
+// Refresh - как часто читающие эндпоинты просят Java-сервис обновить голосования
+type Refresh struct {
+	StalenessBudget time.Duration `yaml:"staleness_budget" env-default:"10s"` // Данные моложе этого отдаются без обновления
+	MinInterval     time.Duration `yaml:"min_interval" env-default:"2s"`      // Не чаще одного триггера на голосование за интервал
+	HotInterval     time.Duration `yaml:"hot_interval" env-default:"5s"`      // Период фонового обновления часто читаемых голосований
+	HotThreshold    int           `yaml:"hot_threshold" env-default:"5"`      // Сколько чтений за период делают голосование часто читаемым
 }
 
 type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8062"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	AdminToken  string        `yaml:"admin_token" env:"ADMIN_TOKEN"` // Bearer-токен для /admin/*; пусто - служебные эндпоинты выключены
 }
 
 type Kafka struct {
-	Brokers         []string `yaml:"brokers" env-required:"true"`
-	GroupID         string   `yaml:"group_id" env-default:"voting-service"`
-	AutoOffsetReset string   `yaml:"auto_offset_reset" env-default:"earliest"`
+	Driver          string    `yaml:"driver" env:"KAFKA_DRIVER" env-default:"kafka"` // kafka или memory (брокер в памяти, без Kafka)
+	Brokers         []string  `yaml:"brokers"`                                       // Обязательны для драйвера kafka
+	GroupID         string    `yaml:"group_id" env-default:"voting-service"`
+	AutoOffsetReset string    `yaml:"auto_offset_reset" env-default:"earliest"`
+	Encoding        string    `yaml:"encoding" env-default:"json"` // Кодек конверта сообщений: json, protobuf, avro
+	DLQ             DLQ       `yaml:"dlq"`
+	Consumer        Consumer  `yaml:"consumer"`
+	Producer        Producer  `yaml:"producer"`
+	Bootstrap       Bootstrap `yaml:"bootstrap"`
+
+	Topics       map[string]Topic `yaml:"topics"`                                // Ключ - логическое имя топика (config.Topic*)
+	TopicPrefix  string           `yaml:"topic_prefix" env:"KAFKA_TOPIC_PREFIX"` // Префикс имен топиков и групп, например "staging."
+	CreateTopics bool             `yaml:"create_topics" env-default:"false"`     // Создавать недостающие топики при старте
+
+	TLS  KafkaTLS  `yaml:"tls"`
+	SASL KafkaSASL `yaml:"sasl"`
+}
+
+// Bootstrap - восстановление состояния из Kafka при старте
+type Bootstrap struct {
+	Mode    string        `yaml:"mode" env:"KAFKA_BOOTSTRAP_MODE" env-default:"snapshot"` // snapshot, replay или none
+	Timeout time.Duration `yaml:"timeout" env-default:"30s"`                              // Ожидание снимка в режиме snapshot
+	// Попыток перечитать топики до запуска консюмеров; после них replay переходит на снимок
+	ReplayAttempts int `yaml:"replay_attempts" env-default:"5"`
+}
+
+// KafkaTLS - шифрование соединений с брокерами
+type KafkaTLS struct {
+	Enabled            bool   `yaml:"enabled" env:"KAFKA_TLS_ENABLED" env-default:"false"`
+	CAFile             string `yaml:"ca_file" env:"KAFKA_TLS_CA_FILE"`     // Пусто - системные корневые сертификаты
+	CertFile           string `yaml:"cert_file" env:"KAFKA_TLS_CERT_FILE"` // Клиентский сертификат для mTLS
+	KeyFile            string `yaml:"key_file" env:"KAFKA_TLS_KEY_FILE"`
+	ServerName         string `yaml:"server_name" env:"KAFKA_TLS_SERVER_NAME"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" env-default:"false"` // Только для локальной отладки
+}
+
+// KafkaSASL - аутентификация на брокерах. Секреты можно задать прямо, через окружение или файлом
+type KafkaSASL struct {
+	Mechanism    string `yaml:"mechanism" env:"KAFKA_SASL_MECHANISM"` // plain, scram-sha-256, scram-sha-512; пусто - без SASL
+	Username     string `yaml:"username" env:"KAFKA_SASL_USERNAME"`
+	UsernameFile string `yaml:"username_file" env:"KAFKA_SASL_USERNAME_FILE"`
+	Password     string `yaml:"password" env:"KAFKA_SASL_PASSWORD"`
+	PasswordFile string `yaml:"password_file" env:"KAFKA_SASL_PASSWORD_FILE"`
+}
+
+// Consumer - настройки обработки входящих сообщений
+type Consumer struct {
+	Workers      int           `yaml:"workers" env-default:"8"`           // Сообщения с одним ключом всегда обрабатывает один воркер
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"3"`      // Попыток обработки до отправки в DLQ
+	RetryBackoff time.Duration `yaml:"retry_backoff" env-default:"500ms"` // Пауза перед повтором, растет с каждой попыткой
+}
+
+// Producer - настройки отправки сообщений
+type Producer struct {
+	Mode         string        `yaml:"mode" env:"KAFKA_PRODUCER_MODE" env-default:"sync"` // sync - ждать подтверждения в запросе; async - отправлять пачками в фоне
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`                      // Сообщений в пачке
+	BatchBytes   int64         `yaml:"batch_bytes" env-default:"1048576"`                 // Максимальный размер пачки в байтах
+	BatchTimeout time.Duration `yaml:"batch_timeout" env-default:"10ms"`                  // Сколько ждать заполнения пачки
+	RequiredAcks string        `yaml:"required_acks" env-default:"all"`                   // all, one или none
+	Compression  string        `yaml:"compression" env-default:"none"`                    // none, gzip, snappy, lz4, zstd
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"10"`                     // Попыток доставки пачки
+	WriteTimeout time.Duration `yaml:"write_timeout" env-default:"10s"`
+}
+
+// DLQ - настройки dead-letter топиков для сообщений, которые консюмеры не смогли обработать
+type DLQ struct {
+	Enabled bool   `yaml:"enabled" env-default:"true"`
+	Suffix  string `yaml:"suffix" env-default:".dlq"` // Имя DLQ = <topic><suffix>
 }
 
 type Blockchain struct {
@@ -35,6 +110,15 @@ type Blockchain struct {
 	StakeManagerContractAddress string `yaml:"stake_manager_contract_address" env-required:"true"`
 	PrivateKey                  string `yaml:"private_key" env-required:"true"`
 	ChainID                     int64  `yaml:"chain_id" env-default:"31337"`
+
+	LogsFromBlock  uint64 `yaml:"logs_from_block" env-default:"0"`      // С какого блока читать логи контрактов (блок деплоя)
+	LogsBlockRange uint64 `yaml:"logs_block_range" env-default:"10000"` // Блоков в одном запросе eth_getLogs
+
+	StakeHistoryInterval time.Duration `yaml:"stake_history_interval" env-default:"15s"` // Как часто индексировать новые события стейк-менеджера
+
+	Confirmations       uint64        `yaml:"confirmations" env-default:"1"`          // Сколько блоков (включая блок транзакции) ждать до ответа
+	ReceiptPollInterval time.Duration `yaml:"receipt_poll_interval" env-default:"1s"` // Опрос новых блоков, если rpc_url не WebSocket
+	ReceiptTimeout      time.Duration `yaml:"receipt_timeout" env-default:"60s"`      // Сколько ждать квитанцию транзакции
 }
 
 // MustLoad выгружает данные с конфига по пути до файла
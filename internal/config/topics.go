@@ -0,0 +1,80 @@
+package config
+
+// Логические имена топиков. Фактическое имя, число партиций, репликация и группа
+// консюмеров берутся из kafka.topics, к имени добавляется kafka.topic_prefix.
+const (
+	TopicUserRegistrations   = "user-registrations"
+	TopicVoteHistoryRequest  = "vote-history-request"
+	TopicTriggerAllVotings   = "trigger-all-votings"
+	TopicVotingRequest       = "voting-request"
+	TopicVotingCreate        = "voting-create"
+	TopicVoteCast            = "vote-cast"
+	TopicVoteChanged         = "vote-changed"
+	TopicVoteDelegation      = "vote-delegation"
+	TopicVoteCommit          = "vote-commit"
+	TopicVotingStatusChanged = "voting-status-changed"
+
+	TopicAllVotingsResponse  = "all-votings-response"
+	TopicVotingResponse      = "voting-response"
+	TopicVoteHistoryResponse = "vote-history-response"
+)
+
+// KnownTopics - все топики, с которыми работает шлюз
+var KnownTopics = []string{
+	TopicUserRegistrations,
+	TopicVoteHistoryRequest,
+	TopicTriggerAllVotings,
+	TopicVotingRequest,
+	TopicVotingCreate,
+	TopicVoteCast,
+	TopicVoteChanged,
+	TopicVoteDelegation,
+	TopicVoteCommit,
+	TopicVotingStatusChanged,
+	TopicAllVotingsResponse,
+	TopicVotingResponse,
+	TopicVoteHistoryResponse,
+}
+
+// defaultGroupIDs - группы консюмеров, которые были у топиков до появления kafka.topics.
+// Без них после обновления группа сменилась бы на kafka.group_id и перечитала топик по auto_offset_reset
+var defaultGroupIDs = map[string]string{
+	TopicVoteHistoryResponse: "go-app-profile-history-updater-group",
+}
+
+// Topic - настройки одного топика
+type Topic struct {
+	Name              string `yaml:"name"`               // Пусто - совпадает с логическим именем
+	Partitions        int    `yaml:"partitions"`         // Используется при создании топика, по умолчанию 1
+	ReplicationFactor int    `yaml:"replication_factor"` // Используется при создании топика, по умолчанию 1
+	GroupID           string `yaml:"group_id"`           // Группа консюмеров для входящих топиков, пусто - прежняя группа топика или kafka.group_id
+}
+
+// Topic возвращает настройки топика по логическому имени с учетом значений по умолчанию и префикса
+func (k Kafka) Topic(key string) Topic {
+	t := k.Topics[key]
+	if t.Name == "" {
+		t.Name = key
+	}
+	if t.Partitions < 1 {
+		t.Partitions = 1
+	}
+	if t.ReplicationFactor < 1 {
+		t.ReplicationFactor = 1
+	}
+	if t.GroupID == "" {
+		t.GroupID = defaultGroupIDs[key]
+	}
+	if t.GroupID == "" {
+		t.GroupID = k.GroupID
+	}
+
+	t.Name = k.TopicPrefix + t.Name
+	t.GroupID = k.TopicPrefix + t.GroupID
+	return t
+}
+
+// TopicName возвращает фактическое имя топика по логическому
+func (k Kafka) TopicName(key string) string {
+	return k.Topic(key).Name
+}
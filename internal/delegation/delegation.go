@@ -0,0 +1,287 @@
+package delegation
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSelfDelegation = errors.New("cannot delegate to yourself")
+	ErrCycle          = errors.New("delegation would create a cycle")
+	ErrNotFound       = errors.New("delegation not found")
+)
+
+// Delegation - передача голоса от delegator к delegate.
+// Пустой VotingID означает глобальное делегирование для всех голосований.
+type Delegation struct {
+	Delegator string    `json:"delegator_address"`
+	Delegate  string    `json:"delegate_address"`
+	VotingID  string    `json:"voting_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Registry хранит делегирования: глобальные и для конкретных голосований.
+// Делегирование для голосования важнее глобального.
+type Registry struct {
+	mu        sync.RWMutex
+	global    map[string]Delegation
+	perVoting map[string]map[string]Delegation
+	changedAt map[string]time.Time // Последнее изменение по changeKey, чтобы Apply пропускал устаревшие события
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		global:    make(map[string]Delegation),
+		perVoting: make(map[string]map[string]Delegation),
+		changedAt: make(map[string]time.Time),
+	}
+}
+
+func changeKey(votingID, delegator string) string {
+	return votingID + "/" + delegator
+}
+
+func normalize(addr string) string {
+	return strings.ToLower(strings.TrimSpace(addr))
+}
+
+// Delegate сохраняет делегирование, если оно не создает цикл
+func (r *Registry) Delegate(votingID, delegator, delegate string) (Delegation, error) {
+	d := Delegation{
+		Delegator: normalize(delegator),
+		Delegate:  normalize(delegate),
+		VotingID:  votingID,
+		CreatedAt: time.Now(),
+	}
+	if d.Delegator == d.Delegate {
+		return Delegation{}, ErrSelfDelegation
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.createsCycleLocked(d) {
+		return Delegation{}, ErrCycle
+	}
+	r.putLocked(d)
+	return d, nil
+}
+
+// Revoke удаляет делегирование delegator в указанном контексте
+func (r *Registry) Revoke(votingID, delegator string) (Delegation, error) {
+	delegator = normalize(delegator)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.removeLocked(votingID, delegator, time.Now())
+	if !ok {
+		return Delegation{}, ErrNotFound
+	}
+	return d, nil
+}
+
+// Apply применяет делегирование d или его отзыв (revoked), восстановленные из событий vote-delegation.
+// Событие старше последнего изменения делегирования того же адреса в том же контексте пропускается
+// (applied == false), поэтому повторные и запоздавшие события не откатывают более новые изменения
+func (r *Registry) Apply(d Delegation, revoked bool) (applied bool, err error) {
+	d.Delegator, d.Delegate = normalize(d.Delegator), normalize(d.Delegate)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if last, ok := r.changedAt[changeKey(d.VotingID, d.Delegator)]; ok && d.CreatedAt.Before(last) {
+		return false, nil
+	}
+	if revoked {
+		r.removeLocked(d.VotingID, d.Delegator, d.CreatedAt)
+		return true, nil
+	}
+
+	if d.Delegator == d.Delegate {
+		return false, ErrSelfDelegation
+	}
+	if r.createsCycleLocked(d) {
+		return false, ErrCycle
+	}
+	r.putLocked(d)
+	return true, nil
+}
+
+// createsCycleLocked проверяет, замкнет ли d цепочку делегирований.
+// Глобальное делегирование участвует во всех голосованиях, поэтому цикл проверяется в каждом контексте
+func (r *Registry) createsCycleLocked(d Delegation) bool {
+	contexts := []string{d.VotingID}
+	if d.VotingID == "" {
+		for id := range r.perVoting {
+			contexts = append(contexts, id)
+		}
+	}
+	for _, ctxID := range contexts {
+		if r.reachesLocked(ctxID, d.Delegate, d.Delegator) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Registry) delegationLocked(votingID, delegator string) (Delegation, bool) {
+	if votingID == "" {
+		d, ok := r.global[delegator]
+		return d, ok
+	}
+	d, ok := r.perVoting[votingID][delegator]
+	return d, ok
+}
+
+func (r *Registry) putLocked(d Delegation) {
+	if d.VotingID == "" {
+		r.global[d.Delegator] = d
+	} else {
+		if r.perVoting[d.VotingID] == nil {
+			r.perVoting[d.VotingID] = make(map[string]Delegation)
+		}
+		r.perVoting[d.VotingID][d.Delegator] = d
+	}
+	r.changedAt[changeKey(d.VotingID, d.Delegator)] = d.CreatedAt
+}
+
+func (r *Registry) removeLocked(votingID, delegator string, at time.Time) (Delegation, bool) {
+	r.changedAt[changeKey(votingID, delegator)] = at
+
+	d, ok := r.delegationLocked(votingID, delegator)
+	if !ok {
+		return Delegation{}, false
+	}
+	if votingID == "" {
+		delete(r.global, delegator)
+		return d, true
+	}
+	delete(r.perVoting[votingID], delegator)
+	if len(r.perVoting[votingID]) == 0 {
+		delete(r.perVoting, votingID)
+	}
+	return d, true
+}
+
+// Of возвращает все делегирования, выданные адресом
+func (r *Registry) Of(addr string) []Delegation {
+	addr = normalize(addr)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var res []Delegation
+	if d, ok := r.global[addr]; ok {
+		res = append(res, d)
+	}
+	for _, byDelegator := range r.perVoting {
+		if d, ok := byDelegator[addr]; ok {
+			res = append(res, d)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].VotingID < res[j].VotingID })
+	return res
+}
+
+// Chain возвращает цепочку представителей адреса в голосовании (без самого адреса).
+// Цепочка обрывается на первом повторе, поэтому циклы не приводят к зависанию.
+func (r *Registry) Chain(votingID, addr string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.chainLocked(votingID, normalize(addr))
+}
+
+// Representative возвращает первого проголосовавшего в цепочке делегирования addr
+func (r *Registry) Representative(votingID, addr string, hasVoted func(string) bool) (string, bool) {
+	for _, a := range r.Chain(votingID, addr) {
+		if hasVoted(a) {
+			return a, true
+		}
+	}
+	return "", false
+}
+
+// Represented возвращает не голосовавших делегаторов, чей голос достается voter:
+// voter - первый проголосовавший в их цепочке (сам voter считается проголосовавшим).
+func (r *Registry) Represented(votingID, voter string, hasVoted func(string) bool) []string {
+	voter = normalize(voter)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var res []string
+	for _, delegator := range r.delegatorsLocked(votingID) {
+		if delegator == voter || hasVoted(delegator) {
+			continue
+		}
+		for _, a := range r.chainLocked(votingID, delegator) {
+			if a == voter {
+				res = append(res, delegator)
+				break
+			}
+			if hasVoted(a) {
+				break
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (r *Registry) delegateOfLocked(votingID, addr string) (string, bool) {
+	if votingID != "" {
+		if d, ok := r.perVoting[votingID][addr]; ok {
+			return d.Delegate, true
+		}
+	}
+	d, ok := r.global[addr]
+	return d.Delegate, ok
+}
+
+func (r *Registry) chainLocked(votingID, addr string) []string {
+	visited := map[string]bool{addr: true}
+	var chain []string
+	for {
+		next, ok := r.delegateOfLocked(votingID, addr)
+		if !ok || visited[next] {
+			return chain
+		}
+		visited[next] = true
+		chain = append(chain, next)
+		addr = next
+	}
+}
+
+// reachesLocked проверяет, приводит ли цепочка from к target
+func (r *Registry) reachesLocked(votingID, from, target string) bool {
+	if from == target {
+		return true
+	}
+	for _, a := range r.chainLocked(votingID, from) {
+		if a == target {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Registry) delegatorsLocked(votingID string) []string {
+	seen := make(map[string]bool, len(r.global))
+	var res []string
+	for addr := range r.global {
+		seen[addr] = true
+		res = append(res, addr)
+	}
+	if votingID != "" {
+		for addr := range r.perVoting[votingID] {
+			if !seen[addr] {
+				res = append(res, addr)
+			}
+		}
+	}
+	return res
+}
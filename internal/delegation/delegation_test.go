@@ -0,0 +1,69 @@
+package delegation
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func delegateOf(r *Registry, votingID, addr string) string {
+	chain := r.Chain(votingID, addr)
+	if len(chain) == 0 {
+		return ""
+	}
+	return chain[0]
+}
+
+func TestApply(t *testing.T) {
+	r := NewRegistry()
+	t0 := time.Now().Add(-time.Hour)
+
+	apply := func(d Delegation, revoked bool) bool {
+		t.Helper()
+		applied, err := r.Apply(d, revoked)
+		if err != nil {
+			t.Fatalf("Apply(%+v, %t) error = %v", d, revoked, err)
+		}
+		return applied
+	}
+
+	// Восстановление по порядку событий
+	apply(Delegation{Delegator: "0xA", Delegate: "0xB", CreatedAt: t0}, false)
+	apply(Delegation{Delegator: "0xa", Delegate: "0xc", CreatedAt: t0}, false) // Та же секунда - применяется по порядку
+	if got := delegateOf(r, "", "0xa"); got != "0xc" {
+		t.Fatalf("delegate = %q, want 0xc", got)
+	}
+	apply(Delegation{Delegator: "0xa", CreatedAt: t0.Add(time.Second)}, true)
+	if got := r.Of("0xa"); len(got) != 0 {
+		t.Fatalf("delegations after revoke = %v, want none", got)
+	}
+
+	// Запоздавшее событие не возвращает отозванное делегирование
+	if apply(Delegation{Delegator: "0xa", Delegate: "0xb", CreatedAt: t0}, false) {
+		t.Error("outdated delegation was applied after revoke")
+	}
+
+	// Событие о собственном изменении шлюза старше самого изменения и пропускается
+	d, err := r.Delegate("1", "0xa", "0xd")
+	if err != nil {
+		t.Fatalf("Delegate() error = %v", err)
+	}
+	if apply(Delegation{Delegator: "0xa", Delegate: "0xb", VotingID: "1", CreatedAt: d.CreatedAt.Add(-time.Second)}, false) {
+		t.Error("event older than the local change was applied")
+	}
+	if !apply(Delegation{Delegator: "0xa", Delegate: "0xd", VotingID: "1", CreatedAt: d.CreatedAt}, false) {
+		t.Error("repeated event of the current delegation was not applied")
+	}
+	if got := delegateOf(r, "1", "0xa"); got != "0xd" {
+		t.Errorf("delegate in voting 1 = %q, want 0xd", got)
+	}
+
+	// Ошибки проверяются так же, как в Delegate
+	later := time.Now().Add(time.Hour)
+	if _, err := r.Apply(Delegation{Delegator: "0xd", Delegate: "0xa", VotingID: "1", CreatedAt: later}, false); !errors.Is(err, ErrCycle) {
+		t.Errorf("Apply() of a cycle error = %v, want ErrCycle", err)
+	}
+	if _, err := r.Apply(Delegation{Delegator: "0xe", Delegate: "0xE", CreatedAt: later}, false); !errors.Is(err, ErrSelfDelegation) {
+		t.Errorf("Apply() of self-delegation error = %v, want ErrSelfDelegation", err)
+	}
+}
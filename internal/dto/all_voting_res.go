@@ -12,4 +12,8 @@ type AllVotingRes struct {
 
 type AllVotingsKafkaResponse struct {
 	Votings []AllVotingRes `json:"votings"`
+	// Поля снимка по страницам. Старые сообщения без них считаются полным снимком из одной страницы
+	Generation int64 `json:"generation,omitempty"` // Номер снимка, растет с каждым новым снимком
+	Page       int   `json:"page,omitempty"`       // Номер страницы с 0
+	TotalPages int   `json:"totalPages,omitempty"` // Всего страниц в снимке
 }
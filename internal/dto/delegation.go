@@ -0,0 +1,17 @@
+package dto
+
+// topic: vote-delegation
+
+// Значения Delegation.Action
+const (
+	DelegationActionDelegated = "delegated"
+	DelegationActionRevoked   = "revoked"
+)
+
+type Delegation struct {
+	DelegatorID string `json:"delegatorId"`
+	DelegateID  string `json:"delegateId"`
+	VotingID    string `json:"votingId,omitempty"` // Пустой, если делегирование глобальное
+	Action      string `json:"action"`             // DelegationActionDelegated или DelegationActionRevoked
+	CreatedAt   string `json:"createdAt"`
+}
@@ -3,7 +3,9 @@ package dto
 // topic: vote-cast
 
 type VoteCast struct {
-	VotingID string `json:"votingId"`
-	VoterID  string `json:"voterId"`
-	OptionID string `json:"optionId"`
+	VotingID   string   `json:"votingId"`
+	VoterID    string   `json:"voterId"`
+	OptionID   string   `json:"optionId"`
+	Weight     int64    `json:"weight,omitempty"`     // Вес голоса с учетом делегирований
+	Delegators []string `json:"delegators,omitempty"` // Делегаторы, чьи голоса учтены
 }
@@ -0,0 +1,14 @@
+package dto
+
+// topic: vote-changed
+
+type VoteChanged struct {
+	VotingID         string   `json:"votingId"`
+	VoterID          string   `json:"voterId"`
+	PreviousOptionID string   `json:"previousOptionId"`     // "-1", если голос до этого был отозван
+	OptionID         string   `json:"optionId"`             // Пустая строка, если голос отозван
+	Action           string   `json:"action"`               // "changed" или "revoked"
+	Weight           int64    `json:"weight"`               // Вес, перенесенный на OptionID или снятый с PreviousOptionID, с учетом делегирований
+	Delegators       []string `json:"delegators,omitempty"` // Делегаторы, чьи голоса учтены в Weight
+	ChangedAt        string   `json:"changedAt"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+// topic: vote-commit
+
+type VoteCommit struct {
+	VotingID    string `json:"votingId"`
+	VoterID     string `json:"voterId"`
+	Commitment  string `json:"commitment"`
+	CommittedAt string `json:"committedAt"`
+	// RevealEndDate и Revealed нужны шлюзу, чтобы восстановить фазу раскрытия после рестарта:
+	// Java-сервис не хранит режим голосования
+	RevealEndDate string `json:"revealEndDate,omitempty"`
+	Revealed      bool   `json:"revealed,omitempty"`
+}
@@ -3,15 +3,29 @@ package dto
 // topic: voting-create
 
 type VotingReq struct {
-	ID          string   `json:"id"`
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	CreatorID   string   `json:"creatorId"`
-	Private     bool     `json:"private"`
-	MinVotes    int      `json:"minVotes"`
-	EndDate     string   `json:"endDate"`
-	StartDate   string   `json:"startDate"`
-	Options     []Option `json:"options"`
+	ID              string       `json:"id"`
+	Title           string       `json:"title"`
+	Description     string       `json:"description"`
+	CreatorID       string       `json:"creatorId"`
+	Private         bool         `json:"private"`
+	MinVotes        int          `json:"minVotes"`
+	EndDate         string       `json:"endDate"`
+	StartDate       string       `json:"startDate"`
+	Options         []Option     `json:"options"`
+	ResultRules     *ResultRules `json:"resultRules,omitempty"`
+	AllowVoteChange bool         `json:"allowVoteChange"`
+	BallotMode      string       `json:"ballotMode"`
+	RevealEndDate   string       `json:"revealEndDate,omitempty"`
+}
+
+// ResultRules - правила подведения итогов голосования
+type ResultRules struct {
+	QuorumType     string  `json:"quorumType"`
+	Quorum         float64 `json:"quorum"`
+	ThresholdType  string  `json:"thresholdType"`
+	ThresholdBase  string  `json:"thresholdBase"`
+	TieBreak       string  `json:"tieBreak"`
+	EligibleVoters int64   `json:"eligibleVoters"`
 }
 
 type Option struct {
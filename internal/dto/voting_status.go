@@ -0,0 +1,12 @@
+package dto
+
+// topic: voting-status-changed
+
+type VotingStatusChanged struct {
+	VotingID       string   `json:"votingId"`
+	PreviousStatus string   `json:"previousStatus"`
+	Status         string   `json:"status"`
+	Winners        []string `json:"winners"`
+	Explanation    []string `json:"explanation,omitempty"`
+	ChangedAt      string   `json:"changedAt"`
+}
@@ -0,0 +1,177 @@
+package events
+
+import (
+	"apiGateway/internal/models"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Типы внутренних событий
+const (
+	VotingUpdated       = "voting-updated"        // Голосование добавлено или изменено (например, из Kafka)
+	VotingStatusChanged = "voting-status-changed" // Статус голосования изменился
+	VotingsReconciled   = "votings-reconciled"    // Применен полный снимок всех голосований
+)
+
+// Event - внутреннее событие шлюза
+type Event struct {
+	Type     string
+	VotingID string
+	At       time.Time
+	Payload  any
+}
+
+// SnapshotDiff - полезная нагрузка VotingsReconciled: что изменил снимок
+type SnapshotDiff struct {
+	Generation int64
+	Added      []string
+	Updated    []string
+	Removed    []string
+}
+
+// StatusChange - полезная нагрузка VotingStatusChanged
+type StatusChange struct {
+	VotingID string
+	From     string
+	To       string
+	Winners  []string
+	Result   *models.VotingResult
+}
+
+type subscription struct {
+	ch    chan Event
+	types map[string]bool
+
+	// Очередь неограниченной подписки: Publish складывает события сюда, pump перекладывает их в ch
+	unbounded bool
+	qmu       sync.Mutex
+	queue     []Event
+	notify    chan struct{}
+	done      chan struct{}
+}
+
+// Bus - простая шина событий для подсистем внутри процесса.
+// Publish не блокируется: если подписчик с буфером не успевает, событие для него отбрасывается.
+// Подписчики, которым нельзя терять события, используют SubscribeUnbounded.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[*subscription]struct{}
+	log  *slog.Logger
+}
+
+func NewBus(log *slog.Logger) *Bus {
+	return &Bus{
+		subs: make(map[*subscription]struct{}),
+		log:  log,
+	}
+}
+
+// Subscribe подписывается на события указанных типов (все, если типы не указаны).
+// Возвращает канал событий и функцию отписки, закрывающую канал.
+func (b *Bus) Subscribe(buffer int, types ...string) (<-chan Event, func()) {
+	sub := newSubscription(make(chan Event, buffer), types)
+	return sub.ch, b.add(sub)
+}
+
+// SubscribeUnbounded подписывается на события, как Subscribe, но без потерь: события,
+// которые подписчик еще не забрал, копятся в очереди без ограничения размера.
+// Подходит для редких событий, каждое из которых нужно доставить (например, смены статуса)
+func (b *Bus) SubscribeUnbounded(types ...string) (<-chan Event, func()) {
+	sub := newSubscription(make(chan Event), types)
+	sub.unbounded = true
+	sub.notify = make(chan struct{}, 1)
+	sub.done = make(chan struct{})
+	go sub.pump()
+	return sub.ch, b.add(sub)
+}
+
+func newSubscription(ch chan Event, types []string) *subscription {
+	sub := &subscription{
+		ch:    ch,
+		types: make(map[string]bool, len(types)),
+	}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+	return sub
+}
+
+// add регистрирует подписку и возвращает функцию отписки
+func (b *Bus) add(sub *subscription) func() {
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, sub)
+			b.mu.Unlock()
+			if sub.unbounded {
+				close(sub.done) // Канал закроет pump
+				return
+			}
+			close(sub.ch)
+		})
+	}
+}
+
+// pump перекладывает события из очереди неограниченной подписки в ее канал, пока подписка не отменена
+func (s *subscription) pump() {
+	defer close(s.ch)
+	for {
+		s.qmu.Lock()
+		batch := s.queue
+		s.queue = nil
+		s.qmu.Unlock()
+
+		for _, e := range batch {
+			select {
+			case s.ch <- e:
+			case <-s.done:
+				return
+			}
+		}
+
+		select {
+		case <-s.notify:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Publish рассылает событие подписчикам
+func (b *Bus) Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		if len(sub.types) > 0 && !sub.types[e.Type] {
+			continue
+		}
+		if sub.unbounded {
+			sub.qmu.Lock()
+			sub.queue = append(sub.queue, e)
+			sub.qmu.Unlock()
+			select {
+			case sub.notify <- struct{}{}:
+			default: // pump уже разбудили, он заберет и это событие
+			}
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			b.log.Warn("Event subscriber is too slow, event dropped",
+				slog.String("type", e.Type),
+				slog.String("voting_id", e.VotingID))
+		}
+	}
+}
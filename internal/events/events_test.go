@@ -0,0 +1,71 @@
+package events
+
+import (
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestBus() *Bus {
+	return NewBus(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestSubscribeDropsWhenFull(t *testing.T) {
+	b := newTestBus()
+	ch, unsubscribe := b.Subscribe(1, VotingUpdated)
+
+	b.Publish(Event{Type: VotingUpdated, VotingID: "1"})
+	b.Publish(Event{Type: VotingUpdated, VotingID: "2"})       // Буфер полон - событие отбрасывается
+	b.Publish(Event{Type: VotingStatusChanged, VotingID: "3"}) // Другой тип - не доставляется
+
+	if e := <-ch; e.VotingID != "1" || e.At.IsZero() {
+		t.Errorf("event = %+v, want voting 1 with time", e)
+	}
+	select {
+	case e := <-ch:
+		t.Errorf("unexpected event %+v", e)
+	default:
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-ch; ok {
+		t.Error("channel is open after unsubscribe")
+	}
+}
+
+func TestSubscribeUnbounded(t *testing.T) {
+	b := newTestBus()
+	ch, unsubscribe := b.SubscribeUnbounded(VotingStatusChanged)
+
+	// Подписчик ничего не читает, а Publish не блокируется и ничего не теряет
+	const n = 5000
+	for i := range n {
+		b.Publish(Event{Type: VotingStatusChanged, VotingID: strconv.Itoa(i)})
+	}
+	b.Publish(Event{Type: VotingUpdated, VotingID: "other"})
+
+	for i := range n {
+		select {
+		case e := <-ch:
+			if e.VotingID != strconv.Itoa(i) {
+				t.Fatalf("event %d = %s, want events in publish order", i, e.VotingID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("got %d of %d events", i, n)
+		}
+	}
+	select {
+	case e := <-ch:
+		t.Errorf("unexpected event %+v", e)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	b.Publish(Event{Type: VotingStatusChanged, VotingID: "pending"})
+	unsubscribe()
+	for range ch {
+		// Канал закрывается после отписки, даже если в очереди остались события
+	}
+}
@@ -0,0 +1,98 @@
+package chainerr
+
+import (
+	"apiGateway/internal/client"
+	"apiGateway/internal/lib/logger/sl"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+// Response - тело ответа об ошибке вызова контракта
+type Response struct {
+	Status  int                    `json:"status"`
+	Message string                 `json:"message"`
+	Error   string                 `json:"error,omitempty"` // Имя custom error контракта, "Error" или "Panic"
+	Args    map[string]interface{} `json:"args,omitempty"`  // Аргументы custom error
+	Details string                 `json:"details,omitempty"`
+}
+
+type mapping struct {
+	status  int
+	message string
+}
+
+// contractErrors - custom errors контрактов, для которых есть свой статус и понятное сообщение.
+// Остальные custom errors отдаются как 422 с именем ошибки
+var contractErrors = map[string]mapping{
+	"CooldownClaimNotReached":    {http.StatusTooManyRequests, "Claim cooldown period not reached yet"},
+	"NothingToClaim":             {http.StatusNotFound, "Nothing to claim"},
+	"NotEnoughBalanceOnContract": {http.StatusInternalServerError, "Contract does not have enough tokens to fulfill claim"},
+	"TransferFailed":             {http.StatusInternalServerError, "Token transfer failed on contract"},
+}
+
+// IsRevert сообщает, что контракт отклонил вызов (в отличие от ошибок сети или узла)
+func IsRevert(err error) bool {
+	var (
+		contractErr *client.ContractError
+		revertErr   *client.RevertError
+		panicErr    *client.PanicError
+	)
+	return errors.As(err, &contractErr) || errors.As(err, &revertErr) || errors.As(err, &panicErr)
+}
+
+// Describe переводит ошибку клиента блокчейна в HTTP-ответ.
+// action - что не удалось сделать, используется как сообщение для ошибок без собственного текста
+func Describe(err error, action string) Response {
+	var (
+		contractErr *client.ContractError
+		revertErr   *client.RevertError
+		panicErr    *client.PanicError
+	)
+
+	switch {
+	case errors.As(err, &contractErr):
+		if m, ok := contractErrors[contractErr.Name]; ok {
+			return Response{Status: m.status, Message: m.message, Error: contractErr.Name, Args: contractErr.Args}
+		}
+		return Response{
+			Status:  http.StatusUnprocessableEntity,
+			Message: action + ": contract rejected the call with " + contractErr.Name,
+			Error:   contractErr.Name,
+			Args:    contractErr.Args,
+		}
+	case errors.As(err, &revertErr):
+		return Response{Status: http.StatusUnprocessableEntity, Message: action + ": " + revertErr.Error(), Error: "Error"}
+	case errors.As(err, &panicErr):
+		return Response{Status: http.StatusInternalServerError, Message: action + ": " + panicErr.Error(), Error: "Panic"}
+	case errors.Is(err, client.ErrInsufficientBalance), errors.Is(err, client.ErrPartialUnstakeUnsupported):
+		return Response{Status: http.StatusBadRequest, Message: err.Error()}
+	case errors.Is(err, client.ErrReorged):
+		return Response{Status: http.StatusConflict, Message: action + ": transaction was dropped by a chain reorg, retry the request", Details: err.Error()}
+	case errors.Is(err, context.DeadlineExceeded):
+		return Response{Status: http.StatusGatewayTimeout, Message: action + ": blockchain request timed out"}
+	default:
+		return Response{Status: http.StatusInternalServerError, Message: action, Details: err.Error()}
+	}
+}
+
+// Status - HTTP-статус для ошибки клиента блокчейна
+func Status(err error) int {
+	return Describe(err, "").Status
+}
+
+// Write логирует ошибку и отвечает JSON-ом по Describe. Это единственное место,
+// где ошибки контрактов переводятся в HTTP-статусы
+func Write(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error, action string) {
+	res := Describe(err, action)
+	if res.Status >= http.StatusInternalServerError {
+		log.Error(action, sl.Err(err))
+	} else {
+		log.Warn(action, sl.Err(err))
+	}
+	render.Status(r, res.Status)
+	render.JSON(w, r, res)
+}
@@ -0,0 +1,40 @@
+package adminauth
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// New пускает к служебным эндпоинтам только запросы с заголовком "Authorization: Bearer <token>".
+// Если token пустой, служебные эндпоинты выключены и всегда отвечают 404
+func New(log *slog.Logger, token string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(slog.String("component", "middleware/adminauth"))
+
+		if token == "" {
+			log.Warn("admin token is not set, admin endpoints are disabled")
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				http.NotFound(w, r)
+				return
+			}
+
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				log.Warn("rejected admin request",
+					slog.String("path", r.URL.Path),
+					slog.String("remote_address", r.RemoteAddr))
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,23 @@
+package correlation
+
+import (
+	"apiGateway/internal/kafka/codec"
+	"github.com/go-chi/chi/middleware"
+	"net/http"
+)
+
+// New пробрасывает ID HTTP-запроса в контекст как ID корреляции,
+// чтобы он попал в конверты сообщений Kafka, отправленных обработчиком.
+// Должен подключаться после middleware.RequestID.
+func New() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if id := middleware.GetReqID(r.Context()); id != "" {
+				r = r.WithContext(codec.ContextWithCorrelationID(r.Context(), id))
+			}
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
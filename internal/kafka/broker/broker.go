@@ -0,0 +1,144 @@
+package broker
+
+import (
+	"apiGateway/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Драйверы брокера (kafka.driver)
+const (
+	DriverKafka  = "kafka"  // Настоящий кластер через segmentio/kafka-go
+	DriverMemory = "memory" // Брокер в памяти процесса: для тестов и запуска без Kafka
+)
+
+// ErrClosed - брокер или подписка закрыты; Fetch после этого уже ничего не вернет
+var ErrClosed = errors.New("broker is closed")
+
+// Сообщения везде передаются как kafka.Message из segmentio/kafka-go: это простая структура,
+// и брокер в памяти заполняет те же поля (Topic, Partition, Offset, Key, Value, Headers, Time).
+
+// Publisher отправляет сообщения; топик берется из kafka.Message.Topic
+type Publisher interface {
+	Publish(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Subscription - чтение топика в составе группы консюмеров с явным коммитом оффсетов
+type Subscription interface {
+	// Fetch блокируется до следующего сообщения или отмены ctx
+	Fetch(ctx context.Context) (kafka.Message, error)
+	// Commit коммитит оффсеты сообщений: после перезапуска чтение продолжится со следующего
+	Commit(ctx context.Context, msgs ...kafka.Message) error
+	Topic() string
+	Close() error
+}
+
+// Subscriber подписывает группы консюмеров на топики
+type Subscriber interface {
+	// Subscribe начинает чтение topic группой groupID. startOffset (kafka.FirstOffset или kafka.LastOffset)
+	// используется, если у группы еще нет закоммиченного оффсета
+	Subscribe(topic, groupID string, startOffset int64) (Subscription, error)
+	// Replay передает в fn все сообщения topic с начала до конца на момент вызова, без группы и коммитов
+	Replay(ctx context.Context, topic string, fn func(kafka.Message)) error
+	// Unread возвращает для партиций topic, где у группы groupID есть непрочитанные сообщения, high-water mark -
+	// оффсет, следующий за последним сообщением на момент вызова. Группа без коммитов считается читающей с начала
+	Unread(ctx context.Context, topic, groupID string) (map[int]int64, error)
+}
+
+// Режимы отправки (kafka.producer.mode)
+const (
+	ModeSync  = "sync"  // Publish ждет подтверждения брокера
+	ModeAsync = "async" // Publish только ставит сообщения в очередь, результат приходит в Completion
+)
+
+// PublisherOptions - настройки отправителя. Нулевые значения - умолчания segmentio/kafka-go
+type PublisherOptions struct {
+	AutoCreateTopics bool // Создавать топик при первой отправке (для DLQ)
+
+	Async        bool
+	BatchSize    int
+	BatchBytes   int64
+	BatchTimeout time.Duration
+	RequiredAcks kafka.RequiredAcks
+	Compression  kafka.Compression
+	MaxAttempts  int
+	WriteTimeout time.Duration
+
+	// Completion вызывается после доставки или окончательной ошибки доставки пачки сообщений,
+	// в том числе в синхронном режиме. В асинхронном режиме это единственный способ узнать об ошибке
+	Completion func(msgs []kafka.Message, err error)
+}
+
+// ProducerOptions переводит kafka.producer из конфига в настройки отправителя
+func ProducerOptions(cfg config.Producer) (PublisherOptions, error) {
+	opts := PublisherOptions{
+		BatchSize:    cfg.BatchSize,
+		BatchBytes:   cfg.BatchBytes,
+		BatchTimeout: cfg.BatchTimeout,
+		MaxAttempts:  cfg.MaxAttempts,
+		WriteTimeout: cfg.WriteTimeout,
+	}
+
+	switch cfg.Mode {
+	case "", ModeSync:
+	case ModeAsync:
+		opts.Async = true
+	default:
+		return PublisherOptions{}, fmt.Errorf("unknown kafka producer mode: %s", cfg.Mode)
+	}
+
+	switch strings.ToLower(cfg.RequiredAcks) {
+	case "", "all", "-1":
+		opts.RequiredAcks = kafka.RequireAll
+	case "one", "1":
+		opts.RequiredAcks = kafka.RequireOne
+	case "none", "0":
+		opts.RequiredAcks = kafka.RequireNone
+	default:
+		return PublisherOptions{}, fmt.Errorf("unknown kafka producer required_acks: %s", cfg.RequiredAcks)
+	}
+
+	switch strings.ToLower(cfg.Compression) {
+	case "", "none":
+	case "gzip":
+		opts.Compression = kafka.Gzip
+	case "snappy":
+		opts.Compression = kafka.Snappy
+	case "lz4":
+		opts.Compression = kafka.Lz4
+	case "zstd":
+		opts.Compression = kafka.Zstd
+	default:
+		return PublisherOptions{}, fmt.Errorf("unknown kafka producer compression: %s", cfg.Compression)
+	}
+
+	return opts, nil
+}
+
+// Broker - подключение к Kafka или его замене
+type Broker interface {
+	Subscriber
+	NewPublisher(opts PublisherOptions) (Publisher, error)
+	// EnsureTopics проверяет, что топики существуют, и создает недостающие, если create = true
+	EnsureTopics(ctx context.Context, topics []kafka.TopicConfig, create bool) error
+	Close() error
+}
+
+// New создает брокер по kafka.driver из конфига
+func New(cfg config.Kafka, log *slog.Logger) (Broker, error) {
+	switch cfg.Driver {
+	case "", DriverKafka:
+		return NewSegmentio(cfg, log)
+	case DriverMemory:
+		log.Warn("Using in-memory Kafka broker: messages are not shared with other services")
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown kafka driver: %s", cfg.Driver)
+	}
+}
@@ -0,0 +1,285 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Memory - брокер в памяти процесса с топиками, партициями, группами консюмеров и оффсетами.
+// Группа читает топик одной общей позицией: несколько подписок одной группы делят сообщения между собой,
+// и новая подписка продолжает с этой позиции. Когда закрыты все подписки группы, незакоммиченные сообщения
+// снова выдаются следующей подписке, как после перезапуска с Kafka.
+type Memory struct {
+	mu      sync.Mutex
+	topics  map[string]*memTopic
+	groups  map[groupKey]*memGroup
+	notify  chan struct{} // Закрывается при каждой публикации, чтобы разбудить ждущие Fetch
+	closed  bool
+	counter int // Для распределения сообщений без ключа по партициям
+}
+
+type memTopic struct {
+	partitions [][]kafka.Message
+}
+
+type groupKey struct {
+	group string
+	topic string
+}
+
+type memGroup struct {
+	committed map[int]int64 // Следующий оффсет для чтения после перезапуска
+	cursor    map[int]int64 // Следующий оффсет для выдачи, общий для всех подписок группы
+	members   int           // Открытые подписки группы
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		topics: make(map[string]*memTopic),
+		groups: make(map[groupKey]*memGroup),
+		notify: make(chan struct{}),
+	}
+}
+
+// topicLocked возвращает топик, создавая его с одной партицией при первом обращении
+func (m *Memory) topicLocked(name string) *memTopic {
+	t, ok := m.topics[name]
+	if !ok {
+		t = &memTopic{partitions: make([][]kafka.Message, 1)}
+		m.topics[name] = t
+	}
+	return t
+}
+
+// NewPublisher в памяти пишет сразу, поэтому пачки, подтверждения и сжатие не используются.
+// Completion вызывается после каждой отправки, как и у segmentio
+func (m *Memory) NewPublisher(opts PublisherOptions) (Publisher, error) {
+	return memPublisher{m: m, completion: opts.Completion}, nil
+}
+
+type memPublisher struct {
+	m          *Memory
+	completion func([]kafka.Message, error)
+}
+
+func (p memPublisher) Publish(ctx context.Context, msgs ...kafka.Message) error {
+	err := p.m.publish(ctx, msgs...)
+	if p.completion != nil {
+		p.completion(msgs, err)
+	}
+	return err
+}
+
+func (p memPublisher) Close() error { return nil }
+
+func (m *Memory) publish(ctx context.Context, msgs ...kafka.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+
+	for _, msg := range msgs {
+		if msg.Topic == "" {
+			return fmt.Errorf("message topic is empty")
+		}
+		t := m.topicLocked(msg.Topic)
+
+		partition := 0
+		if len(msg.Key) > 0 {
+			h := fnv.New32a()
+			h.Write(msg.Key)
+			partition = int(h.Sum32() % uint32(len(t.partitions)))
+		} else {
+			partition = m.counter % len(t.partitions)
+			m.counter++
+		}
+
+		msg.Partition = partition
+		msg.Offset = int64(len(t.partitions[partition]))
+		if msg.Time.IsZero() {
+			msg.Time = time.Now()
+		}
+		t.partitions[partition] = append(t.partitions[partition], msg)
+	}
+
+	close(m.notify)
+	m.notify = make(chan struct{})
+	return nil
+}
+
+func (m *Memory) Subscribe(topic, groupID string, startOffset int64) (Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+
+	t := m.topicLocked(topic)
+	key := groupKey{group: groupID, topic: topic}
+	g, ok := m.groups[key]
+	if !ok {
+		g = &memGroup{committed: make(map[int]int64), cursor: make(map[int]int64)}
+		for p, msgs := range t.partitions {
+			if startOffset == kafka.LastOffset {
+				g.committed[p] = int64(len(msgs))
+			}
+		}
+		m.groups[key] = g
+	}
+	// Первая подписка группы начинает с закоммиченных оффсетов, остальные - с общей позиции группы
+	if g.members == 0 {
+		for p := range t.partitions {
+			g.cursor[p] = g.committed[p]
+		}
+	}
+	g.members++
+
+	return &memSubscription{m: m, topic: topic, group: g}, nil
+}
+
+type memSubscription struct {
+	m      *Memory
+	topic  string
+	group  *memGroup
+	closed bool
+}
+
+func (s *memSubscription) Fetch(ctx context.Context) (kafka.Message, error) {
+	for {
+		s.m.mu.Lock()
+		if s.m.closed || s.closed {
+			s.m.mu.Unlock()
+			return kafka.Message{}, ErrClosed
+		}
+		t := s.m.topics[s.topic]
+		for p, msgs := range t.partitions {
+			if next := s.group.cursor[p]; next < int64(len(msgs)) {
+				s.group.cursor[p] = next + 1
+				msg := msgs[next]
+				s.m.mu.Unlock()
+				return msg, nil
+			}
+		}
+		wait := s.m.notify
+		s.m.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		}
+	}
+}
+
+func (s *memSubscription) Commit(_ context.Context, msgs ...kafka.Message) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for _, msg := range msgs {
+		if msg.Offset+1 > s.group.committed[msg.Partition] {
+			s.group.committed[msg.Partition] = msg.Offset + 1
+		}
+	}
+	return nil
+}
+
+func (s *memSubscription) Topic() string { return s.topic }
+
+func (s *memSubscription) Close() error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		s.group.members--
+		// Будим Fetch, который ждет новых сообщений на этой подписке
+		if !s.m.closed {
+			close(s.m.notify)
+			s.m.notify = make(chan struct{})
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Replay(ctx context.Context, topic string, fn func(kafka.Message)) error {
+	m.mu.Lock()
+	t, ok := m.topics[topic]
+	var snapshot [][]kafka.Message
+	if ok {
+		for _, msgs := range t.partitions {
+			snapshot = append(snapshot, msgs[:len(msgs):len(msgs)])
+		}
+	}
+	m.mu.Unlock()
+
+	for _, msgs := range snapshot {
+		for _, msg := range msgs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			fn(msg)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Unread(_ context.Context, topic, groupID string) (map[int]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+
+	unread := make(map[int]int64)
+	t, ok := m.topics[topic]
+	if !ok {
+		return unread, nil
+	}
+	g := m.groups[groupKey{group: groupID, topic: topic}]
+	for p, msgs := range t.partitions {
+		var from int64
+		if g != nil {
+			from = g.committed[p]
+		}
+		if end := int64(len(msgs)); from < end {
+			unread[p] = end
+		}
+	}
+	return unread, nil
+}
+
+// EnsureTopics в памяти всегда создает недостающие топики: проверять их наличие не с чем
+func (m *Memory) EnsureTopics(_ context.Context, topics []kafka.TopicConfig, _ bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tc := range topics {
+		if _, ok := m.topics[tc.Topic]; ok {
+			continue
+		}
+		partitions := tc.NumPartitions
+		if partitions < 1 {
+			partitions = 1
+		}
+		m.topics[tc.Topic] = &memTopic{partitions: make([][]kafka.Message, partitions)}
+	}
+	return nil
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.closed {
+		m.closed = true
+		close(m.notify)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const testTopic = "voting-response"
+
+func publish(t *testing.T, m *Memory, values ...string) {
+	t.Helper()
+	pub, err := m.NewPublisher(PublisherOptions{})
+	if err != nil {
+		t.Fatalf("NewPublisher() error = %v", err)
+	}
+	for _, v := range values {
+		if err := pub.Publish(context.Background(), kafka.Message{Topic: testTopic, Value: []byte(v)}); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+}
+
+func subscribe(t *testing.T, m *Memory, group string, startOffset int64) Subscription {
+	t.Helper()
+	sub, err := m.Subscribe(testTopic, group, startOffset)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	return sub
+}
+
+// fetch читает n сообщений и возвращает их значения
+func fetch(t *testing.T, sub Subscription, n int) []kafka.Message {
+	t.Helper()
+	msgs := make([]kafka.Message, 0, n)
+	for range n {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		msg, err := sub.Fetch(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("Fetch() error = %v after %d messages", err, len(msgs))
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// expectEmpty проверяет, что подписке больше нечего выдать
+func expectEmpty(t *testing.T, sub Subscription) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if msg, err := sub.Fetch(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Fetch() = %q, %v, want no messages", msg.Value, err)
+	}
+}
+
+func values(msgs []kafka.Message) []string {
+	out := make([]string, len(msgs))
+	for i, msg := range msgs {
+		out[i] = string(msg.Value)
+	}
+	return out
+}
+
+func TestMemoryPublishFetch(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+
+	publish(t, m, "a", "b")
+	sub := subscribe(t, m, "g", kafka.FirstOffset)
+
+	msgs := fetch(t, sub, 2)
+	if got := values(msgs); got[0] != "a" || got[1] != "b" {
+		t.Errorf("values = %v, want [a b]", got)
+	}
+	if msgs[0].Offset != 0 || msgs[1].Offset != 1 || msgs[0].Topic != testTopic || msgs[0].Time.IsZero() {
+		t.Errorf("messages = %+v, want offsets 0, 1 with topic and time", msgs)
+	}
+
+	// Ждущий Fetch просыпается на новой публикации
+	done := make(chan string)
+	go func() {
+		msg, _ := sub.Fetch(context.Background())
+		done <- string(msg.Value)
+	}()
+	publish(t, m, "c")
+	select {
+	case v := <-done:
+		if v != "c" {
+			t.Errorf("Fetch() = %q, want c", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Fetch() did not wake up on publish")
+	}
+}
+
+func TestMemoryStartOffset(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+	publish(t, m, "old")
+
+	latest := subscribe(t, m, "latest", kafka.LastOffset)
+	earliest := subscribe(t, m, "earliest", kafka.FirstOffset)
+	publish(t, m, "new")
+
+	if got := values(fetch(t, latest, 1)); got[0] != "new" {
+		t.Errorf("LastOffset group got %v, want [new]", got)
+	}
+	if got := values(fetch(t, earliest, 2)); got[0] != "old" || got[1] != "new" {
+		t.Errorf("FirstOffset group got %v, want [old new]", got)
+	}
+}
+
+func TestMemoryGroupSharesCursor(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+	publish(t, m, "1", "2", "3", "4")
+
+	first := subscribe(t, m, "g", kafka.FirstOffset)
+	fetch(t, first, 2)
+
+	// Новая подписка той же группы продолжает с общей позиции и не сбрасывает ее для первой
+	second := subscribe(t, m, "g", kafka.FirstOffset)
+	if got := values(fetch(t, second, 1)); got[0] != "3" {
+		t.Errorf("second subscription got %v, want [3]", got)
+	}
+	if got := values(fetch(t, first, 1)); got[0] != "4" {
+		t.Errorf("first subscription got %v, want [4]", got)
+	}
+	expectEmpty(t, first)
+	expectEmpty(t, second)
+
+	// Другая группа читает топик независимо
+	other := subscribe(t, m, "other", kafka.FirstOffset)
+	if got := values(fetch(t, other, 4)); got[0] != "1" || got[3] != "4" {
+		t.Errorf("other group got %v, want [1 2 3 4]", got)
+	}
+}
+
+func TestMemoryRedeliversUncommitted(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+	publish(t, m, "1", "2", "3")
+
+	sub := subscribe(t, m, "g", kafka.FirstOffset)
+	msgs := fetch(t, sub, 3)
+	if err := sub.Commit(context.Background(), msgs[0]); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	// Меньший оффсет не откатывает закоммиченный
+	if err := sub.Commit(context.Background(), kafka.Message{Offset: -1}); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	sub.Close()
+	if _, err := sub.Fetch(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Fetch() on closed subscription error = %v, want ErrClosed", err)
+	}
+
+	// После закрытия всех подписок группа продолжает с закоммиченного оффсета
+	restarted := subscribe(t, m, "g", kafka.FirstOffset)
+	if got := values(fetch(t, restarted, 2)); got[0] != "2" || got[1] != "3" {
+		t.Errorf("restarted subscription got %v, want [2 3]", got)
+	}
+	expectEmpty(t, restarted)
+}
+
+func TestMemoryCloseWakesFetch(t *testing.T) {
+	m := NewMemory()
+	sub := subscribe(t, m, "g", kafka.FirstOffset)
+
+	errs := make(chan error)
+	go func() {
+		_, err := sub.Fetch(context.Background())
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	m.Close()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("Fetch() error = %v, want ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Fetch() did not return after Close")
+	}
+
+	if _, err := m.Subscribe(testTopic, "g", kafka.FirstOffset); !errors.Is(err, ErrClosed) {
+		t.Errorf("Subscribe() after Close error = %v, want ErrClosed", err)
+	}
+	pub, _ := m.NewPublisher(PublisherOptions{})
+	if err := pub.Publish(context.Background(), kafka.Message{Topic: testTopic}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish() after Close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestMemoryReplay(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+	if err := m.EnsureTopics(context.Background(), []kafka.TopicConfig{{Topic: testTopic, NumPartitions: 3}}, false); err != nil {
+		t.Fatalf("EnsureTopics() error = %v", err)
+	}
+	pub, _ := m.NewPublisher(PublisherOptions{})
+	for _, key := range []string{"a", "b", "c", "a"} {
+		if err := pub.Publish(context.Background(), kafka.Message{Topic: testTopic, Key: []byte(key), Value: []byte(key)}); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	var replayed []kafka.Message
+	if err := m.Replay(context.Background(), testTopic, func(msg kafka.Message) { replayed = append(replayed, msg) }); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if len(replayed) != 4 {
+		t.Fatalf("Replay() returned %d messages, want 4", len(replayed))
+	}
+	// Сообщения одного ключа попадают в одну партицию по порядку
+	partitionOf := map[string]int{}
+	for _, msg := range replayed {
+		if p, ok := partitionOf[string(msg.Key)]; ok && p != msg.Partition {
+			t.Errorf("key %s is in partitions %d and %d", msg.Key, p, msg.Partition)
+		}
+		partitionOf[string(msg.Key)] = msg.Partition
+	}
+
+	// Replay не двигает оффсеты групп
+	sub := subscribe(t, m, "g", kafka.FirstOffset)
+	fetch(t, sub, 4)
+}
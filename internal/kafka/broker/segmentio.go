@@ -0,0 +1,277 @@
+package broker
+
+import (
+	"apiGateway/internal/config"
+	"apiGateway/internal/kafka/transport"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Segmentio - брокер поверх segmentio/kafka-go
+type Segmentio struct {
+	brokers   []string
+	dialer    *kafka.Dialer
+	transport *kafka.Transport
+	log       *slog.Logger
+}
+
+// NewSegmentio создает брокер поверх segmentio/kafka-go с TLS и SASL из конфига
+func NewSegmentio(cfg config.Kafka, log *slog.Logger) (*Segmentio, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers not provided in configuration")
+	}
+
+	// TLS и SASL из конфига
+	dialer, err := transport.Dialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := transport.Transport(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Segmentio{
+		brokers:   cfg.Brokers,
+		dialer:    dialer,
+		transport: tr,
+		log:       log,
+	}, nil
+}
+
+func (s *Segmentio) NewPublisher(opts PublisherOptions) (Publisher, error) {
+	return &segmentioPublisher{writer: &kafka.Writer{
+		Addr:                   kafka.TCP(s.brokers...), // Использование varargs для нескольких брокеров
+		Balancer:               &kafka.LeastBytes{},     // Балансировщик
+		Transport:              s.transport,
+		AllowAutoTopicCreation: opts.AutoCreateTopics,
+		Async:                  opts.Async,
+		BatchSize:              opts.BatchSize,
+		BatchBytes:             opts.BatchBytes,
+		BatchTimeout:           opts.BatchTimeout,
+		RequiredAcks:           opts.RequiredAcks,
+		Compression:            opts.Compression,
+		MaxAttempts:            opts.MaxAttempts,
+		WriteTimeout:           opts.WriteTimeout,
+		Completion:             opts.Completion,
+		Logger:                 kafka.LoggerFunc(func(msg string, args ...interface{}) { s.log.Debug(msg, args...) }),
+		ErrorLogger:            kafka.LoggerFunc(func(msg string, args ...interface{}) { s.log.Error(msg, args...) }),
+	}}, nil
+}
+
+func (s *Segmentio) Subscribe(topic, groupID string, startOffset int64) (Subscription, error) {
+	return &segmentioSubscription{reader: kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     s.brokers,
+		Topic:       topic,
+		GroupID:     groupID,
+		StartOffset: startOffset,
+		MaxBytes:    10e6,
+		Dialer:      s.dialer,
+	})}, nil
+}
+
+func (s *Segmentio) Replay(ctx context.Context, topic string, fn func(kafka.Message)) error {
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.brokers[0])
+	if err != nil {
+		return fmt.Errorf("failed to connect to kafka broker: %w", err)
+	}
+	partitions, err := conn.ReadPartitions(topic)
+	conn.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read partitions of %s: %w", topic, err)
+	}
+
+	for _, p := range partitions {
+		if err := s.replayPartition(ctx, p, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Segmentio) replayPartition(ctx context.Context, p kafka.Partition, fn func(kafka.Message)) error {
+	leader, err := s.dialer.DialLeader(ctx, "tcp", net.JoinHostPort(p.Leader.Host, strconv.Itoa(p.Leader.Port)), p.Topic, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to connect to leader of %s/%d: %w", p.Topic, p.ID, err)
+	}
+	first, last, err := leader.ReadOffsets()
+	leader.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read offsets of %s/%d: %w", p.Topic, p.ID, err)
+	}
+	if last <= first {
+		return nil
+	}
+
+	// Ридер без группы: читает конкретную партицию и ничего не коммитит
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   s.brokers,
+		Topic:     p.Topic,
+		Partition: p.ID,
+		MaxBytes:  10e6,
+		Dialer:    s.dialer,
+	})
+	defer reader.Close()
+	if err := reader.SetOffset(first); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read %s/%d: %w", p.Topic, p.ID, err)
+		}
+		fn(msg)
+
+		// last - оффсет следующего сообщения; в compacted-топике оффсеты могут идти с пропусками
+		if msg.Offset >= last-1 {
+			return nil
+		}
+	}
+}
+
+func (s *Segmentio) Unread(ctx context.Context, topic, groupID string) (map[int]int64, error) {
+	client := &kafka.Client{Addr: kafka.TCP(s.brokers...), Transport: s.transport}
+	unread := make(map[int]int64)
+
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata of %s: %w", topic, err)
+	}
+	if len(meta.Topics) == 0 || errors.Is(meta.Topics[0].Error, kafka.UnknownTopicOrPartition) {
+		return unread, nil // Топика еще нет - читать нечего
+	}
+	if err := meta.Topics[0].Error; err != nil {
+		return nil, fmt.Errorf("failed to read metadata of %s: %w", topic, err)
+	}
+
+	ids := make([]int, 0, len(meta.Topics[0].Partitions))
+	requests := make([]kafka.OffsetRequest, 0, 2*len(meta.Topics[0].Partitions))
+	for _, p := range meta.Topics[0].Partitions {
+		ids = append(ids, p.ID)
+		requests = append(requests, kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID))
+	}
+
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{topic: requests}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets of %s: %w", topic, err)
+	}
+	committed, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: groupID, Topics: map[string][]int{topic: ids}})
+	if err == nil {
+		err = committed.Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch offsets of group %s: %w", groupID, err)
+	}
+
+	committedBy := make(map[int]int64, len(ids))
+	for _, p := range committed.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to fetch offset of group %s for %s/%d: %w", groupID, topic, p.Partition, p.Error)
+		}
+		committedBy[p.Partition] = p.CommittedOffset
+	}
+	for _, p := range offsets.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to list offsets of %s/%d: %w", topic, p.Partition, p.Error)
+		}
+		// Без коммита (-1) группа начинает с начала партиции
+		from, ok := committedBy[p.Partition]
+		if !ok || from < 0 {
+			from = p.FirstOffset
+		}
+		if from < p.LastOffset {
+			unread[p.Partition] = p.LastOffset
+		}
+	}
+	return unread, nil
+}
+
+func (s *Segmentio) EnsureTopics(ctx context.Context, topics []kafka.TopicConfig, create bool) error {
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.brokers[0])
+	if err != nil {
+		return fmt.Errorf("failed to connect to kafka broker %s: %w", s.brokers[0], err)
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions()
+	if err != nil {
+		return fmt.Errorf("failed to read kafka topics metadata: %w", err)
+	}
+	existing := make(map[string]bool, len(partitions))
+	for _, p := range partitions {
+		existing[p.Topic] = true
+	}
+
+	var missing []kafka.TopicConfig
+	var names []string
+	for _, t := range topics {
+		if !existing[t.Topic] {
+			missing = append(missing, t)
+			names = append(names, t.Topic)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if !create {
+		return fmt.Errorf("kafka topics do not exist: %s", strings.Join(names, ", "))
+	}
+
+	// Топики создаются только через контроллер кластера
+	controller, err := conn.Controller()
+	if err != nil {
+		return fmt.Errorf("failed to find kafka controller: %w", err)
+	}
+	controllerConn, err := s.dialer.DialContext(ctx, "tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to kafka controller: %w", err)
+	}
+	defer controllerConn.Close()
+
+	if err := controllerConn.CreateTopics(missing...); err != nil {
+		return fmt.Errorf("failed to create kafka topics %s: %w", strings.Join(names, ", "), err)
+	}
+	s.log.Info("Created missing Kafka topics", slog.Any("topics", names))
+	return nil
+}
+
+func (s *Segmentio) Close() error { return nil }
+
+type segmentioPublisher struct {
+	writer *kafka.Writer
+}
+
+func (p *segmentioPublisher) Publish(ctx context.Context, msgs ...kafka.Message) error {
+	return p.writer.WriteMessages(ctx, msgs...)
+}
+
+// Close дожидается отправки всех сообщений, накопленных в пачках, и закрывает writer
+func (p *segmentioPublisher) Close() error { return p.writer.Close() }
+
+type segmentioSubscription struct {
+	reader *kafka.Reader
+}
+
+func (s *segmentioSubscription) Fetch(ctx context.Context) (kafka.Message, error) {
+	msg, err := s.reader.FetchMessage(ctx)
+	// Закрытый kafka.Reader отвечает io.EOF
+	if errors.Is(err, io.EOF) {
+		return msg, ErrClosed
+	}
+	return msg, err
+}
+
+func (s *segmentioSubscription) Commit(ctx context.Context, msgs ...kafka.Message) error {
+	return s.reader.CommitMessages(ctx, msgs...)
+}
+
+func (s *segmentioSubscription) Topic() string { return s.reader.Config().Topic }
+func (s *segmentioSubscription) Close() error  { return s.reader.Close() }
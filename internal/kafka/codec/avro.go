@@ -0,0 +1,111 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// AvroSchema - схема конверта для Avro (в канонической форме)
+const AvroSchema = `{"name":"Envelope","type":"record","fields":[{"name":"type","type":"string"},{"name":"schemaVersion","type":"int"},{"name":"correlationId","type":"string"},{"name":"producedAt","type":"long"},{"name":"payload","type":"bytes"}]}`
+
+// Avro кодирует конверт в формате Avro single-object encoding:
+// маркер 0xC3 0x01, 8 байт CRC-64-AVRO отпечатка схемы (little-endian) и бинарная запись.
+type Avro struct{}
+
+var avroFingerprint = rabinFingerprint([]byte(AvroSchema))
+
+func (Avro) Name() string        { return "avro" }
+func (Avro) ContentType() string { return "avro/binary" }
+
+func (Avro) Encode(env Envelope) ([]byte, error) {
+	buf := []byte{0xC3, 0x01}
+	buf = binary.LittleEndian.AppendUint64(buf, avroFingerprint)
+	buf = avroAppendBytes(buf, []byte(env.Type))
+	buf = binary.AppendVarint(buf, int64(env.SchemaVersion))
+	buf = avroAppendBytes(buf, []byte(env.CorrelationID))
+	buf = binary.AppendVarint(buf, env.ProducedAt.UnixMilli())
+	buf = avroAppendBytes(buf, env.Payload)
+	return buf, nil
+}
+
+func (Avro) Decode(data []byte) (Envelope, error) {
+	if len(data) == 0 {
+		return Envelope{}, ErrEmptyMessage
+	}
+	if len(data) < 10 || !bytes.Equal(data[:2], []byte{0xC3, 0x01}) {
+		return Envelope{}, fmt.Errorf("missing avro single-object header")
+	}
+	if fp := binary.LittleEndian.Uint64(data[2:10]); fp != avroFingerprint {
+		return Envelope{}, fmt.Errorf("unknown avro schema fingerprint %x", fp)
+	}
+	r := bytes.NewReader(data[10:])
+
+	var env Envelope
+	typ, err := avroReadBytes(r)
+	if err != nil {
+		return Envelope{}, err
+	}
+	env.Type = string(typ)
+
+	version, err := binary.ReadVarint(r)
+	if err != nil {
+		return Envelope{}, errTruncated
+	}
+	env.SchemaVersion = int(version)
+
+	correlationID, err := avroReadBytes(r)
+	if err != nil {
+		return Envelope{}, err
+	}
+	env.CorrelationID = string(correlationID)
+
+	producedAt, err := binary.ReadVarint(r)
+	if err != nil {
+		return Envelope{}, errTruncated
+	}
+	env.ProducedAt = time.UnixMilli(producedAt).UTC()
+
+	if env.Payload, err = avroReadBytes(r); err != nil {
+		return Envelope{}, err
+	}
+	return env, nil
+}
+
+// avroAppendBytes пишет string/bytes: длина (zigzag varint) и данные
+func avroAppendBytes(buf []byte, v []byte) []byte {
+	buf = binary.AppendVarint(buf, int64(len(v)))
+	return append(buf, v...)
+}
+
+func avroReadBytes(r *bytes.Reader) ([]byte, error) {
+	l, err := binary.ReadVarint(r)
+	if err != nil || l < 0 || l > int64(r.Len()) {
+		return nil, errTruncated
+	}
+	v := make([]byte, l)
+	if _, err := r.Read(v); err != nil && l > 0 {
+		return nil, errTruncated
+	}
+	return v, nil
+}
+
+// rabinFingerprint - CRC-64-AVRO из спецификации Avro
+func rabinFingerprint(data []byte) uint64 {
+	const empty = 0xc15d213aa4d7a795
+	var table [256]uint64
+	for i := range table {
+		fp := uint64(i)
+		for j := 0; j < 8; j++ {
+			fp = (fp >> 1) ^ (empty & -(fp & 1))
+		}
+		table[i] = fp
+	}
+
+	fp := uint64(empty)
+	for _, b := range data {
+		fp = (fp >> 8) ^ table[byte(fp)^b]
+	}
+	return fp
+}
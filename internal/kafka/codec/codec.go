@@ -0,0 +1,170 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion - текущая версия конверта. Версия 0 означает сообщение без конверта (старый формат).
+const SchemaVersion = 1
+
+// HeaderContentType - заголовок Kafka, по которому консюмер выбирает кодек
+const HeaderContentType = "content-type"
+
+var ErrEmptyMessage = errors.New("empty message")
+
+// Envelope - версионированный конверт для всех сообщений Kafka
+type Envelope struct {
+	Type          string          `json:"type"`
+	SchemaVersion int             `json:"schemaVersion"`
+	CorrelationID string          `json:"correlationId"`
+	ProducedAt    time.Time       `json:"producedAt"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// Codec кодирует конверт в байты сообщения и обратно
+type Codec interface {
+	Name() string
+	ContentType() string
+	Encode(env Envelope) ([]byte, error)
+	Decode(data []byte) (Envelope, error)
+}
+
+// New возвращает кодек по имени из config.Kafka.Encoding
+func New(name string) (Codec, error) {
+	switch name {
+	case "", "json":
+		return JSON{}, nil
+	case "protobuf":
+		return Protobuf{}, nil
+	case "avro":
+		return Avro{}, nil
+	default:
+		return nil, fmt.Errorf("unknown kafka encoding: %s", name)
+	}
+}
+
+// ForContentType выбирает кодек по заголовку content-type.
+// Сообщения без заголовка (в т.ч. от Java-сервиса) считаются JSON.
+func ForContentType(contentType string) Codec {
+	for _, c := range []Codec{Protobuf{}, Avro{}} {
+		if c.ContentType() == contentType {
+			return c
+		}
+	}
+	return JSON{}
+}
+
+// NewEnvelope упаковывает payload в конверт текущей версии
+func NewEnvelope(ctx context.Context, eventType string, payload any) (Envelope, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+
+	correlationID := CorrelationIDFromContext(ctx)
+	if correlationID == "" {
+		correlationID = newCorrelationID()
+	}
+
+	return Envelope{
+		Type:          eventType,
+		SchemaVersion: SchemaVersion,
+		CorrelationID: correlationID,
+		ProducedAt:    time.Now().UTC(),
+		Payload:       raw,
+	}, nil
+}
+
+// Unmarshal раскладывает payload конверта в v
+func (e Envelope) Unmarshal(v any) error {
+	if len(e.Payload) == 0 {
+		return ErrEmptyMessage
+	}
+	return json.Unmarshal(e.Payload, v)
+}
+
+// JSON - кодек по умолчанию. При декодировании понимает три формата:
+// конверт, обычный JSON без конверта и старый дважды закодированный JSON (строка с JSON внутри).
+type JSON struct{}
+
+func (JSON) Name() string        { return "json" }
+func (JSON) ContentType() string { return "application/json" }
+
+func (JSON) Encode(env Envelope) ([]byte, error) {
+	return json.Marshal(env)
+}
+
+func (c JSON) Decode(data []byte) (Envelope, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return Envelope{}, ErrEmptyMessage
+	}
+
+	// Старый формат Java-сервиса: JSON, сериализованный в строку
+	if data[0] == '"' {
+		var inner string
+		if err := json.Unmarshal(data, &inner); err != nil {
+			return Envelope{}, fmt.Errorf("failed to unmarshal double-encoded message: %w", err)
+		}
+		return c.Decode([]byte(inner))
+	}
+
+	if !json.Valid(data) {
+		return Envelope{}, fmt.Errorf("message is not valid JSON")
+	}
+
+	// Конверт узнаем по наличию полей type и payload
+	var probe struct {
+		Type    *string         `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if data[0] == '{' && json.Unmarshal(data, &probe) == nil && probe.Type != nil && len(probe.Payload) > 0 {
+		var env Envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			return Envelope{}, fmt.Errorf("failed to unmarshal envelope: %w", err)
+		}
+		// Payload тоже может оказаться дважды закодированным
+		if len(env.Payload) > 0 && env.Payload[0] == '"' {
+			inner, err := c.Decode(env.Payload)
+			if err != nil {
+				return Envelope{}, err
+			}
+			env.Payload = inner.Payload
+		}
+		return env, nil
+	}
+
+	// Обычный JSON без конверта
+	return Envelope{Payload: json.RawMessage(data)}, nil
+}
+
+type correlationIDKey struct{}
+
+// ContextWithCorrelationID сохраняет ID корреляции (например, ID HTTP-запроса) в контексте
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext возвращает ID корреляции из контекста
+func CorrelationIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+func newCorrelationID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,133 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	env, err := NewEnvelope(ContextWithCorrelationID(context.Background(), "req-1"), "VoteCast",
+		map[string]string{"votingId": "42", "voterId": "0xabc"})
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+	// Бинарные кодеки хранят время с точностью до миллисекунды
+	env.ProducedAt = env.ProducedAt.Truncate(time.Millisecond)
+
+	for _, name := range []string{"json", "protobuf", "avro"} {
+		t.Run(name, func(t *testing.T) {
+			c, err := New(name)
+			if err != nil {
+				t.Fatalf("New(%q) error = %v", name, err)
+			}
+			if got := ForContentType(c.ContentType()); got.Name() != name {
+				t.Errorf("ForContentType(%q) = %s, want %s", c.ContentType(), got.Name(), name)
+			}
+
+			data, err := c.Encode(env)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			got, err := c.Decode(data)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			if got.Type != env.Type || got.SchemaVersion != SchemaVersion || got.CorrelationID != "req-1" || !got.ProducedAt.Equal(env.ProducedAt) {
+				t.Errorf("Decode() = %+v, want %+v", got, env)
+			}
+			if !bytes.Equal(got.Payload, env.Payload) {
+				t.Errorf("Payload = %s, want %s", got.Payload, env.Payload)
+			}
+
+			var payload map[string]string
+			if err := got.Unmarshal(&payload); err != nil || payload["votingId"] != "42" {
+				t.Errorf("Unmarshal() = %v, %v", payload, err)
+			}
+		})
+	}
+}
+
+func TestJSONDecodeFormats(t *testing.T) {
+	const plain = `{"votingId":"42","votesCount":"3"}`
+	doubled, _ := json.Marshal(plain)
+	envelope := `{"type":"VotingResponse","schemaVersion":1,"correlationId":"c","producedAt":"2025-01-01T00:00:00Z","payload":` + plain + `}`
+	envelopeDoubledPayload := `{"type":"VotingResponse","schemaVersion":1,"correlationId":"c","producedAt":"2025-01-01T00:00:00Z","payload":` + string(doubled) + `}`
+	doubledEnvelope, _ := json.Marshal(envelope)
+
+	tests := []struct {
+		name    string
+		data    string
+		typ     string
+		version int
+	}{
+		{"plain JSON without envelope", plain, "", 0},
+		{"legacy double-encoded JSON", string(doubled), "", 0},
+		{"envelope", envelope, "VotingResponse", 1},
+		{"envelope with double-encoded payload", envelopeDoubledPayload, "VotingResponse", 1},
+		{"double-encoded envelope", string(doubledEnvelope), "VotingResponse", 1},
+		{"surrounding whitespace", "\n " + plain + " \n", "", 0},
+		{"object with type but no payload is not an envelope", `{"type":"x","votingId":"42"}`, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := JSON{}.Decode([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if env.Type != tt.typ || env.SchemaVersion != tt.version {
+				t.Errorf("Type = %q, SchemaVersion = %d, want %q, %d", env.Type, env.SchemaVersion, tt.typ, tt.version)
+			}
+
+			var payload struct {
+				VotingID string `json:"votingId"`
+			}
+			if err := env.Unmarshal(&payload); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if payload.VotingID != "42" {
+				t.Errorf("votingId = %q, want 42", payload.VotingID)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid, _ := Protobuf{}.Encode(Envelope{Type: "VoteCast", Payload: json.RawMessage(`{}`)})
+
+	tests := []struct {
+		name  string
+		codec Codec
+		data  []byte
+	}{
+		{"json: empty", JSON{}, nil},
+		{"json: blank", JSON{}, []byte("  ")},
+		{"json: invalid", JSON{}, []byte(`{"votingId":`)},
+		{"json: broken double encoding", JSON{}, []byte(`"{\"votingId\"`)},
+		{"protobuf: empty", Protobuf{}, nil},
+		{"protobuf: truncated", Protobuf{}, valid[:len(valid)-1]},
+		{"avro: empty", Avro{}, nil},
+		{"avro: no header", Avro{}, []byte(`{"votingId":"42"}`)},
+		{"avro: unknown fingerprint", Avro{}, []byte{0xC3, 0x01, 1, 2, 3, 4, 5, 6, 7, 8, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.codec.Decode(tt.data); err == nil {
+				t.Error("Decode() error = nil, want error")
+			}
+		})
+	}
+
+	if _, err := (JSON{}).Decode(nil); !errors.Is(err, ErrEmptyMessage) {
+		t.Errorf("Decode(nil) error = %v, want ErrEmptyMessage", err)
+	}
+	if _, err := New("xml"); err == nil {
+		t.Error("New(\"xml\") error = nil, want error")
+	}
+}
@@ -0,0 +1,101 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Protobuf кодирует конверт в wire-формате Protocol Buffers по схеме:
+//
+//	message Envelope {
+//	  string type = 1;
+//	  int32  schema_version = 2;
+//	  string correlation_id = 3;
+//	  int64  produced_at_unix_ms = 4;
+//	  bytes  payload = 5; // JSON полезной нагрузки
+//	}
+//
+// Схема маленькая и стабильная, поэтому кодируется вручную без сгенерированного кода.
+type Protobuf struct{}
+
+const (
+	pbWireVarint = 0
+	pbWireBytes  = 2
+)
+
+var errTruncated = errors.New("truncated message")
+
+func (Protobuf) Name() string        { return "protobuf" }
+func (Protobuf) ContentType() string { return "application/x-protobuf" }
+
+func (Protobuf) Encode(env Envelope) ([]byte, error) {
+	var buf []byte
+	buf = pbAppendBytes(buf, 1, []byte(env.Type))
+	buf = pbAppendVarint(buf, 2, uint64(env.SchemaVersion))
+	buf = pbAppendBytes(buf, 3, []byte(env.CorrelationID))
+	buf = pbAppendVarint(buf, 4, uint64(env.ProducedAt.UnixMilli()))
+	buf = pbAppendBytes(buf, 5, env.Payload)
+	return buf, nil
+}
+
+func (Protobuf) Decode(data []byte) (Envelope, error) {
+	if len(data) == 0 {
+		return Envelope{}, ErrEmptyMessage
+	}
+
+	var env Envelope
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return Envelope{}, errTruncated
+		}
+		data = data[n:]
+		field, wire := key>>3, key&7
+
+		switch wire {
+		case pbWireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return Envelope{}, errTruncated
+			}
+			data = data[n:]
+			switch field {
+			case 2:
+				env.SchemaVersion = int(int32(v))
+			case 4:
+				env.ProducedAt = time.UnixMilli(int64(v)).UTC()
+			}
+		case pbWireBytes:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return Envelope{}, errTruncated
+			}
+			v := data[n : n+int(l)]
+			data = data[n+int(l):]
+			switch field {
+			case 1:
+				env.Type = string(v)
+			case 3:
+				env.CorrelationID = string(v)
+			case 5:
+				env.Payload = append([]byte(nil), v...)
+			}
+		default:
+			return Envelope{}, fmt.Errorf("unsupported protobuf wire type %d for field %d", wire, field)
+		}
+	}
+	return env, nil
+}
+
+func pbAppendVarint(buf []byte, field int, v uint64) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|pbWireVarint)
+	return binary.AppendUvarint(buf, v)
+}
+
+func pbAppendBytes(buf []byte, field int, v []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|pbWireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(v)))
+	return append(buf, v...)
+}
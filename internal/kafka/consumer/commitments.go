@@ -0,0 +1,106 @@
+package consumer
+
+import (
+	"apiGateway/internal/dto"
+	"apiGateway/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// sealedBallot - хеши скрытых голосов одного голосования, восстановленные из vote-commit.
+// Java-сервис их не хранит, поэтому после рестарта шлюз собирает их из собственного топика
+type sealedBallot struct {
+	revealEnd   time.Time
+	commitments map[string]models.Commitment
+}
+
+// handleVoteCommit восстанавливает хеш скрытого голоса из vote-commit.
+// Если голосования еще нет в CurrentVotings, хеш применится, когда оно придет из voting-response или снимка
+func (c *Consumer) handleVoteCommit(_ context.Context, msg Message[dto.VoteCommit]) error {
+	commit := msg.Value
+	if commit.VotingID == "" || commit.VoterID == "" {
+		return Permanent(errors.New("empty votingId or voterId"))
+	}
+	committedAt, err := time.Parse(time.RFC3339, commit.CommittedAt)
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid committedAt %q: %w", commit.CommittedAt, err))
+	}
+	var revealEnd time.Time
+	if commit.RevealEndDate != "" {
+		if revealEnd, err = time.Parse(time.RFC3339, commit.RevealEndDate); err != nil {
+			return Permanent(fmt.Errorf("invalid revealEndDate %q: %w", commit.RevealEndDate, err))
+		}
+	}
+
+	c.Mu.Lock()
+	if _, removed := c.tombstones[commit.VotingID]; removed {
+		c.Mu.Unlock()
+		return nil
+	}
+	sealed, ok := c.sealed[commit.VotingID]
+	if !ok {
+		sealed = &sealedBallot{commitments: make(map[string]models.Commitment)}
+		c.sealed[commit.VotingID] = sealed
+	}
+	if !revealEnd.IsZero() {
+		sealed.revealEnd = revealEnd
+	}
+	mergeCommitment(sealed.commitments, strings.ToLower(commit.VoterID), models.Commitment{
+		Hash:        commit.Commitment,
+		CommittedAt: committedAt,
+		Revealed:    commit.Revealed,
+	})
+
+	voting, exists := c.CurrentVotings[commit.VotingID]
+	if exists {
+		// Режим commit_reveal добавляет фазу раскрытия, статус мог измениться
+		c.applySealed(&voting)
+		c.CurrentVotings[commit.VotingID] = voting
+		c.refreshStatus(commit.VotingID)
+	}
+	c.Mu.Unlock()
+
+	c.Log.Debug("Vote commitment restored from Kafka",
+		slog.String("voting_id", commit.VotingID),
+		slog.String("voter_id", commit.VoterID),
+		slog.Bool("revealed", commit.Revealed))
+	if exists {
+		c.publishVotingUpdated(commit.VotingID)
+	}
+	return nil
+}
+
+// applySealed переносит восстановленные хеши в голосование. Вызывать под Mu
+func (c *Consumer) applySealed(v *models.VoteSession) {
+	sealed, ok := c.sealed[v.ID]
+	if !ok {
+		return
+	}
+	v.BallotMode = models.BallotModeCommitReveal
+	if v.RevealEndTime.IsZero() {
+		v.RevealEndTime = sealed.revealEnd
+	}
+	if v.Commitments == nil {
+		v.Commitments = make(map[string]models.Commitment, len(sealed.commitments))
+	}
+	for voter, commitment := range sealed.commitments {
+		mergeCommitment(v.Commitments, voter, commitment)
+	}
+}
+
+// mergeCommitment записывает хеш, не давая устаревшему сообщению затереть более новый хеш
+// или вернуть раскрытый голос в нераскрытые
+func mergeCommitment(commitments map[string]models.Commitment, voter string, c models.Commitment) {
+	existing, ok := commitments[voter]
+	switch {
+	case !ok, c.CommittedAt.After(existing.CommittedAt):
+		commitments[voter] = c
+	case existing.Hash == c.Hash && c.Revealed:
+		existing.Revealed = true
+		commitments[voter] = existing
+	}
+}
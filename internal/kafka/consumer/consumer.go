@@ -2,354 +2,431 @@ package consumer
 
 import (
 	"apiGateway/internal/config"
+	"apiGateway/internal/delegation"
 	"apiGateway/internal/dto"
+	"apiGateway/internal/events"
 	"apiGateway/internal/models"
+	"apiGateway/internal/results"
 	"context"
-	"encoding/json"
-	"github.com/segmentio/kafka-go"
+	"errors"
+	"fmt"
 	"log/slog"
 	"sync"
 	"time"
 )
 
-// Consumer инкапсулирует Kafka reader и хранилище данных.
+// allVotingsSnapKey - ключ снимка всех голосований: каждый снимок заменяет предыдущий, поэтому ключ у них общий
+const allVotingsSnapKey = "all"
+
+// Consumer хранит данные, которые приходят из Kafka, и обработчики топиков, которые их обновляют.
 type Consumer struct {
-	reader              *kafka.Reader
 	Mu                  *sync.RWMutex
 	CurrentVotings      map[string]models.VoteSession // Изменил тип на models.VoteSession, как в main
 	Log                 *slog.Logger                  // Добавляем логгер
 	votingResponseChans map[string]chan models.VoteSession
-	UserProfilesHistory map[string][]dto.History
+	Events              *events.Bus // Если задан, получает VotingUpdated после обновления голосований
+	// UpdateStatus пересчитывает статус голосования, публикует его смену и итоги (в main - UpdateVotingStatusAndWinner).
+	// Вызывается под Mu после каждого изменения голосования из Kafka; если не задан, статус только пересчитывается
+	UpdateStatus func(votingID string)
+	// Delegations, если задан, восстанавливается из vote-delegation: делегирования есть только у шлюза.
+	// DelegationChanged вызывается без Mu после каждого примененного события (в main - пересчет весов голосов)
+	Delegations       *delegation.Registry
+	DelegationChanged func(votingID string)
+
+	historyMu           sync.RWMutex
+	userProfilesHistory map[string][]dto.History
+
+	// Сверка со снимками all-votings-response; защищены Mu
+	snapshots  snapshotAssembler
+	generation int64                // Поколение последнего примененного снимка с номером поколения
+	snapshotAt time.Time            // Время последнего примененного снимка; по нему упорядочиваются снимки без поколения
+	known      map[string]bool      // Голосования, которые присылал Java-сервис
+	tombstones map[string]time.Time // Удаленные голосования и время снимка, в котором они пропали
+
+	sealed map[string]*sealedBallot // Хеши скрытых голосов из vote-commit; защищены Mu
+
+	snapshotApplied     chan struct{} // Закрывается после первого примененного снимка всех голосований
+	snapshotAppliedOnce sync.Once
+}
+
+// JavaHistoryMessage - ответ Java-сервиса с историей голосований пользователя
+type JavaHistoryMessage struct {
+	UserID  string        `json:"userId"`
+	History []dto.History `json:"history"`
 }
 
 // NewConsumer создает новый консюмер Kafka.
 // Передаем сюда map, который будем обновлять.
-func NewConsumer(cfg config.Kafka, topic string, currentVotings map[string]models.VoteSession, logger *slog.Logger) *Consumer {
+func NewConsumer(currentVotings map[string]models.VoteSession, logger *slog.Logger) *Consumer {
 	return &Consumer{
-		reader: kafka.NewReader(kafka.ReaderConfig{
-			Brokers:     cfg.Brokers,
-			Topic:       topic,
-			GroupID:     cfg.GroupID,
-			StartOffset: kafka.LastOffset, // Начинать чтение с последнего сообщения
-			MaxBytes:    10e6,
-			Dialer: &kafka.Dialer{ // Добавим таймаут для соединения
-				Timeout: 10 * time.Second,
-			},
-		}),
 		Mu:                  &sync.RWMutex{},
 		CurrentVotings:      currentVotings, // Получаем ссылку на общую map из main
 		Log:                 logger,
 		votingResponseChans: make(map[string]chan models.VoteSession),
-		UserProfilesHistory: make(map[string][]dto.History),
+		userProfilesHistory: make(map[string][]dto.History),
+		known:               make(map[string]bool),
+		tombstones:          make(map[string]time.Time),
+		sealed:              make(map[string]*sealedBallot),
+		snapshotApplied:     make(chan struct{}),
 	}
 }
 
-// RunAllVotingsMain запускает консюмер для получения всех голосований на основной странице.
-// RunAllVotingsMain запускает консьюмер для получения всех голосований на основной странице.
-func (c *Consumer) RunAllVotingsMain(ctx context.Context, wg *sync.WaitGroup) {
-	defer wg.Done()
-	c.Log.Info("Starting Kafka consumer for all-votings-response", slog.String("topic", c.reader.Config().Topic), slog.String("group_id", c.reader.Config().GroupID))
+// SnapshotApplied возвращает канал, который закрывается, когда применен первый полный снимок всех голосований
+func (c *Consumer) SnapshotApplied() <-chan struct{} {
+	return c.snapshotApplied
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			c.Log.Info("Stopping Kafka consumer...")
-			if err := c.reader.Close(); err != nil {
-				c.Log.Error("Failed to close Kafka reader", slog.Any("error", err))
-			}
-			return
-		default:
-			msg, err := c.reader.ReadMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					c.Log.Debug("Kafka read error during shutdown", slog.Any("error", err))
-					continue
-				}
-				c.Log.Error("Kafka read error", slog.String("error_details", err.Error()), slog.Any("full_error", err))
-				continue
-			}
+// Register регистрирует обработчики всех топиков консюмера в рантайме
+func (c *Consumer) Register(rt *Runtime) {
+	Register(rt, Handler[dto.AllVotingsKafkaResponse]{
+		Topic:  config.TopicAllVotingsResponse,
+		Key:    func(dto.AllVotingsKafkaResponse) string { return allVotingsSnapKey },
+		Handle: c.handleAllVotings,
+	})
+	Register(rt, Handler[dto.VotingKafkaResponse]{
+		Topic:  config.TopicVotingResponse,
+		Key:    func(v dto.VotingKafkaResponse) string { return v.VotingID },
+		Handle: c.handleVoting,
+	})
+	Register(rt, Handler[JavaHistoryMessage]{
+		Topic:  config.TopicVoteHistoryResponse,
+		Key:    func(m JavaHistoryMessage) string { return m.UserID },
+		Handle: c.handleVoteHistory,
+	})
+	Register(rt, Handler[dto.VoteCommit]{
+		Topic:  config.TopicVoteCommit,
+		Key:    func(v dto.VoteCommit) string { return v.VotingID + "-" + v.VoterID },
+		Handle: c.handleVoteCommit,
+	})
+	if c.Delegations != nil {
+		Register(rt, Handler[dto.Delegation]{
+			Topic:  config.TopicVoteDelegation,
+			Key:    func(d dto.Delegation) string { return d.VotingID + "-" + d.DelegatorID },
+			Handle: c.handleDelegation,
+		})
+	}
+}
 
-			var rawMessage string
-			if err := json.Unmarshal(msg.Value, &rawMessage); err != nil {
-				c.Log.Error("Failed to unmarshal Kafka message into raw string (likely double-encoded JSON)",
-					slog.Any("error", err),
-					slog.String("message_value", string(msg.Value)))
-				continue
-			}
+// History возвращает историю голосований пользователя, полученную из Kafka
+func (c *Consumer) History(userAddress string) ([]dto.History, bool) {
+	c.historyMu.RLock()
+	defer c.historyMu.RUnlock()
 
-			// Используем новую структуру для анмаршалинга
-			var kafkaResponse dto.AllVotingsKafkaResponse // <-- ИЗМЕНЕНО
-			if err := json.Unmarshal([]byte(rawMessage), &kafkaResponse); err != nil {
-				c.Log.Error("Failed to unmarshal all the votings from raw string (check JSON structure vs DTO)",
-					slog.Any("error", err),
-					slog.String("raw_message_value", rawMessage))
-				continue
-			}
+	history, ok := c.userProfilesHistory[userAddress]
+	return history, ok
+}
+
+// handleAllVotings собирает снимок всех голосований из страниц и сверяет с ним список на основной странице.
+func (c *Consumer) handleAllVotings(_ context.Context, msg Message[dto.AllVotingsKafkaResponse]) error {
+	snap := msg.Value
+	generation := snap.Generation
+
+	var (
+		receivedVotings []dto.AllVotingRes
+		complete, stale bool
+		err             error
+	)
+	c.Mu.Lock()
+	if generation == 0 && snap.TotalPages <= 1 {
+		// У старых сообщений нет номера поколения и страниц: снимок целиком в одном сообщении,
+		// а упорядочиваются такие снимки по времени сообщения (см. reconcile)
+		receivedVotings, complete = snap.Votings, true
+	} else {
+		// Страницы без поколения собираются по времени сообщения
+		pageGeneration := generation
+		if pageGeneration == 0 {
+			pageGeneration = msg.Version.UnixNano()
+		}
+		receivedVotings, complete, stale, err = c.snapshots.add(pageGeneration, snap.Page, snap.TotalPages, snap.Votings)
+	}
+	pendingGen, pendingPages, totalPages := c.snapshots.pending()
+	c.Mu.Unlock()
+	if err != nil {
+		return Permanent(err)
+	}
+	if stale {
+		c.Log.Warn("Ignoring page of outdated votings snapshot", slog.Int64("generation", generation), slog.Int64("current_generation", pendingGen))
+		return nil
+	}
+	if !complete {
+		c.Log.Debug("Votings snapshot page received",
+			slog.Int64("generation", pendingGen),
+			slog.Int("pages", pendingPages),
+			slog.Int("total_pages", totalPages))
+		return nil
+	}
 
-			// Теперь получаем слайс голосований из поля Votings новой структуры
-			receivedVotings := kafkaResponse.Votings // <-- ИЗМЕНЕНО
+	c.Log.Info("Successfully consumed all votings list", slog.Int("count", len(receivedVotings)), slog.Int64("generation", generation))
 
-			c.Log.Info("Successfully consumed all votings list", slog.Int("count", len(receivedVotings)))
+	diff, applied := c.reconcile(generation, msg.Version, receivedVotings)
+	if !applied {
+		c.Log.Warn("Ignoring outdated votings snapshot", slog.Int64("generation", generation))
+		return nil
+	}
 
-			c.Mu.Lock()
-			for k := range c.CurrentVotings {
-				delete(c.CurrentVotings, k)
-			}
+	c.Log.Info("Global votings map reconciled with Kafka snapshot",
+		slog.Int64("generation", generation),
+		slog.Any("added", diff.Added),
+		slog.Any("updated", diff.Updated),
+		slog.Any("removed", diff.Removed))
 
-			for _, v := range receivedVotings {
-				// Преобразование float64 в int64 перед передачей в time.Unix
-				startTime := time.Unix(int64(v.StartDate), 0)
-				endTime := time.Unix(int64(v.EndDate), 0)
-
-				newVoting := models.VoteSession{
-					ID:              v.VotingID, // Поле "id" в AllVotingRes теперь мапится на VotingID
-					Title:           v.Title,
-					Description:     v.Description,
-					StartTime:       startTime,
-					EndTime:         endTime,
-					CreatorAddr:     "",
-					IsPrivate:       false,
-					MinNumberVotes:  0,
-					TempNumberVotes: 0,
-					Choices:         []models.Choice{},
-					Voters:          make(map[string]models.Voter),
-					Winner:          []string{},
-					Status:          "Upcoming",
-				}
-				c.CurrentVotings[newVoting.ID] = newVoting
-			}
-			c.Mu.Unlock()
-			c.Log.Info("Global votings map updated from Kafka", slog.Int("new_count", len(c.CurrentVotings)))
-		}
+	c.snapshotAppliedOnce.Do(func() { close(c.snapshotApplied) })
+
+	// Пустой VotingID - обновился весь список
+	c.publishVotingUpdated("")
+	if c.Events != nil {
+		c.Events.Publish(events.Event{Type: events.VotingsReconciled, Payload: diff})
 	}
+	return nil
 } // РАБОТАЕТ
 
-// --- НОВЫЙ КОНСЮМЕР ДЛЯ ОДНОГО ГОЛОСОВАНИЯ ---
-func (c *Consumer) RunVotingByIdMain(ctx context.Context, wg *sync.WaitGroup) {
-	defer wg.Done()
-	c.Log.Info("Starting Kafka consumer for voting-response", slog.String("topic", c.reader.Config().Topic), slog.String("group_id", c.reader.Config().GroupID))
-
-	for {
-		select {
-		case <-ctx.Done():
-			c.Log.Info("Stopping Kafka consumer for voting-response...")
-			if err := c.reader.Close(); err != nil {
-				c.Log.Error("Failed to close Kafka reader for voting-response", slog.Any("error", err))
-			}
-			return
-		default:
-			msg, err := c.reader.ReadMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					c.Log.Debug("Kafka read error during shutdown for voting-response", slog.Any("error", err))
-					continue
-				}
-				c.Log.Error("Kafka read error for voting-response", slog.String("error_details", err.Error()), slog.Any("full_error", err))
-				continue
+// reconcile сверяет голосования с полным снимком: новые добавляются, у известных обновляются поля из снимка
+// (детали из voting-response и состояние шлюза сохраняются), а пропавшие из снимка удаляются и запоминаются
+// как удаленные. Голосования, созданные в шлюзе и еще не известные Java-сервису, не удаляются.
+// Снимок старше уже примененного игнорируется (applied = false).
+func (c *Consumer) reconcile(generation int64, version time.Time, receivedVotings []dto.AllVotingRes) (diff events.SnapshotDiff, applied bool) {
+	c.Mu.Lock()
+	defer c.Mu.Unlock()
+
+	// Номера поколений и время старых снимков без поколения - разные шкалы, поэтому они не сравниваются между собой:
+	// снимок с поколением сравнивается с последним поколением, снимок без него - со временем последнего снимка
+	if generation > 0 && generation <= c.generation {
+		return diff, false
+	}
+	if generation == 0 && !version.After(c.snapshotAt) {
+		return diff, false
+	}
+	if generation > 0 {
+		c.generation = generation
+	}
+	if version.After(c.snapshotAt) {
+		c.snapshotAt = version
+	}
+	diff.Generation = generation
+
+	received := make(map[string]bool, len(receivedVotings))
+	for _, v := range receivedVotings {
+		received[v.VotingID] = true
+		c.known[v.VotingID] = true
+		delete(c.tombstones, v.VotingID)
+
+		// Преобразование float64 в int64 перед передачей в time.Unix
+		startTime := time.Unix(int64(v.StartDate), 0)
+		endTime := time.Unix(int64(v.EndDate), 0)
+
+		existing, ok := c.CurrentVotings[v.VotingID]
+		if !ok {
+			newVoting := models.VoteSession{
+				ID:              v.VotingID, // Поле "id" в AllVotingRes теперь мапится на VotingID
+				Title:           v.Title,
+				Description:     v.Description,
+				StartTime:       startTime,
+				EndTime:         endTime,
+				CreatorAddr:     "",
+				IsPrivate:       false,
+				MinNumberVotes:  0,
+				TempNumberVotes: 0,
+				Choices:         []models.Choice{},
+				Voters:          make(map[string]models.Voter),
+				Winner:          []string{},
 			}
+			c.applySealed(&newVoting)
+			c.CurrentVotings[v.VotingID] = newVoting
+			c.refreshStatus(v.VotingID)
+			diff.Added = append(diff.Added, v.VotingID)
+			continue
+		}
 
-			// Применяем двойной Unmarshal для обработки двойной сериализации JSON
-			var rawMessage string
-			if err := json.Unmarshal(msg.Value, &rawMessage); err != nil {
-				c.Log.Error("Failed to unmarshal Kafka message into raw string (likely double-encoded JSON) for voting-response",
-					slog.Any("error", err),
-					slog.String("message_value", string(msg.Value)))
-				continue
-			}
+		if existing.Title != v.Title || existing.Description != v.Description ||
+			!existing.StartTime.Equal(startTime) || !existing.EndTime.Equal(endTime) {
+			existing.Title = v.Title
+			existing.Description = v.Description
+			existing.StartTime = startTime
+			existing.EndTime = endTime
+			c.CurrentVotings[v.VotingID] = existing
+			c.refreshStatus(v.VotingID)
+			diff.Updated = append(diff.Updated, v.VotingID)
+		}
+	}
 
-			var receivedVoting dto.VotingKafkaResponse
-			if err := json.Unmarshal([]byte(rawMessage), &receivedVoting); err != nil {
-				c.Log.Error("Failed to unmarshal single voting response from raw string (check JSON structure vs DTO) for voting-response",
-					slog.Any("error", err),
-					slog.String("raw_message_value", rawMessage))
-				continue
-			}
+	for id := range c.CurrentVotings {
+		if received[id] || !c.known[id] {
+			continue
+		}
+		delete(c.CurrentVotings, id)
+		delete(c.known, id)
+		delete(c.sealed, id)
+		c.tombstones[id] = version
+		diff.Removed = append(diff.Removed, id)
+	}
 
-			var actualVotesCount int64
-			if receivedVoting.VotesCount != "" { // Проверяем, что не пустая строка
-				val, err := receivedVoting.VotesCount.Int64()
-				if err != nil {
-					c.Log.Error("Failed to parse VotesCount from json.Number to int64",
-						slog.Any("error", err),
-						slog.String("votes_count_json_number", receivedVoting.VotesCount.String()))
-					// Продолжаем с 0 или пропускаем, в зависимости от желаемого поведения
-					continue // Пропускаем сообщение, если не можем распарсить
-				}
-				actualVotesCount = val
-			}
+	for id, at := range c.tombstones {
+		if time.Since(at) > tombstoneTTL {
+			delete(c.tombstones, id)
+		}
+	}
 
-			c.Log.Info("Successfully unmarshalled VotingKafkaResponse",
-				slog.String("voting_id_from_dto", receivedVoting.VotingID),
-				slog.Int64("votes_count_from_dto", actualVotesCount)) // Используем actualVotesCount здесь
-
-			// *****************************************************************
-			// --- Извлекаем ID голосования из тела JSON-сообщения ---
-			// *****************************************************************
-			votingID := receivedVoting.VotingID // <--- ИЗМЕНЕНО: теперь берем ID из DTO
-			if votingID == "" {
-				// Это критическая ошибка, так как без ID мы не можем обновить мапу.
-				c.Log.Error("Received single voting response with empty VotingID in JSON body. Message cannot be processed.", slog.String("message_value", rawMessage))
-				continue // Сообщение игнорируется, так как не можем его идентифицировать
-			}
+	return diff, true
+}
 
-			var calculatedTotalVotes int64
-			for _, opt := range receivedVoting.Options {
-				calculatedTotalVotes += int64(opt.VoteCount)
-			}
+// handleVoting обновляет одно голосование данными из voting-response.
+func (c *Consumer) handleVoting(_ context.Context, msg Message[dto.VotingKafkaResponse]) error {
+	receivedVoting := msg.Value
 
-			if actualVotesCount == 0 {
-				c.Log.Error("ERROR: Votes count is equal to 0 (after conversion)",
-					slog.String("voting_id", receivedVoting.VotingID))
-			} else {
-				c.Log.Info("INFO: Votes count is NOT zero (after conversion)",
-					slog.String("voting_id", receivedVoting.VotingID),
-					slog.Int64("votes_count", actualVotesCount))
-			}
+	var actualVotesCount int64
+	if receivedVoting.VotesCount != "" { // Проверяем, что не пустая строка
+		val, err := receivedVoting.VotesCount.Int64()
+		if err != nil {
+			// Пропускаем сообщение, если не можем распарсить
+			return Permanent(fmt.Errorf("invalid votesCount %q: %w", receivedVoting.VotesCount.String(), err))
+		}
+		actualVotesCount = val
+	}
 
-			c.Log.Info("Successfully consumed single voting response", slog.String("voting_id", votingID), slog.String("title", receivedVoting.Title))
+	c.Log.Info("Successfully unmarshalled VotingKafkaResponse",
+		slog.String("voting_id_from_dto", receivedVoting.VotingID),
+		slog.Int64("votes_count_from_dto", actualVotesCount)) // Используем actualVotesCount здесь
+
+	// *****************************************************************
+	// --- Извлекаем ID голосования из тела JSON-сообщения ---
+	// *****************************************************************
+	votingID := receivedVoting.VotingID // <--- ИЗМЕНЕНО: теперь берем ID из DTO
+	if votingID == "" {
+		// Это критическая ошибка, так как без ID мы не можем обновить мапу.
+		return Permanent(errors.New("empty votingId"))
+	}
 
-			// Преобразование float64 UNIX timestamp в time.Time
-			startTime := time.Unix(int64(receivedVoting.StartDate), 0)
-			endDate := time.Unix(int64(receivedVoting.EndDate), 0)
+	var calculatedTotalVotes int64
+	for _, opt := range receivedVoting.Options {
+		calculatedTotalVotes += int64(opt.VoteCount)
+	}
 
-			// Преобразование OptionRes в models.Choice
-			var choices []models.Choice
-			for _, opt := range receivedVoting.Options {
-				choices = append(choices, models.Choice{
-					Title:      opt.Text,
-					CountVotes: int64(opt.VoteCount),
-				})
-			}
+	if actualVotesCount == 0 {
+		c.Log.Error("ERROR: Votes count is equal to 0 (after conversion)",
+			slog.String("voting_id", receivedVoting.VotingID))
+	} else {
+		c.Log.Info("INFO: Votes count is NOT zero (after conversion)",
+			slog.String("voting_id", receivedVoting.VotingID),
+			slog.Int64("votes_count", actualVotesCount))
+	}
 
-			c.Mu.Lock()
-			currentVoting, exists := c.CurrentVotings[votingID]
-			if !exists {
-				c.Log.Debug("Creating new VoteSession entry for received single voting response", slog.String("voting_id", votingID))
-				currentVoting = models.VoteSession{
-					ID: votingID, // ID берем из поля DTO
-					// Инициализация остальных полей по умолчанию
-					IsPrivate: false,
-					Voters:    make(map[string]models.Voter),
-					Winner:    []string{},
-					Status:    "Upcoming",
-				}
-			}
+	c.Log.Info("Successfully consumed single voting response", slog.String("voting_id", votingID), slog.String("title", receivedVoting.Title))
 
-			// Обновляем поля VoteSession
-			currentVoting.Title = receivedVoting.Title
-			currentVoting.Description = receivedVoting.Description
-			currentVoting.CreatorAddr = receivedVoting.CreatorID
-			currentVoting.MinNumberVotes = receivedVoting.MinVotes
-			currentVoting.StartTime = startTime
-			currentVoting.EndTime = endDate
-			currentVoting.TempNumberVotes = calculatedTotalVotes
-			currentVoting.Choices = choices
+	// Преобразование float64 UNIX timestamp в time.Time
+	startTime := time.Unix(int64(receivedVoting.StartDate), 0)
+	endDate := time.Unix(int64(receivedVoting.EndDate), 0)
 
-			// Поля, которые не обновляются этим сообщением, сохраняют свои значения.
-			// Если эти поля уже были установлены ранее, они останутся без изменений.
+	// Преобразование OptionRes в models.Choice
+	var choices []models.Choice
+	for _, opt := range receivedVoting.Options {
+		choices = append(choices, models.Choice{
+			Title:      opt.Text,
+			CountVotes: int64(opt.VoteCount),
+		})
+	}
 
-			c.CurrentVotings[votingID] = currentVoting
+	c.Mu.Lock()
+	// Запоздавший ответ по голосованию, которое уже пропало из снимка, не должен его вернуть
+	if removedAt, ok := c.tombstones[votingID]; ok {
+		if !msg.Version.After(removedAt) {
 			c.Mu.Unlock()
-			c.Log.Info("Global votings map updated from Kafka with single voting data", slog.String("voting_id", votingID))
-
-			c.Mu.Lock()                                        // Блокируем мьютекс для votingResponseChans
-			respChan, found := c.votingResponseChans[votingID] // Ищем канал по votingID
-			if found {
-				select {
-				case respChan <- currentVoting: // Отправляем обновленное голосование в канал
-					c.Log.Debug("Sent updated voting data to response channel", slog.String("voting_id", votingID))
-				case <-time.After(100 * time.Millisecond): // Таймаут на случай, если канал уже неактивен
-					c.Log.Warn("Timeout sending voting data to response channel, channel likely closed", slog.String("voting_id", votingID))
-				}
-				delete(c.votingResponseChans, votingID) // Удаляем канал после отправки/таймаута
-			} else {
-				c.Log.Debug("No active response channel found for voting ID. Data updated in map only.", slog.String("voting_id", votingID))
-			}
-			c.Mu.Unlock() // Отпускаем мьютекс responseMu
+			c.Log.Info("Ignoring voting response for removed voting", slog.String("voting_id", votingID))
+			return nil
 		}
+		delete(c.tombstones, votingID)
 	}
-}
-
-// RunVoteHistoryConsumer теперь будет просто обновлять UserProfilesHistory
-func (c *Consumer) RunVoteHistoryConsumer(ctx context.Context, wg *sync.WaitGroup) {
-	type JavaHistoryMessage struct {
-		UserID  string        `json:"userId"`
-		History []dto.History `json:"history"`
-	}
-
-	defer wg.Done()
-
-	// Создаем новый ридер для этого топика, как и раньше
-	historyReader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:  []string{"localhost:9092"},             // Или ваш адрес Kafka-брокера
-		Topic:    "vote-history-response",                // Топик для истории профиля
-		GroupID:  "go-app-profile-history-updater-group", // Уникальная группа
-		MinBytes: 10e3,
-		MaxBytes: 10e6,
-	})
-	defer func() {
-		if err := historyReader.Close(); err != nil {
-			c.Log.Error("Failed to close Kafka reader for vote-history-response", slog.Any("error", err))
+	c.known[votingID] = true
+	currentVoting, exists := c.CurrentVotings[votingID]
+	if !exists {
+		c.Log.Debug("Creating new VoteSession entry for received single voting response", slog.String("voting_id", votingID))
+		currentVoting = models.VoteSession{
+			ID: votingID, // ID берем из поля DTO
+			// Инициализация остальных полей по умолчанию
+			IsPrivate: false,
+			Voters:    make(map[string]models.Voter),
+			Winner:    []string{},
 		}
-	}()
-
-	c.Log.Info("Starting Kafka consumer for vote-history-response",
-		slog.String("topic", historyReader.Config().Topic),
-		slog.String("group_id", historyReader.Config().GroupID))
+		c.applySealed(&currentVoting)
+	}
 
-	for {
+	// Обновляем поля VoteSession
+	currentVoting.Title = receivedVoting.Title
+	currentVoting.Description = receivedVoting.Description
+	currentVoting.CreatorAddr = receivedVoting.CreatorID
+	currentVoting.MinNumberVotes = receivedVoting.MinVotes
+	currentVoting.StartTime = startTime
+	currentVoting.EndTime = endDate
+	currentVoting.TempNumberVotes = calculatedTotalVotes
+	currentVoting.Choices = choices
+
+	// Поля, которые не обновляются этим сообщением, сохраняют свои значения.
+	// Если эти поля уже были установлены ранее, они останутся без изменений.
+
+	// Новые счетчики и сроки могут сменить статус и победителя
+	c.CurrentVotings[votingID] = currentVoting
+	c.refreshStatus(votingID)
+	currentVoting = c.CurrentVotings[votingID]
+	c.Mu.Unlock()
+	c.Log.Info("Global votings map updated from Kafka with single voting data", slog.String("voting_id", votingID))
+	c.publishVotingUpdated(votingID)
+
+	c.Mu.Lock()                                        // Блокируем мьютекс для votingResponseChans
+	respChan, found := c.votingResponseChans[votingID] // Ищем канал по votingID
+	if found {
 		select {
-		case <-ctx.Done():
-			c.Log.Info("Stopping Kafka consumer for vote-history-response...")
-			return
-		default:
-			msg, err := historyReader.ReadMessage(ctx)
-			if err != nil {
-				if ctx.Err() != nil {
-					c.Log.Debug("Kafka read error during shutdown for vote-history-response", slog.Any("error", err))
-					continue
-				}
-				c.Log.Error("Kafka read error for vote-history-response", slog.String("error_details", err.Error()), slog.Any("full_error", err))
-				continue
-			}
+		case respChan <- currentVoting: // Отправляем обновленное голосование в канал
+			c.Log.Debug("Sent updated voting data to response channel", slog.String("voting_id", votingID))
+		case <-time.After(100 * time.Millisecond): // Таймаут на случай, если канал уже неактивен
+			c.Log.Warn("Timeout sending voting data to response channel, channel likely closed", slog.String("voting_id", votingID))
+		}
+		delete(c.votingResponseChans, votingID) // Удаляем канал после отправки/таймаута
+	} else {
+		c.Log.Debug("No active response channel found for voting ID. Data updated in map only.", slog.String("voting_id", votingID))
+	}
+	c.Mu.Unlock() // Отпускаем мьютекс responseMu
+	return nil
+}
 
-			var rawMessage string
-			if err := json.Unmarshal(msg.Value, &rawMessage); err != nil {
-				c.Log.Error("Failed to unmarshal Kafka message into raw string (likely double-encoded JSON)",
-					slog.Any("error", err),
-					slog.String("message_value", string(msg.Value)))
-				continue
-			}
+// handleVoteHistory сохраняет историю голосований пользователя из vote-history-response.
+func (c *Consumer) handleVoteHistory(_ context.Context, msg Message[JavaHistoryMessage]) error {
+	userAddress := msg.Value.UserID // <--- ПОЛУЧАЕМ userAddress ИЗ ЗНАЧЕНИЯ СООБЩЕНИЯ
+	if userAddress == "" {
+		return Permanent(errors.New("empty userId"))
+	}
 
-			var javaMsg JavaHistoryMessage
-			if err := json.Unmarshal([]byte(rawMessage), &javaMsg); err != nil {
-				c.Log.Error("Failed to unmarshal Java history message (check JSON structure vs DTO)",
-					slog.Any("error", err),
-					slog.String("raw_message_value", rawMessage))
-				continue
-			}
+	receivedHistory := msg.Value.History
 
-			userAddress := javaMsg.UserID // <--- ПОЛУЧАЕМ userAddress ИЗ ЗНАЧЕНИЯ СООБЩЕНИЯ
-			if userAddress == "" {
-				c.Log.Warn("Received vote history message with empty userId in value. Skipping.",
-					slog.String("message_value", string(msg.Value)))
-				continue
-			}
+	c.Log.Info("Successfully consumed vote history events",
+		slog.String("user_address", userAddress),
+		slog.Int("num_entries", len(receivedHistory)))
 
-			receivedHistory := javaMsg.History
+	// ОБНОВЛЯЕМ ГЛОБАЛЬНУЮ МАПУ ИСТОРИИ ПРОФИЛЯ
+	c.historyMu.Lock()
+	c.userProfilesHistory[userAddress] = receivedHistory
+	c.historyMu.Unlock()
 
-			c.Log.Info("Successfully consumed vote history events",
-				slog.String("user_address", userAddress),
-				slog.Int("num_entries", len(receivedHistory)))
+	c.Log.Info("User profile history map updated",
+		slog.String("user_address", userAddress),
+		slog.Int("history_count_in_map", len(receivedHistory)))
+	return nil
+}
 
-			// ОБНОВЛЯЕМ ГЛОБАЛЬНУЮ МАПУ ИСТОРИИ ПРОФИЛЯ
-			c.Mu.Lock()
-			c.UserProfilesHistory[userAddress] = receivedHistory
-			c.Mu.Unlock()
+// refreshStatus пересчитывает статус голосования после изменения из Kafka. Вызывать под Mu
+func (c *Consumer) refreshStatus(votingID string) {
+	if c.UpdateStatus != nil {
+		c.UpdateStatus(votingID)
+		return
+	}
+	if v, ok := c.CurrentVotings[votingID]; ok {
+		results.Refresh(&v, time.Now())
+		c.CurrentVotings[votingID] = v
+	}
+}
 
-			c.Log.Info("User profile history map updated",
-				slog.String("user_address", userAddress),
-				slog.Int("history_count_in_map", len(c.UserProfilesHistory[userAddress])))
-		}
+// publishVotingUpdated сообщает подписчикам (например, планировщику статусов), что голосование изменилось
+func (c *Consumer) publishVotingUpdated(votingID string) {
+	if c.Events == nil {
+		return
 	}
+	c.Events.Publish(events.Event{Type: events.VotingUpdated, VotingID: votingID})
 }
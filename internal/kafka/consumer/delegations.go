@@ -0,0 +1,56 @@
+package consumer
+
+import (
+	"apiGateway/internal/delegation"
+	"apiGateway/internal/dto"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// handleDelegation восстанавливает делегирование из vote-delegation.
+// Шлюз читает и собственные события: Registry.Apply пропускает те, что старше уже примененных изменений
+func (c *Consumer) handleDelegation(_ context.Context, msg Message[dto.Delegation]) error {
+	event := msg.Value
+	if event.DelegatorID == "" {
+		return Permanent(errors.New("empty delegatorId"))
+	}
+	createdAt, err := time.Parse(time.RFC3339, event.CreatedAt)
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid createdAt %q: %w", event.CreatedAt, err))
+	}
+
+	var revoked bool
+	switch event.Action {
+	case dto.DelegationActionDelegated:
+	case dto.DelegationActionRevoked:
+		revoked = true
+	default:
+		return Permanent(fmt.Errorf("unknown delegation action %q", event.Action))
+	}
+
+	applied, err := c.Delegations.Apply(delegation.Delegation{
+		Delegator: event.DelegatorID,
+		Delegate:  event.DelegateID,
+		VotingID:  event.VotingID,
+		CreatedAt: createdAt,
+	}, revoked)
+	if err != nil {
+		return Permanent(err)
+	}
+	if !applied {
+		return nil
+	}
+
+	c.Log.Debug("Delegation restored from Kafka",
+		slog.String("delegator", event.DelegatorID),
+		slog.String("delegate", event.DelegateID),
+		slog.String("voting_id", event.VotingID),
+		slog.String("action", event.Action))
+	if c.DelegationChanged != nil {
+		c.DelegationChanged(event.VotingID)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package consumer
+
+import (
+	"apiGateway/internal/kafka/codec"
+	"context"
+	"errors"
+	"github.com/segmentio/kafka-go"
+	"time"
+)
+
+// Message - декодированное сообщение топика вместе с исходным сообщением Kafka
+type Message[T any] struct {
+	Value    T
+	Key      string    // Ключ упорядочивания и идемпотентности
+	Version  time.Time // Версия для отбрасывания устаревших сообщений по ключу
+	Envelope codec.Envelope
+	Raw      kafka.Message
+}
+
+// Handler описывает обработку одного топика: как декодировать сообщение, по какому ключу
+// упорядочивать и что делать с результатом. Сообщения с одним ключом обрабатываются строго по порядку.
+type Handler[T any] struct {
+	Topic string // Логическое имя топика (config.Topic*), фактическое берется из конфига
+	// Decode раскладывает сообщение в T. Если не задан, используется кодек по заголовку content-type
+	Decode func(msg kafka.Message) (T, codec.Envelope, error)
+	// Key возвращает ключ сообщения. Если не задан или вернул пустую строку, берется ключ Kafka,
+	// а если нет и его - имя топика (все такие сообщения идут одной очередью)
+	Key func(value T) string
+	// Handle применяет сообщение. Ошибка, обернутая в Permanent, сразу отправляет сообщение в DLQ,
+	// остальные ошибки повторяются до MaxAttempts
+	Handle func(ctx context.Context, msg Message[T]) error
+}
+
+// permanentError - ошибка, которую бесполезно повторять (битое сообщение, не прошло валидацию)
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent помечает ошибку как неповторяемую
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent сообщает, что ошибку не нужно повторять
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// route - обработчик топика со стертым типом сообщения, который хранит Runtime
+type route interface {
+	topic() string
+	prepare(msg kafka.Message) (job, error)
+}
+
+// job - подготовленное к обработке сообщение
+type job struct {
+	key     string
+	version time.Time
+	msg     kafka.Message
+	handle  func(ctx context.Context) error
+}
+
+func (h Handler[T]) topic() string { return h.Topic }
+
+func (h Handler[T]) prepare(msg kafka.Message) (job, error) {
+	var (
+		value T
+		env   codec.Envelope
+		err   error
+	)
+	if h.Decode != nil {
+		value, env, err = h.Decode(msg)
+	} else {
+		env, err = decode(msg, &value)
+	}
+	if err != nil {
+		return job{}, Permanent(err)
+	}
+
+	key := ""
+	if h.Key != nil {
+		key = h.Key(value)
+	}
+	if key == "" {
+		key = string(msg.Key)
+	}
+	if key == "" {
+		key = msg.Topic
+	}
+
+	m := Message[T]{
+		Value:    value,
+		Key:      key,
+		Version:  messageVersion(env, msg),
+		Envelope: env,
+		Raw:      msg,
+	}
+	return job{
+		key:     key,
+		version: m.Version,
+		msg:     msg,
+		handle:  func(ctx context.Context) error { return h.Handle(ctx, m) },
+	}, nil
+}
+
+// decode раскладывает сообщение в v кодеком, выбранным по заголовку content-type
+func decode(msg kafka.Message, v any) (codec.Envelope, error) {
+	var contentType string
+	for _, h := range msg.Headers {
+		if h.Key == codec.HeaderContentType {
+			contentType = string(h.Value)
+		}
+	}
+
+	env, err := codec.ForContentType(contentType).Decode(msg.Value)
+	if err != nil {
+		return env, err
+	}
+	return env, env.Unmarshal(v)
+}
@@ -0,0 +1,142 @@
+package consumer
+
+import (
+	"apiGateway/internal/kafka/codec"
+	"container/list"
+	"github.com/segmentio/kafka-go"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxTrackedKeys - сколько ключей Tracker помнит. Сверх предела забываются ключи, которые дольше всех
+// не обновлялись и чей оффсет уже закоммичен: повторно такие сообщения группа не получит.
+// Без предела карта росла бы на каждый новый ID голосования или коммитмент
+const maxTrackedKeys = 100_000
+
+// Tracker не дает применить одно сообщение дважды при повторной доставке
+// (после перебалансировки группы или если коммит оффсета не прошел).
+// По каждому ключу помнит последнее примененное сообщение: его оффсет, чтобы узнать повтор,
+// и версию, чтобы старое сообщение не затерло более новое состояние.
+type Tracker struct {
+	mu        sync.Mutex
+	maxKeys   int
+	applied   map[string]*list.Element // Значение элемента - *appliedMessage
+	order     *list.List               // Ключи от недавно обновленных к давно не обновлявшимся
+	committed map[string]int64         // Последний закоммиченный оффсет по топику и партиции
+}
+
+type appliedMessage struct {
+	key       string
+	topic     string
+	partition int
+	offset    int64
+	version   time.Time
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		maxKeys:   maxTrackedKeys,
+		applied:   make(map[string]*list.Element),
+		order:     list.New(),
+		committed: make(map[string]int64),
+	}
+}
+
+// lastLocked возвращает последнее примененное сообщение по ключу. Вызывается под t.mu
+func (t *Tracker) lastLocked(k string) (*appliedMessage, bool) {
+	e, ok := t.applied[k]
+	if !ok {
+		return nil, false
+	}
+	return e.Value.(*appliedMessage), true
+}
+
+// Applied сообщает, что это сообщение уже было применено.
+// Сообщения одного ключа лежат в одной партиции, поэтому достаточно сравнить оффсеты
+func (t *Tracker) Applied(msg kafka.Message, key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last, ok := t.lastLocked(msg.Topic + "/" + key)
+	return ok && last.partition == msg.Partition && msg.Offset <= last.offset
+}
+
+// Stale сообщает, что по ключу key уже применено сообщение новее version
+func (t *Tracker) Stale(topic, key string, version time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last, ok := t.lastLocked(topic + "/" + key)
+	return ok && version.Before(last.version)
+}
+
+// Mark запоминает сообщение как примененное
+func (t *Tracker) Mark(msg kafka.Message, key string, version time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := msg.Topic + "/" + key
+	if last, ok := t.lastLocked(k); ok {
+		if version.Before(last.version) {
+			version = last.version
+		}
+		last.partition, last.offset, last.version = msg.Partition, msg.Offset, version
+		t.order.MoveToFront(t.applied[k])
+		return
+	}
+	t.applied[k] = t.order.PushFront(&appliedMessage{key: k, topic: msg.Topic, partition: msg.Partition, offset: msg.Offset, version: version})
+	t.evictLocked()
+}
+
+// Committed запоминает, что оффсет offset партиции закоммичен и сообщения до него включительно
+// повторно не придут
+func (t *Tracker) Committed(topic string, partition int, offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := partitionKey(topic, partition)
+	if last, ok := t.committed[p]; !ok || offset > last {
+		t.committed[p] = offset
+	}
+	t.evictLocked()
+}
+
+// evictLocked забывает давно не обновлявшиеся ключи сверх предела. Сначала вытесняются ключи
+// с закоммиченным оффсетом; ключи, которые еще могут прийти повторно, вытесняются, только если
+// без них предел не соблюсти (например, после перебалансировки партиция досталась другому экземпляру
+// и ее оффсеты здесь больше не коммитятся). Вызывается под t.mu
+func (t *Tracker) evictLocked() {
+	if len(t.applied) <= t.maxKeys {
+		return
+	}
+	for e := t.order.Back(); e != nil && len(t.applied) > t.maxKeys; {
+		prev := e.Prev()
+		last := e.Value.(*appliedMessage)
+		if committed, ok := t.committed[partitionKey(last.topic, last.partition)]; ok && last.offset <= committed {
+			t.removeLocked(e)
+		}
+		e = prev
+	}
+	for len(t.applied) > t.maxKeys {
+		t.removeLocked(t.order.Back())
+	}
+}
+
+func (t *Tracker) removeLocked(e *list.Element) {
+	t.order.Remove(e)
+	delete(t.applied, e.Value.(*appliedMessage).key)
+}
+
+func partitionKey(topic string, partition int) string {
+	return topic + "/" + strconv.Itoa(partition)
+}
+
+// messageVersion - версия сообщения для сравнения по ключу: время из конверта,
+// а для сообщений без конверта - время записи в Kafka
+func messageVersion(env codec.Envelope, msg kafka.Message) time.Time {
+	if !env.ProducedAt.IsZero() {
+		return env.ProducedAt
+	}
+	return msg.Time
+}
@@ -0,0 +1,30 @@
+package consumer
+
+import "sync/atomic"
+
+// TopicMetrics - счетчики обработки сообщений одного топика
+type TopicMetrics struct {
+	Consumed     int64 `json:"consumed"`      // Прочитано из Kafka
+	Processed    int64 `json:"processed"`     // Успешно применено
+	Skipped      int64 `json:"skipped"`       // Повторы и устаревшие сообщения
+	Retried      int64 `json:"retried"`       // Повторные попытки обработки
+	Failed       int64 `json:"failed"`        // Не удалось обработать
+	DeadLettered int64 `json:"dead_lettered"` // Отправлено в DLQ
+	CommitErrors int64 `json:"commit_errors"` // Ошибки коммита оффсетов
+}
+
+type topicCounters struct {
+	consumed, processed, skipped, retried, failed, deadLettered, commitErrors atomic.Int64
+}
+
+func (c *topicCounters) snapshot() TopicMetrics {
+	return TopicMetrics{
+		Consumed:     c.consumed.Load(),
+		Processed:    c.processed.Load(),
+		Skipped:      c.skipped.Load(),
+		Retried:      c.retried.Load(),
+		Failed:       c.failed.Load(),
+		DeadLettered: c.deadLettered.Load(),
+		CommitErrors: c.commitErrors.Load(),
+	}
+}
@@ -0,0 +1,59 @@
+package consumer
+
+import (
+	"slices"
+	"sync"
+)
+
+// offsetTracker считает, какой оффсет можно закоммитить, когда сообщения одной партиции
+// обрабатываются разными воркерами и завершаются не по порядку: коммитится только
+// непрерывный префикс обработанных оффсетов.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[int]*partitionOffsets
+}
+
+type partitionOffsets struct {
+	inFlight []int64 // Оффсеты в порядке чтения, еще не закоммиченные
+	done     map[int64]bool
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[int]*partitionOffsets)}
+}
+
+// fetched регистрирует прочитанный оффсет и сообщает, что это первое сообщение партиции
+func (t *offsetTracker) fetched(partition int, offset int64) (first bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[partition]
+	if !ok {
+		p = &partitionOffsets{done: make(map[int64]bool)}
+		t.partitions[partition] = p
+	}
+	// После перебалансировки сообщение может прийти повторно
+	if slices.Contains(p.inFlight, offset) {
+		return !ok
+	}
+	p.inFlight = append(p.inFlight, offset)
+	return !ok
+}
+
+// completeLocked отмечает оффсет обработанным и возвращает наибольший оффсет,
+// до которого включительно все сообщения партиции обработаны (ok = false, если коммитить нечего).
+// Вызывается под t.mu
+func (t *offsetTracker) completeLocked(partition int, offset int64) (commit int64, ok bool) {
+	p, exists := t.partitions[partition]
+	if !exists {
+		return 0, false
+	}
+	p.done[offset] = true
+
+	for len(p.inFlight) > 0 && p.done[p.inFlight[0]] {
+		commit, ok = p.inFlight[0], true
+		delete(p.done, commit)
+		p.inFlight = p.inFlight[1:]
+	}
+	return commit, ok
+}
@@ -0,0 +1,246 @@
+package consumer
+
+import (
+	"apiGateway/internal/config"
+	"apiGateway/internal/kafka/broker"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestOffsetTrackerCommitsContiguousPrefix(t *testing.T) {
+	type step struct {
+		partition int
+		offset    int64
+		commit    int64
+		ok        bool
+	}
+	tests := []struct {
+		name    string
+		fetched map[int][]int64
+		steps   []step
+	}{
+		{
+			name:    "in order",
+			fetched: map[int][]int64{0: {0, 1, 2}},
+			steps:   []step{{0, 0, 0, true}, {0, 1, 1, true}, {0, 2, 2, true}},
+		},
+		{
+			name:    "out of order waits for the gap",
+			fetched: map[int][]int64{0: {10, 11, 12}},
+			steps:   []step{{0, 12, 0, false}, {0, 11, 0, false}, {0, 10, 12, true}},
+		},
+		{
+			name:    "gap in the middle",
+			fetched: map[int][]int64{0: {0, 1, 2, 3}},
+			steps:   []step{{0, 0, 0, true}, {0, 2, 0, false}, {0, 3, 0, false}, {0, 1, 3, true}},
+		},
+		{
+			name:    "partitions are independent",
+			fetched: map[int][]int64{0: {0, 1}, 1: {5, 6}},
+			steps:   []step{{0, 1, 0, false}, {1, 5, 5, true}, {0, 0, 1, true}, {1, 6, 6, true}},
+		},
+		{
+			name:    "redelivered offset is tracked once",
+			fetched: map[int][]int64{0: {0, 1, 1}},
+			steps:   []step{{0, 1, 0, false}, {0, 0, 1, true}},
+		},
+		{
+			name:    "unknown partition",
+			fetched: map[int][]int64{0: {0}},
+			steps:   []step{{3, 0, 0, false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newOffsetTracker()
+			for partition, offsets := range tt.fetched {
+				for _, offset := range offsets {
+					tracker.fetched(partition, offset)
+				}
+			}
+			for i, s := range tt.steps {
+				commit, ok := tracker.completeLocked(s.partition, s.offset)
+				if commit != s.commit || ok != s.ok {
+					t.Errorf("step %d: complete(%d, %d) = %d, %t, want %d, %t", i, s.partition, s.offset, commit, ok, s.commit, s.ok)
+				}
+			}
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker()
+	now := time.Now()
+	msg := kafka.Message{Topic: "voting-response", Partition: 0, Offset: 5}
+
+	if tracker.Applied(msg, "42") {
+		t.Fatal("Applied() = true before Mark")
+	}
+	tracker.Mark(msg, "42", now)
+
+	for _, tt := range []struct {
+		name    string
+		msg     kafka.Message
+		key     string
+		applied bool
+	}{
+		{"same offset", msg, "42", true},
+		{"earlier offset", kafka.Message{Topic: msg.Topic, Offset: 4}, "42", true},
+		{"next offset", kafka.Message{Topic: msg.Topic, Offset: 6}, "42", false},
+		{"other key", msg, "43", false},
+		{"other partition", kafka.Message{Topic: msg.Topic, Partition: 1, Offset: 5}, "42", false},
+		{"other topic", kafka.Message{Topic: "all-votings-response", Offset: 5}, "42", false},
+	} {
+		if got := tracker.Applied(tt.msg, tt.key); got != tt.applied {
+			t.Errorf("%s: Applied() = %t, want %t", tt.name, got, tt.applied)
+		}
+	}
+
+	if !tracker.Stale(msg.Topic, "42", now.Add(-time.Second)) {
+		t.Error("Stale() = false for an older version")
+	}
+	if tracker.Stale(msg.Topic, "42", now) || tracker.Stale(msg.Topic, "42", now.Add(time.Second)) {
+		t.Error("Stale() = true for the same or a newer version")
+	}
+
+	// Более старая версия не откатывает запомненную
+	tracker.Mark(kafka.Message{Topic: msg.Topic, Offset: 6}, "42", now.Add(-time.Hour))
+	if !tracker.Stale(msg.Topic, "42", now.Add(-time.Second)) {
+		t.Error("Mark() with an older version lowered the stored version")
+	}
+}
+
+func TestTrackerEviction(t *testing.T) {
+	tracker := NewTracker()
+	tracker.maxKeys = 3
+	now := time.Now()
+	mark := func(offset int64, key string) {
+		tracker.Mark(kafka.Message{Topic: "commitments", Offset: offset}, key, now)
+	}
+	tracked := func(key string) bool {
+		_, ok := tracker.lastLocked("commitments/" + key)
+		return ok
+	}
+
+	mark(0, "a")
+	mark(1, "b")
+	mark(2, "c")
+	mark(3, "a") // Ключ a обновлен и вытесняется последним
+
+	// Пока ничего не закоммичено, вытесняется самый давний ключ
+	mark(4, "d")
+	if tracked("b") || !tracked("a") || len(tracker.applied) != 3 {
+		t.Fatalf("tracked b = %t, a = %t, keys = %d, want b evicted", tracked("b"), tracked("a"), len(tracker.applied))
+	}
+
+	// Закоммиченные ключи вытесняются раньше незакоммиченных, даже если обновлялись позже
+	tracker.Committed("commitments", 0, 3)
+	mark(5, "e")
+	if tracked("c") || !tracked("d") {
+		t.Fatalf("tracked c = %t, d = %t, want committed c evicted", tracked("c"), tracked("d"))
+	}
+	mark(6, "f")
+	if tracked("a") || !tracked("d") || !tracked("e") || !tracked("f") {
+		t.Errorf("keys = %d, want a evicted and d, e, f kept", len(tracker.applied))
+	}
+
+	// Оффсет в другой партиции не считается закоммиченным
+	tracker.Committed("commitments", 1, 100)
+	if !tracker.Applied(kafka.Message{Topic: "commitments", Offset: 4}, "d") {
+		t.Error("Applied() = false for a tracked key after commit in another partition")
+	}
+}
+
+// runRuntime запускает рантайм на брокере в памяти, ждет обработки want сообщений и останавливает его
+func runRuntime(t *testing.T, b broker.Broker, cfg config.Kafka, handle func(context.Context, Message[map[string]string]) error, want int) {
+	t.Helper()
+
+	rt := NewRuntime(cfg, b, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	Register(rt, Handler[map[string]string]{
+		Topic:  config.TopicVotingResponse,
+		Key:    func(v map[string]string) string { return v["votingId"] },
+		Handle: handle,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go rt.Run(ctx, wg)
+
+	deadline := time.After(5 * time.Second)
+	for {
+		m := rt.Metrics()[config.TopicVotingResponse]
+		if int(m.Processed+m.Failed) >= want {
+			break
+		}
+		select {
+		case <-deadline:
+			cancel()
+			wg.Wait()
+			t.Fatalf("runtime handled %d of %d messages", m.Processed+m.Failed, want)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	wg.Wait()
+}
+
+func TestRuntimeCommitsHandledMessages(t *testing.T) {
+	b := broker.NewMemory()
+	defer b.Close()
+	cfg := config.Kafka{GroupID: "gateway", Consumer: config.Consumer{Workers: 4, MaxAttempts: 2, RetryBackoff: time.Millisecond}}
+
+	pub, _ := b.NewPublisher(broker.PublisherOptions{})
+	publish := func(values ...string) {
+		for _, v := range values {
+			msg := kafka.Message{Topic: config.TopicVotingResponse, Value: []byte(`{"votingId":"` + v + `"}`)}
+			if err := pub.Publish(context.Background(), msg); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+		}
+	}
+	publish("1", "2", "3", "1")
+
+	var (
+		mu       sync.Mutex
+		handled  []string
+		attempts int
+	)
+	runRuntime(t, b, cfg, func(_ context.Context, msg Message[map[string]string]) error {
+		mu.Lock()
+		defer mu.Unlock()
+		// Первая попытка по голосованию 2 падает и повторяется
+		if msg.Value["votingId"] == "2" && attempts == 0 {
+			attempts++
+			return errors.New("temporary failure")
+		}
+		handled = append(handled, msg.Key)
+		return nil
+	}, 4)
+
+	if len(handled) != 4 {
+		t.Fatalf("handled = %v, want 4 messages", handled)
+	}
+
+	// Группа продолжает с закоммиченного оффсета: старые сообщения не приходят повторно
+	publish("4")
+	handled = nil
+	runRuntime(t, b, cfg, func(_ context.Context, msg Message[map[string]string]) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, msg.Key)
+		return nil
+	}, 1)
+
+	if len(handled) != 1 || handled[0] != "4" {
+		t.Errorf("handled after restart = %v, want [4]", handled)
+	}
+}
@@ -0,0 +1,60 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"log/slog"
+)
+
+// Handles сообщает, зарегистрирован ли обработчик топика с логическим именем key
+func (r *Runtime) Handles(key string) bool {
+	_, ok := r.routes[r.cfg.TopicName(key)]
+	return ok
+}
+
+// Replay применяет все сообщения топика с начала до конца, который был на момент вызова,
+// не трогая оффсеты группы. Нужен, чтобы восстановить состояние из compacted-топика при старте.
+// Применение идет через тот же обработчик и Tracker, поэтому сообщения, которые рантайм потом
+// получит повторно, второй раз не применятся. progress вызывается после каждого сообщения.
+func (r *Runtime) Replay(ctx context.Context, key string, progress func(applied int)) (int, error) {
+	topic := r.cfg.TopicName(key)
+	rt, ok := r.routes[topic]
+	if !ok {
+		return 0, fmt.Errorf("topic %s is not registered", topic)
+	}
+
+	applied := 0
+	err := r.broker.Replay(ctx, topic, func(msg kafka.Message) {
+		r.replayMessage(ctx, rt, msg)
+		applied++
+		if progress != nil {
+			progress(applied)
+		}
+	})
+	if err != nil {
+		return applied, err
+	}
+	r.log.Debug("Topic replayed", slog.String("topic", topic), slog.Int("messages", applied))
+	return applied, nil
+}
+
+// replayMessage применяет одно сообщение при восстановлении. Битые сообщения только логируются:
+// они уже были обработаны (и при необходимости отправлены в DLQ) при первом чтении
+func (r *Runtime) replayMessage(ctx context.Context, rt route, msg kafka.Message) {
+	j, err := rt.prepare(msg)
+	if err != nil {
+		r.log.Warn("Skipping undecodable message during replay",
+			slog.String("topic", msg.Topic), slog.Int64("offset", msg.Offset), slog.Any("error", err))
+		return
+	}
+	if r.tracker.Stale(msg.Topic, j.key, j.version) {
+		return
+	}
+	if err := j.handle(ctx); err != nil {
+		r.log.Warn("Failed to apply message during replay",
+			slog.String("topic", msg.Topic), slog.Int64("offset", msg.Offset), slog.Any("error", err))
+		return
+	}
+	r.tracker.Mark(msg, j.key, j.version)
+}
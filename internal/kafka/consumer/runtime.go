@@ -0,0 +1,326 @@
+package consumer
+
+import (
+	"apiGateway/internal/config"
+	"apiGateway/internal/kafka/broker"
+	"apiGateway/internal/kafka/dlq"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// commitTimeout - сколько ждать коммит оффсета. Коммит не привязан к ctx рантайма,
+// чтобы уже примененное при остановке сервиса сообщение не пришло повторно
+const commitTimeout = 5 * time.Second
+
+// maxFetchBackoff - предел паузы между попытками чтения, когда брокер недоступен
+const maxFetchBackoff = 30 * time.Second
+
+// Runtime читает все зарегистрированные топики и раздает сообщения пулу воркеров.
+// Сообщения с одним ключом всегда попадают к одному воркеру и обрабатываются по порядку.
+// Оффсет коммитится только после того, как обработаны все предыдущие сообщения партиции.
+type Runtime struct {
+	cfg     config.Kafka
+	broker  broker.Broker
+	log     *slog.Logger
+	routes  map[string]route
+	tracker *Tracker
+	metrics map[string]*topicCounters
+
+	DLQ *dlq.Writer // Если задан, получает сообщения, которые не удалось обработать
+}
+
+// task - сообщение, отданное воркеру
+type task struct {
+	job     job
+	sub     broker.Subscription
+	offsets *offsetTracker
+}
+
+func NewRuntime(cfg config.Kafka, b broker.Broker, log *slog.Logger) *Runtime {
+	if cfg.Consumer.Workers < 1 {
+		cfg.Consumer.Workers = 1
+	}
+	if cfg.Consumer.MaxAttempts < 1 {
+		cfg.Consumer.MaxAttempts = 1
+	}
+
+	return &Runtime{
+		cfg:     cfg,
+		broker:  b,
+		log:     log.With(slog.String("component", "kafka/consumer")),
+		routes:  make(map[string]route),
+		tracker: NewTracker(),
+		metrics: make(map[string]*topicCounters),
+	}
+}
+
+// Register добавляет обработчик топика. Вызывается до Run
+func Register[T any](r *Runtime, h Handler[T]) {
+	if h.Handle == nil {
+		panic(fmt.Sprintf("kafka consumer: handler for topic %s has no Handle func", h.Topic))
+	}
+	// Дальше топик везде идет под фактическим именем - так он приходит в kafka.Message
+	h.Topic = r.cfg.TopicName(h.Topic)
+	if _, ok := r.routes[h.Topic]; ok {
+		panic(fmt.Sprintf("kafka consumer: topic %s registered twice", h.Topic))
+	}
+	r.routes[h.Topic] = h
+	r.metrics[h.Topic] = &topicCounters{}
+}
+
+// Metrics возвращает счетчики по каждому топику
+func (r *Runtime) Metrics() map[string]TopicMetrics {
+	out := make(map[string]TopicMetrics, len(r.metrics))
+	for topic, c := range r.metrics {
+		out[topic] = c.snapshot()
+	}
+	return out
+}
+
+// Run запускает чтение всех топиков и блокируется до отмены ctx.
+// При остановке дожидается обработки уже прочитанных сообщений и коммитит их оффсеты.
+func (r *Runtime) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	workers := make([]chan task, r.cfg.Consumer.Workers)
+	workersWg := &sync.WaitGroup{}
+	// Уже прочитанные сообщения дорабатываются и после отмены ctx, повторы при этом прекращаются
+	handleCtx := context.WithoutCancel(ctx)
+	for i := range workers {
+		workers[i] = make(chan task, 64)
+		workersWg.Add(1)
+		go r.work(ctx, handleCtx, workers[i], workersWg)
+	}
+
+	subs := make([]broker.Subscription, 0, len(r.routes))
+	fetchWg := &sync.WaitGroup{}
+	for topic, rt := range r.routes {
+		// startOffset - откуда читать, если у группы еще нет закоммиченного оффсета
+		sub, err := r.broker.Subscribe(topic, r.groupID(topic), startOffset(r.cfg.AutoOffsetReset))
+		if err != nil {
+			r.log.Error("Failed to subscribe to Kafka topic", slog.String("topic", topic), slog.Any("error", err))
+			continue
+		}
+		subs = append(subs, sub)
+
+		fetchWg.Add(1)
+		go r.fetch(ctx, sub, rt, workers, fetchWg)
+	}
+
+	r.log.Info("Kafka consumer runtime started",
+		slog.Int("topics", len(r.routes)),
+		slog.Int("workers", len(workers)))
+
+	fetchWg.Wait()
+	for _, w := range workers {
+		close(w)
+	}
+	workersWg.Wait()
+
+	for _, sub := range subs {
+		if err := sub.Close(); err != nil {
+			r.log.Error("Failed to close Kafka subscription", slog.String("topic", sub.Topic()), slog.Any("error", err))
+		}
+	}
+	r.log.Info("Kafka consumer runtime stopped")
+}
+
+// fetch читает топик и раздает сообщения воркерам по хешу ключа
+func (r *Runtime) fetch(ctx context.Context, sub broker.Subscription, rt route, workers []chan task, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	topic := rt.topic()
+	counters := r.metrics[topic]
+	offsets := newOffsetTracker()
+	r.log.Info("Starting Kafka consumer", slog.String("topic", topic), slog.String("group_id", r.groupID(topic)))
+
+	failures := 0
+	for {
+		msg, err := sub.Fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				r.log.Info("Stopping Kafka consumer...", slog.String("topic", topic))
+				return
+			}
+			if errors.Is(err, broker.ErrClosed) {
+				r.log.Warn("Kafka subscription closed, stopping consumer", slog.String("topic", topic))
+				return
+			}
+			// Брокер недоступен: пауза растет с каждой ошибкой подряд, чтобы не крутить цикл и не заваливать лог
+			failures++
+			r.log.Error("Kafka read error", slog.String("topic", topic), slog.Int("failures", failures), slog.Any("error", err))
+			select {
+			case <-time.After(fetchBackoff(r.cfg.Consumer.RetryBackoff, failures)):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		failures = 0
+		counters.consumed.Add(1)
+		if offsets.fetched(msg.Partition, msg.Offset) {
+			// Группа читает партицию с закоммиченного оффсета: более ранние сообщения повторно не придут
+			r.tracker.Committed(msg.Topic, msg.Partition, msg.Offset-1)
+		}
+
+		j, err := rt.prepare(msg)
+		if err != nil {
+			r.log.Error("Failed to decode Kafka message (check JSON structure vs DTO)",
+				slog.String("topic", topic),
+				slog.Int64("offset", msg.Offset),
+				slog.Any("error", err),
+				slog.String("message_value", string(msg.Value)))
+			counters.failed.Add(1)
+			r.finish(task{sub: sub, offsets: offsets, job: job{msg: msg}}, r.deadLetter(ctx, msg, err))
+			continue
+		}
+
+		select {
+		case workers[workerFor(j.key, len(workers))] <- task{job: j, sub: sub, offsets: offsets}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// fetchBackoff - пауза после failures ошибок чтения подряд: kafka.consumer.retry_backoff, умноженный на число ошибок
+func fetchBackoff(base time.Duration, failures int) time.Duration {
+	if base <= 0 {
+		base = time.Second
+	}
+	return min(base*time.Duration(failures), maxFetchBackoff)
+}
+
+// work обрабатывает сообщения своей очереди по одному
+func (r *Runtime) work(ctx, handleCtx context.Context, tasks <-chan task, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for t := range tasks {
+		r.finish(t, r.process(ctx, handleCtx, t.job))
+	}
+}
+
+// process применяет сообщение с повторами и возвращает, можно ли считать его обработанным
+func (r *Runtime) process(ctx, handleCtx context.Context, j job) bool {
+	msg := j.msg
+	counters := r.metrics[msg.Topic]
+
+	if r.tracker.Applied(msg, j.key) || r.tracker.Stale(msg.Topic, j.key, j.version) {
+		r.log.Debug("Skipping already applied or stale Kafka message",
+			slog.String("topic", msg.Topic),
+			slog.String("key", j.key),
+			slog.Int64("offset", msg.Offset))
+		counters.skipped.Add(1)
+		r.tracker.Mark(msg, j.key, j.version)
+		return true
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = j.handle(handleCtx); err == nil {
+			counters.processed.Add(1)
+			r.tracker.Mark(msg, j.key, j.version)
+			return true
+		}
+		if IsPermanent(err) || attempt >= r.cfg.Consumer.MaxAttempts {
+			break
+		}
+
+		counters.retried.Add(1)
+		r.log.Warn("Kafka message handling failed, retrying",
+			slog.String("topic", msg.Topic),
+			slog.String("key", j.key),
+			slog.Int("attempt", attempt),
+			slog.Any("error", err))
+
+		select {
+		case <-time.After(r.cfg.Consumer.RetryBackoff * time.Duration(attempt)):
+		case <-ctx.Done():
+			// Сервис останавливается: оффсет не коммитим, сообщение придет снова после перезапуска
+			return false
+		}
+	}
+
+	counters.failed.Add(1)
+	r.log.Error("Failed to handle Kafka message",
+		slog.String("topic", msg.Topic),
+		slog.String("key", j.key),
+		slog.Int64("offset", msg.Offset),
+		slog.Any("error", err))
+	return r.deadLetter(handleCtx, msg, err)
+}
+
+// deadLetter пересылает необработанное сообщение в dead-letter топик, чтобы его можно было повторить после исправления.
+// Если переслать не удалось, сообщение не считается обработанным и его оффсет не коммитится.
+func (r *Runtime) deadLetter(ctx context.Context, msg kafka.Message, cause error) bool {
+	if r.DLQ == nil {
+		return true
+	}
+	if err := r.DLQ.Send(ctx, msg, cause); err != nil {
+		r.log.Error("Failed to move message to dead-letter topic",
+			slog.String("topic", msg.Topic),
+			slog.Int64("offset", msg.Offset),
+			slog.Any("error", err))
+		return false
+	}
+	r.metrics[msg.Topic].deadLettered.Add(1)
+	return true
+}
+
+// finish отмечает сообщение обработанным и коммитит непрерывный префикс оффсетов партиции
+func (r *Runtime) finish(t task, done bool) {
+	if !done {
+		return
+	}
+	msg := t.job.msg
+
+	// Коммиты одной партиции выполняются по очереди, чтобы меньший оффсет не перезаписал больший
+	t.offsets.mu.Lock()
+	defer t.offsets.mu.Unlock()
+	commit, ok := t.offsets.completeLocked(msg.Partition, msg.Offset)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+	defer cancel()
+	if err := t.sub.Commit(ctx, kafka.Message{Topic: msg.Topic, Partition: msg.Partition, Offset: commit}); err != nil {
+		r.metrics[msg.Topic].commitErrors.Add(1)
+		r.log.Error("Failed to commit Kafka offset",
+			slog.String("topic", msg.Topic),
+			slog.Int("partition", msg.Partition),
+			slog.Int64("offset", commit),
+			slog.Any("error", err))
+		return
+	}
+	r.tracker.Committed(msg.Topic, msg.Partition, commit)
+}
+
+// groupID возвращает группу консюмеров топика из kafka.topics
+func (r *Runtime) groupID(topic string) string {
+	for _, key := range config.KnownTopics {
+		if t := r.cfg.Topic(key); t.Name == topic {
+			return t.GroupID
+		}
+	}
+	return r.cfg.TopicPrefix + r.cfg.GroupID
+}
+
+// workerFor выбирает воркера по ключу сообщения
+func workerFor(key string, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
+}
+
+// startOffset переводит auto_offset_reset из конфига в стартовый оффсет ридера
+func startOffset(autoOffsetReset string) int64 {
+	if autoOffsetReset == "latest" {
+		return kafka.LastOffset
+	}
+	return kafka.FirstOffset
+}
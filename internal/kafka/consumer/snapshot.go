@@ -0,0 +1,66 @@
+package consumer
+
+import (
+	"apiGateway/internal/dto"
+	"fmt"
+	"time"
+)
+
+// tombstoneTTL - сколько помнить удаленные голосования, чтобы запоздавший voting-response их не вернул
+const tombstoneTTL = 24 * time.Hour
+
+// snapshotAssembler собирает снимок всех голосований из страниц одного поколения.
+// Собирается только одно поколение: страница более нового поколения отбрасывает недособранное старое.
+type snapshotAssembler struct {
+	generation int64
+	total      int
+	pages      map[int][]dto.AllVotingRes
+}
+
+// add добавляет страницу и возвращает весь снимок, когда собраны все страницы.
+// stale = true, если страница относится к более старому поколению, чем собираемое.
+func (a *snapshotAssembler) add(generation int64, page, total int, votings []dto.AllVotingRes) (snapshot []dto.AllVotingRes, complete, stale bool, err error) {
+	if total <= 1 && page == 0 {
+		// Снимок из одной страницы
+		if a.pages != nil && generation < a.generation {
+			return nil, false, true, nil
+		}
+		a.reset()
+		return votings, true, false, nil
+	}
+	if page < 0 || page >= total {
+		return nil, false, false, fmt.Errorf("snapshot page %d out of range, total %d", page, total)
+	}
+
+	switch {
+	case a.pages == nil || generation > a.generation:
+		a.generation = generation
+		a.total = total
+		a.pages = make(map[int][]dto.AllVotingRes, total)
+	case generation < a.generation:
+		return nil, false, true, nil
+	case total != a.total:
+		return nil, false, false, fmt.Errorf("snapshot generation %d changed page count from %d to %d", generation, a.total, total)
+	}
+
+	a.pages[page] = votings
+	if len(a.pages) < a.total {
+		return nil, false, false, nil
+	}
+
+	for i := 0; i < a.total; i++ {
+		snapshot = append(snapshot, a.pages[i]...)
+	}
+	a.reset()
+	return snapshot, true, false, nil
+}
+
+// pending возвращает число собранных страниц недособранного снимка
+func (a *snapshotAssembler) pending() (generation int64, pages, total int) {
+	return a.generation, len(a.pages), a.total
+}
+
+func (a *snapshotAssembler) reset() {
+	a.pages = nil
+	a.total = 0
+}
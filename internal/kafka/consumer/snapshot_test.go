@@ -0,0 +1,241 @@
+package consumer
+
+import (
+	"apiGateway/internal/dto"
+	"apiGateway/internal/models"
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+func votingsPage(ids ...string) []dto.AllVotingRes {
+	page := make([]dto.AllVotingRes, len(ids))
+	for i, id := range ids {
+		page[i] = dto.AllVotingRes{VotingID: id, Title: "voting " + id}
+	}
+	return page
+}
+
+func ids(votings []dto.AllVotingRes) []string {
+	out := make([]string, len(votings))
+	for i, v := range votings {
+		out[i] = v.VotingID
+	}
+	return out
+}
+
+func TestSnapshotAssembler(t *testing.T) {
+	type page struct {
+		generation  int64
+		page, total int
+		ids         []string
+
+		snapshot []string // Ожидаемый снимок, если он собран
+		stale    bool
+		err      bool
+	}
+	tests := []struct {
+		name  string
+		pages []page
+	}{
+		{
+			name:  "single page snapshot",
+			pages: []page{{generation: 1, total: 1, ids: []string{"1", "2"}, snapshot: []string{"1", "2"}}},
+		},
+		{
+			name:  "legacy message without page fields",
+			pages: []page{{generation: 5, ids: []string{"1"}, snapshot: []string{"1"}}},
+		},
+		{
+			name: "pages out of order are assembled by page number",
+			pages: []page{
+				{generation: 1, page: 2, total: 3, ids: []string{"5"}},
+				{generation: 1, page: 0, total: 3, ids: []string{"1", "2"}},
+				{generation: 1, page: 1, total: 3, ids: []string{"3", "4"}, snapshot: []string{"1", "2", "3", "4", "5"}},
+			},
+		},
+		{
+			name: "newer generation drops the unfinished one",
+			pages: []page{
+				{generation: 1, page: 0, total: 2, ids: []string{"old"}},
+				{generation: 2, page: 1, total: 2, ids: []string{"3"}},
+				{generation: 2, page: 0, total: 2, ids: []string{"1"}, snapshot: []string{"1", "3"}},
+			},
+		},
+		{
+			name: "page of an older generation is stale",
+			pages: []page{
+				{generation: 2, page: 0, total: 2, ids: []string{"1"}},
+				{generation: 1, page: 1, total: 2, ids: []string{"old"}, stale: true},
+				{generation: 1, page: 0, total: 1, ids: []string{"old"}, stale: true},
+				{generation: 2, page: 1, total: 2, ids: []string{"2"}, snapshot: []string{"1", "2"}},
+			},
+		},
+		{
+			name: "repeated page does not complete the snapshot",
+			pages: []page{
+				{generation: 1, page: 0, total: 2, ids: []string{"1"}},
+				{generation: 1, page: 0, total: 2, ids: []string{"1"}},
+				{generation: 1, page: 1, total: 2, ids: []string{"2"}, snapshot: []string{"1", "2"}},
+			},
+		},
+		{
+			name:  "page out of range",
+			pages: []page{{generation: 1, page: 3, total: 2, err: true}},
+		},
+		{
+			name: "page count changed within a generation",
+			pages: []page{
+				{generation: 1, page: 0, total: 2, ids: []string{"1"}},
+				{generation: 1, page: 1, total: 3, err: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a snapshotAssembler
+			for i, p := range tt.pages {
+				snapshot, complete, stale, err := a.add(p.generation, p.page, p.total, votingsPage(p.ids...))
+				if (err != nil) != p.err {
+					t.Fatalf("page %d: error = %v, want error %t", i, err, p.err)
+				}
+				if stale != p.stale {
+					t.Errorf("page %d: stale = %t, want %t", i, stale, p.stale)
+				}
+				if complete != (p.snapshot != nil) {
+					t.Fatalf("page %d: complete = %t, want %t", i, complete, p.snapshot != nil)
+				}
+				if complete && !slices.Equal(ids(snapshot), p.snapshot) {
+					t.Errorf("page %d: snapshot = %v, want %v", i, ids(snapshot), p.snapshot)
+				}
+			}
+		})
+	}
+}
+
+func newTestConsumer() *Consumer {
+	return NewConsumer(make(map[string]models.VoteSession), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func (c *Consumer) votingIDs() []string {
+	c.Mu.RLock()
+	defer c.Mu.RUnlock()
+	out := make([]string, 0, len(c.CurrentVotings))
+	for id := range c.CurrentVotings {
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestReconcileTombstones(t *testing.T) {
+	c := newTestConsumer()
+	ctx := context.Background()
+	t0 := time.Now()
+
+	snapshot := func(generation int64, at time.Time, page, total int, ids ...string) {
+		t.Helper()
+		err := c.handleAllVotings(ctx, Message[dto.AllVotingsKafkaResponse]{
+			Value:   dto.AllVotingsKafkaResponse{Votings: votingsPage(ids...), Generation: generation, Page: page, TotalPages: total},
+			Version: at,
+		})
+		if err != nil {
+			t.Fatalf("handleAllVotings() error = %v", err)
+		}
+	}
+	votingResponse := func(id string, at time.Time) {
+		t.Helper()
+		err := c.handleVoting(ctx, Message[dto.VotingKafkaResponse]{
+			Value:   dto.VotingKafkaResponse{VotingID: id, Title: "voting " + id, VotesCount: "1"},
+			Version: at,
+		})
+		if err != nil {
+			t.Fatalf("handleVoting() error = %v", err)
+		}
+	}
+
+	// Голосование, созданное через шлюз, Java-сервис еще не присылал - снимок его не удаляет
+	c.CurrentVotings["local"] = models.VoteSession{ID: "local"}
+
+	snapshot(1, t0, 0, 2, "1", "2")
+	if got := c.votingIDs(); !slices.Equal(got, []string{"local"}) {
+		t.Fatalf("votings after the first page = %v, want only local", got)
+	}
+	snapshot(1, t0, 1, 2, "3")
+	if got, want := c.votingIDs(), []string{"1", "2", "3", "local"}; !slices.Equal(got, want) {
+		t.Fatalf("votings = %v, want %v", got, want)
+	}
+
+	t1 := t0.Add(time.Minute)
+	snapshot(2, t1, 0, 1, "1", "3")
+	if got, want := c.votingIDs(), []string{"1", "3", "local"}; !slices.Equal(got, want) {
+		t.Fatalf("votings after removal = %v, want %v", got, want)
+	}
+	if _, ok := c.tombstones["2"]; !ok {
+		t.Fatal("removed voting 2 has no tombstone")
+	}
+
+	// Более старый снимок уже ничего не меняет
+	snapshot(1, t0, 0, 1, "1", "2", "3")
+	if got, want := c.votingIDs(), []string{"1", "3", "local"}; !slices.Equal(got, want) {
+		t.Errorf("votings after an outdated snapshot = %v, want %v", got, want)
+	}
+
+	// Запоздавший ответ не возвращает удаленное голосование, а более новый - возвращает
+	votingResponse("2", t0.Add(30*time.Second))
+	if _, ok := c.CurrentVotings["2"]; ok {
+		t.Error("late voting-response restored a removed voting")
+	}
+	votingResponse("2", t1.Add(time.Second))
+	if _, ok := c.CurrentVotings["2"]; !ok {
+		t.Error("newer voting-response did not restore the voting")
+	}
+	if _, ok := c.tombstones["2"]; ok {
+		t.Error("tombstone was kept after the voting came back")
+	}
+}
+
+func TestReconcileLegacyAndGenerations(t *testing.T) {
+	c := newTestConsumer()
+	ctx := context.Background()
+	t0 := time.Now()
+
+	snapshot := func(generation int64, at time.Time, ids ...string) {
+		t.Helper()
+		err := c.handleAllVotings(ctx, Message[dto.AllVotingsKafkaResponse]{
+			Value:   dto.AllVotingsKafkaResponse{Votings: votingsPage(ids...), Generation: generation},
+			Version: at,
+		})
+		if err != nil {
+			t.Fatalf("handleAllVotings() error = %v", err)
+		}
+	}
+	expect := func(want ...string) {
+		t.Helper()
+		if got := c.votingIDs(); !slices.Equal(got, want) {
+			t.Fatalf("votings = %v, want %v", got, want)
+		}
+	}
+
+	// Снимок без поколения не блокирует последующие снимки с небольшими номерами поколений
+	snapshot(0, t0, "1")
+	expect("1")
+	snapshot(1, t0.Add(time.Second), "1", "2")
+	expect("1", "2")
+	snapshot(1, t0.Add(2*time.Second), "1")
+	expect("1", "2")
+
+	// Снимки без поколения упорядочиваются только по времени
+	snapshot(0, t0.Add(time.Second/2), "3")
+	expect("1", "2")
+	snapshot(0, t0.Add(3*time.Second), "2", "3")
+	expect("2", "3")
+
+	snapshot(2, t0.Add(4*time.Second), "4")
+	expect("4")
+}
@@ -0,0 +1,185 @@
+package dlq
+
+import (
+	"apiGateway/internal/config"
+	"apiGateway/internal/kafka/broker"
+	"context"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки, которыми помечается сообщение в dead-letter топике
+const (
+	HeaderError             = "dlq-error"
+	HeaderOriginalTopic     = "dlq-original-topic"
+	HeaderOriginalPartition = "dlq-original-partition"
+	HeaderOriginalOffset    = "dlq-original-offset"
+	HeaderFailedAt          = "dlq-failed-at"
+	HeaderReplayedAt        = "dlq-replayed-at"
+
+	headerPrefix = "dlq-"
+)
+
+// Writer пересылает необработанные сообщения в <topic><suffix> и умеет возвращать их обратно
+type Writer struct {
+	publisher  broker.Publisher
+	subscriber broker.Subscriber
+	groupID    string
+	suffix     string
+	log        *slog.Logger
+}
+
+// New создает Writer. Если DLQ выключен в конфиге, возвращает nil:
+// методы Writer безопасно вызывать на nil, сообщения тогда только логируются.
+func New(cfg config.Kafka, b broker.Broker, log *slog.Logger) (*Writer, error) {
+	if !cfg.DLQ.Enabled {
+		log.Info("Kafka dead-letter topics disabled")
+		return nil, nil
+	}
+
+	// Dead-letter топики заранее не объявляются, поэтому создаются при первой отправке
+	publisher, err := b.NewPublisher(broker.PublisherOptions{AutoCreateTopics: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		publisher:  publisher,
+		subscriber: b,
+		groupID:    cfg.TopicPrefix + cfg.GroupID + "-dlq-replay",
+		suffix:     cfg.DLQ.Suffix,
+		log:        log,
+	}, nil
+}
+
+// Topic возвращает имя dead-letter топика для topic
+func (w *Writer) Topic(topic string) string {
+	return topic + w.suffix
+}
+
+// Send отправляет сообщение msg, которое не удалось обработать из-за cause, в dead-letter топик.
+// Ключ, значение и исходные заголовки сохраняются без изменений.
+func (w *Writer) Send(ctx context.Context, msg kafka.Message, cause error) error {
+	if w == nil {
+		return nil
+	}
+
+	reason := "unknown error"
+	if cause != nil {
+		reason = cause.Error()
+	}
+
+	headers := withoutDLQHeaders(msg.Headers)
+	headers = append(headers,
+		kafka.Header{Key: HeaderError, Value: []byte(reason)},
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderOriginalPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: HeaderFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	dead := kafka.Message{
+		Topic:   w.Topic(msg.Topic),
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+	if err := w.publisher.Publish(ctx, dead); err != nil {
+		return fmt.Errorf("failed to write message to dead-letter topic %s: %w", dead.Topic, err)
+	}
+
+	w.log.Warn("Message moved to dead-letter topic",
+		slog.String("topic", msg.Topic),
+		slog.String("dlq_topic", dead.Topic),
+		slog.Int("partition", msg.Partition),
+		slog.Int64("offset", msg.Offset),
+		slog.String("reason", reason))
+	return nil
+}
+
+// Replay вычитывает до limit сообщений из dead-letter топика для topic (limit <= 0 - все)
+// и отправляет их обратно в исходный топик. Читается все, что лежало в DLQ на момент вызова,
+// до high-water mark каждой партиции; сообщения, пришедшие позже, остаются до следующего повтора.
+// Оффсеты DLQ коммитятся отдельной группой, поэтому одно и то же сообщение повторно не отправляется.
+// Возвращает количество отправленных сообщений.
+func (w *Writer) Replay(ctx context.Context, topic string, limit int) (int, error) {
+	if w == nil {
+		return 0, fmt.Errorf("dead-letter topics are disabled")
+	}
+
+	marks, err := w.subscriber.Unread(ctx, w.Topic(topic), w.groupID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read offsets of dead-letter topic %s: %w", w.Topic(topic), err)
+	}
+	if len(marks) == 0 {
+		w.log.Info("Dead-letter topic is empty, nothing to replay", slog.String("topic", topic))
+		return 0, nil
+	}
+
+	sub, err := w.subscriber.Subscribe(w.Topic(topic), w.groupID, kafka.FirstOffset)
+	if err != nil {
+		return 0, fmt.Errorf("failed to subscribe to dead-letter topic %s: %w", w.Topic(topic), err)
+	}
+	defer func() {
+		if err := sub.Close(); err != nil {
+			w.log.Error("Failed to close dead-letter reader", slog.String("dlq_topic", w.Topic(topic)), slog.Any("error", err))
+		}
+	}()
+
+	replayed := 0
+	for len(marks) > 0 && (limit <= 0 || replayed < limit) {
+		msg, err := sub.Fetch(ctx)
+		if err != nil {
+			return replayed, fmt.Errorf("failed to read dead-letter topic %s: %w", w.Topic(topic), err)
+		}
+		mark, ok := marks[msg.Partition]
+		if !ok || msg.Offset >= mark {
+			// Пришло после начала повтора: без коммита оно достанется следующему повтору
+			continue
+		}
+
+		original := kafka.Message{
+			Topic: topic,
+			Key:   msg.Key,
+			Value: msg.Value,
+			Headers: append(withoutDLQHeaders(msg.Headers),
+				kafka.Header{Key: HeaderReplayedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))}),
+		}
+		if err := w.publisher.Publish(ctx, original); err != nil {
+			return replayed, fmt.Errorf("failed to replay message to topic %s: %w", topic, err)
+		}
+		if err := sub.Commit(ctx, msg); err != nil {
+			return replayed, fmt.Errorf("failed to commit dead-letter offset: %w", err)
+		}
+		replayed++
+		if msg.Offset >= mark-1 {
+			delete(marks, msg.Partition)
+		}
+	}
+
+	w.log.Info("Dead-letter messages replayed", slog.String("topic", topic), slog.Int("count", replayed))
+	return replayed, nil
+}
+
+// Close закрывает writer
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+	return w.publisher.Close()
+}
+
+// withoutDLQHeaders убирает служебные заголовки DLQ, чтобы они не копились при повторных падениях
+func withoutDLQHeaders(headers []kafka.Header) []kafka.Header {
+	out := make([]kafka.Header, 0, len(headers)+5)
+	for _, h := range headers {
+		if !strings.HasPrefix(h.Key, headerPrefix) {
+			out = append(out, h)
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package dlq
+
+import (
+	"apiGateway/internal/config"
+	"apiGateway/internal/kafka/broker"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const topic = "voting-response"
+
+func newWriter(t *testing.T, b broker.Broker) *Writer {
+	t.Helper()
+	w, err := New(config.Kafka{GroupID: "gateway", DLQ: config.DLQ{Enabled: true, Suffix: ".dlq"}}, b, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return w
+}
+
+func sendFailed(t *testing.T, w *Writer, values ...string) {
+	t.Helper()
+	for i, v := range values {
+		msg := kafka.Message{
+			Topic:   topic,
+			Offset:  int64(i),
+			Value:   []byte(v),
+			Headers: []kafka.Header{{Key: "content-type", Value: []byte("application/json")}, {Key: HeaderError, Value: []byte("old")}},
+		}
+		if err := w.Send(context.Background(), msg, errors.New("invalid votesCount")); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+}
+
+// replayedValues возвращает значения сообщений, вернувшихся в исходный топик
+func replayedValues(t *testing.T, b broker.Broker) []string {
+	t.Helper()
+	var out []string
+	err := b.Replay(context.Background(), topic, func(msg kafka.Message) {
+		out = append(out, string(msg.Value))
+	})
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	return out
+}
+
+func TestSendKeepsMessageAndAddsHeaders(t *testing.T) {
+	b := broker.NewMemory()
+	defer b.Close()
+	w := newWriter(t, b)
+	sendFailed(t, w, "a")
+
+	var dead []kafka.Message
+	b.Replay(context.Background(), topic+".dlq", func(msg kafka.Message) { dead = append(dead, msg) })
+	if len(dead) != 1 || string(dead[0].Value) != "a" {
+		t.Fatalf("dead-letter topic = %v, want one message a", dead)
+	}
+
+	headers := map[string][]string{}
+	for _, h := range dead[0].Headers {
+		headers[h.Key] = append(headers[h.Key], string(h.Value))
+	}
+	if got := headers[HeaderError]; len(got) != 1 || got[0] != "invalid votesCount" {
+		t.Errorf("%s = %v, want only the new error", HeaderError, got)
+	}
+	if headers[HeaderOriginalTopic][0] != topic || headers[HeaderOriginalOffset][0] != "0" || len(headers["content-type"]) != 1 {
+		t.Errorf("headers = %v", headers)
+	}
+}
+
+func TestReplayUpToHighWaterMark(t *testing.T) {
+	b := broker.NewMemory()
+	defer b.Close()
+	w := newWriter(t, b)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if n, err := w.Replay(ctx, topic, 0); err != nil || n != 0 {
+		t.Fatalf("Replay() of an empty DLQ = %d, %v, want 0", n, err)
+	}
+
+	sendFailed(t, w, "1", "2", "3")
+	if n, err := w.Replay(ctx, topic, 2); err != nil || n != 2 {
+		t.Fatalf("Replay(limit 2) = %d, %v, want 2", n, err)
+	}
+	if n, err := w.Replay(ctx, topic, 0); err != nil || n != 1 {
+		t.Fatalf("Replay() of the rest = %d, %v, want 1", n, err)
+	}
+	if got := replayedValues(t, b); strings.Join(got, ",") != "1,2,3" {
+		t.Errorf("replayed = %v, want [1 2 3]", got)
+	}
+
+	// Уже повторенные сообщения второй раз не отправляются, новые - отправляются
+	sendFailed(t, w, "4")
+	if n, err := w.Replay(ctx, topic, 0); err != nil || n != 1 {
+		t.Fatalf("Replay() after a new failure = %d, %v, want 1", n, err)
+	}
+	if got := replayedValues(t, b); strings.Join(got, ",") != "1,2,3,4" {
+		t.Errorf("replayed = %v, want [1 2 3 4]", got)
+	}
+
+	var replayed kafka.Message
+	b.Replay(context.Background(), topic, func(msg kafka.Message) { replayed = msg })
+	for _, h := range replayed.Headers {
+		if strings.HasPrefix(h.Key, headerPrefix) && h.Key != HeaderReplayedAt {
+			t.Errorf("replayed message kept header %s", h.Key)
+		}
+	}
+}
+
+func TestReplayDisabled(t *testing.T) {
+	var w *Writer
+	if _, err := w.Replay(context.Background(), topic, 0); err == nil {
+		t.Error("Replay() on disabled DLQ error = nil, want error")
+	}
+	if err := w.Send(context.Background(), kafka.Message{Topic: topic}, nil); err != nil {
+		t.Errorf("Send() on disabled DLQ error = %v, want nil", err)
+	}
+}
@@ -3,275 +3,288 @@ package producer
 import (
 	"apiGateway/internal/config"
 	"apiGateway/internal/dto" // Убедитесь, что dto.UserIdReq определен здесь
+	"apiGateway/internal/kafka/broker"
+	"apiGateway/internal/kafka/codec"
 	"context"
-	"encoding/json"
-	"fmt"  // Для использования fmt.Errorf
-	"time" // Для BatchTimeout
+	"fmt" // Для использования fmt.Errorf
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 	"log/slog" // Используем slog
 )
 
-// Producer обертка для Segmentio Kafka Writer.
+// Producer упаковывает события шлюза в конверты и отправляет их через брокер.
 type Producer struct {
-	writer *kafka.Writer
-	log    *slog.Logger // Добавляем логгер
+	publisher broker.Publisher
+	cfg       config.Kafka // Имена топиков берутся из cfg.Topics
+	codec     codec.Codec  // Кодек конверта сообщений (config.Kafka.Encoding)
+	log       *slog.Logger // Добавляем логгер
+
+	// onDelivery, если задан, получает результат доставки каждого сообщения.
+	// Вызывается из горутин writer-а и не должен блокироваться
+	onDelivery func(DeliveryReport)
+
+	delivered, failed atomic.Int64
+	errMu             sync.Mutex
+	lastErr           string
+	lastErrAt         time.Time
+	closeOnce         sync.Once
+}
+
+// DeliveryReport - результат доставки одного сообщения
+type DeliveryReport struct {
+	Topic         string
+	EventType     string
+	Key           string
+	CorrelationID string
+	Partition     int
+	Offset        int64
+	Err           error // nil, если брокер подтвердил запись
+}
+
+// DeliveryMetrics - счетчики доставки сообщений
+type DeliveryMetrics struct {
+	Mode        string     `json:"mode"`
+	Delivered   int64      `json:"delivered"`
+	Failed      int64      `json:"failed"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
 }
 
 // NewProducer создает и возвращает новый экземпляр Producer.
-// Теперь принимает slog.Logger и использует более полную конфигурацию Kafka.
-func NewProducer(cfg config.Kafka, log *slog.Logger) (*Producer, error) {
-	if len(cfg.Brokers) == 0 {
-		return nil, fmt.Errorf("kafka brokers not provided in configuration")
+// Режим отправки, пачки, подтверждения и сжатие берутся из cfg.Producer.
+// onDelivery (может быть nil) получает результат доставки каждого сообщения: в режиме async
+// только так вызывающая сторона узнает, что сообщение не дошло до брокера.
+func NewProducer(cfg config.Kafka, b broker.Broker, log *slog.Logger, onDelivery func(DeliveryReport)) (*Producer, error) {
+	messageCodec, err := codec.New(cfg.Encoding)
+	if err != nil {
+		return nil, err
 	}
 
-	writer := kafka.Writer{
-		Addr:        kafka.TCP(cfg.Brokers...), // Использование varargs для нескольких брокеров
-		Balancer:    &kafka.LeastBytes{},       // Балансировщик
-		Logger:      kafka.LoggerFunc(func(msg string, args ...interface{}) { log.Debug(msg, args...) }),
-		ErrorLogger: kafka.LoggerFunc(func(msg string, args ...interface{}) { log.Error(msg, args...) }),
+	opts, err := broker.ProducerOptions(cfg.Producer)
+	if err != nil {
+		return nil, err
 	}
 
 	p := &Producer{
-		writer: &writer,
-		log:    log,
+		cfg:        cfg,
+		codec:      messageCodec,
+		log:        log,
+		onDelivery: onDelivery,
 	}
+	opts.Completion = p.completion
 
-	log.Info("Kafka producer initialized successfully", slog.Any("brokers", cfg.Brokers))
+	if p.publisher, err = b.NewPublisher(opts); err != nil {
+		return nil, err
+	}
+
+	log.Info("Kafka producer initialized successfully",
+		slog.String("driver", cfg.Driver),
+		slog.Any("brokers", cfg.Brokers),
+		slog.String("encoding", messageCodec.Name()),
+		slog.String("mode", p.mode()),
+		slog.String("required_acks", cfg.Producer.RequiredAcks),
+		slog.String("compression", cfg.Producer.Compression))
 	return p, nil
 }
 
-// UserRegistrationProduce отправляет userID в топик "user-registration".
-// Возвращает ошибку, чтобы вызывающая сторона могла ее обработать.
-func (p *Producer) UserRegistrationProduce(ctx context.Context, userID string) error {
-	user := dto.UserIdReq{
-		UserID: userID,
+// produce упаковывает payload в версионированный конверт, кодирует его и отправляет в topic.
+// Пустой key отдает выбор партиции балансировщику.
+// В режиме async возвращает ошибку только если сообщение не удалось поставить в очередь:
+// об ошибках доставки сообщают логи, Metrics и onDelivery.
+func (p *Producer) produce(ctx context.Context, topic, eventType, key string, payload any) error {
+	env, err := codec.NewEnvelope(ctx, eventType, payload)
+	if err != nil {
+		p.log.Error("Failed to build Kafka envelope", slog.String("topic", topic), slog.String("event_type", eventType), slog.Any("error", err))
+		return err
 	}
 
-	value, err := json.Marshal(user)
+	value, err := p.codec.Encode(env)
 	if err != nil {
-		p.log.Error("Failed to marshal UserIdReq", slog.String("user_id", userID), slog.Any("error", err))
-		return fmt.Errorf("failed to marshal user ID: %w", err)
+		p.log.Error("Failed to encode Kafka envelope", slog.String("topic", topic), slog.String("event_type", eventType), slog.Any("error", err))
+		return fmt.Errorf("failed to encode %s message: %w", eventType, err)
 	}
 
 	message := kafka.Message{
-		Topic: "user-registrations",
-		Key:   []byte(userID), // Используем userID как ключ, чтобы все сообщения от одного пользователя шли в одну партицию
+		Topic: topic,
 		Value: value,
 		Headers: []kafka.Header{
-			{Key: "event_type", Value: []byte("UserRegistered")},
-			{Key: "timestamp", Value: []byte(time.Now().Format(time.RFC3339))},
+			{Key: "event_type", Value: []byte(eventType)},
+			{Key: "timestamp", Value: []byte(env.ProducedAt.Format(time.RFC3339))},
+			{Key: "source_service", Value: []byte("api-gateway")},
+			{Key: codec.HeaderContentType, Value: []byte(p.codec.ContentType())},
+			{Key: "schema_version", Value: []byte(strconv.Itoa(env.SchemaVersion))},
+			{Key: "correlation_id", Value: []byte(env.CorrelationID)},
 		},
 	}
-
-	err = p.writer.WriteMessages(ctx, message)
-	if err != nil {
-		p.log.Error("Failed to write message to Kafka", slog.String("topic", message.Topic), slog.String("user_id", userID), slog.Any("error", err))
-		return fmt.Errorf("error writing to kafka topic %s: %w", message.Topic, err)
+	if key != "" {
+		message.Key = []byte(key)
 	}
 
-	p.log.Info("Message sent successfully to Kafka", slog.String("topic", message.Topic), slog.String("user_id", userID))
+	// Результат доставки логируется в completion
+	if err := p.publisher.Publish(ctx, message); err != nil {
+		return fmt.Errorf("error writing to kafka topic %s: %w", topic, err)
+	}
 	return nil
-} // РАБОТАЕТ
+}
 
-// VoteHistoryRequestProduce отправляет userID в топик "vote-history-request".
-// Возвращает ошибку, чтобы вызывающая сторона могла ее обработать.
-func (p *Producer) VoteHistoryRequestProduce(ctx context.Context, userID string) error {
-	user := dto.UserIdReq{
-		UserID: userID,
+// completion получает от брокера результат доставки пачки сообщений
+func (p *Producer) completion(msgs []kafka.Message, err error) {
+	for _, msg := range msgs {
+		report := DeliveryReport{
+			Topic:         msg.Topic,
+			EventType:     header(msg, "event_type"),
+			Key:           string(msg.Key),
+			CorrelationID: header(msg, "correlation_id"),
+			Partition:     msg.Partition,
+			Offset:        msg.Offset,
+			Err:           err,
+		}
+
+		if err != nil {
+			p.failed.Add(1)
+			p.errMu.Lock()
+			p.lastErr, p.lastErrAt = err.Error(), time.Now().UTC()
+			p.errMu.Unlock()
+			p.log.Error("Failed to write message to Kafka",
+				slog.String("topic", report.Topic),
+				slog.String("event_type", report.EventType),
+				slog.String("key", report.Key),
+				slog.String("correlation_id", report.CorrelationID),
+				slog.Any("error", err))
+		} else {
+			p.delivered.Add(1)
+			p.log.Info("Message sent successfully to Kafka",
+				slog.String("topic", report.Topic),
+				slog.String("event_type", report.EventType),
+				slog.String("key", report.Key),
+				slog.String("correlation_id", report.CorrelationID))
+		}
+
+		if p.onDelivery != nil {
+			p.onDelivery(report)
+		}
 	}
+}
 
-	value, err := json.Marshal(user)
-	if err != nil {
-		p.log.Error("Failed to marshal UserIdReq", slog.String("user_id", userID), slog.Any("error", err))
-		return fmt.Errorf("failed to marshal user ID: %w", err)
+// Metrics возвращает счетчики доставки
+func (p *Producer) Metrics() DeliveryMetrics {
+	m := DeliveryMetrics{
+		Mode:      p.mode(),
+		Delivered: p.delivered.Load(),
+		Failed:    p.failed.Load(),
+	}
+	p.errMu.Lock()
+	if p.lastErr != "" {
+		at := p.lastErrAt
+		m.LastError, m.LastErrorAt = p.lastErr, &at
 	}
+	p.errMu.Unlock()
+	return m
+}
 
-	message := kafka.Message{
-		Topic: "vote-history-request",
-		Key:   []byte(userID), // Используем userID как ключ, чтобы все сообщения от одного пользователя шли в одну партицию
-		Value: value,
-		Headers: []kafka.Header{
-			{Key: "event_type", Value: []byte("VoteHistoryRequested")},
-			{Key: "timestamp", Value: []byte(time.Now().Format(time.RFC3339))},
-		},
+func (p *Producer) mode() string {
+	if p.cfg.Producer.Mode == "" {
+		return broker.ModeSync
 	}
+	return p.cfg.Producer.Mode
+}
 
-	err = p.writer.WriteMessages(ctx, message)
-	if err != nil {
-		p.log.Error("Failed to write message to Kafka", slog.String("topic", message.Topic), slog.String("user_id", userID), slog.Any("error", err))
-		return fmt.Errorf("error writing to kafka topic %s: %w", message.Topic, err)
+func header(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
 	}
+	return ""
+}
 
-	p.log.Info("Message sent successfully to Kafka", slog.String("topic", message.Topic), slog.String("user_id", userID))
-	return nil
+// UserRegistrationProduce отправляет userID в топик "user-registration".
+// Возвращает ошибку, чтобы вызывающая сторона могла ее обработать.
+func (p *Producer) UserRegistrationProduce(ctx context.Context, userID string) error {
+	// Используем userID как ключ, чтобы все сообщения от одного пользователя шли в одну партицию
+	return p.produce(ctx, p.cfg.TopicName(config.TopicUserRegistrations), "UserRegistered", userID, dto.UserIdReq{UserID: userID})
+} // РАБОТАЕТ
+
+// VoteHistoryRequestProduce отправляет userID в топик "vote-history-request".
+// Возвращает ошибку, чтобы вызывающая сторона могла ее обработать.
+func (p *Producer) VoteHistoryRequestProduce(ctx context.Context, userID string) error {
+	return p.produce(ctx, p.cfg.TopicName(config.TopicVoteHistoryRequest), "VoteHistoryRequested", userID, dto.UserIdReq{UserID: userID})
 } // РАБОТАЕТ
 
 // TriggerAllVotingsProduce отправляет пустое сообщение в топик "trigger-all-votings".
 // Это служит триггером для других сервисов обновить информацию обо всех голосованиях.
 func (p *Producer) TriggerAllVotingsProduce(ctx context.Context) error {
-	// Пустой JSON как значение сообщения
-	value := []byte("{}")
-
-	message := kafka.Message{
-		Topic: "trigger-all-votings",
-		Key:   nil,
-		Value: value,
-		Headers: []kafka.Header{
-			{Key: "event_type", Value: []byte("AllVotingsTriggered")},
-			{Key: "timestamp", Value: []byte(time.Now().Format(time.RFC3339))},
-		},
-	}
-
-	err := p.writer.WriteMessages(ctx, message)
-	if err != nil {
-		p.log.Error("Failed to write trigger all votings message to Kafka",
-			slog.String("topic", message.Topic),
-			slog.Any("error", err))
-		return fmt.Errorf("error writing trigger all votings event to kafka topic %s: %w", message.Topic, err)
-	}
-
-	p.log.Info("Trigger all votings message sent successfully to Kafka", slog.String("topic", message.Topic))
-	return nil
+	return p.produce(ctx, p.cfg.TopicName(config.TopicTriggerAllVotings), "AllVotingsTriggered", "", struct{}{})
 } // РАБОТАЕТ
 
 // VotingRequestProduce отправляет votingID в топик "voting-request".
 // Возвращает ошибку, чтобы вызывающая сторона могла ее обработать.
 func (p *Producer) VotingRequestProduce(ctx context.Context, VotingID string) error {
-	voting := dto.VotingRequest{
-		VotingID: VotingID,
-	}
-
-	value, err := json.Marshal(voting)
-	if err != nil {
-		p.log.Error("Failed to marshal VotingIdReq", slog.String("voting_id", VotingID), slog.Any("error", err))
-		return fmt.Errorf("failed to marshal voting ID: %w", err)
-	}
-
-	message := kafka.Message{
-		Topic: "voting-request",
-		Key:   []byte(VotingID), // Используем VotingID как ключ, чтобы все сообщения от одного пользователя шли в одну партицию
-		Value: value,
-		Headers: []kafka.Header{
-			{Key: "event_type", Value: []byte("VotingDetailsRequested")},
-			{Key: "timestamp", Value: []byte(time.Now().Format(time.RFC3339))},
-		},
-	}
-
-	err = p.writer.WriteMessages(ctx, message)
-	if err != nil {
-		p.log.Error("Failed to write message to Kafka", slog.String("topic", message.Topic), slog.String("user_id", VotingID), slog.Any("error", err))
-		return fmt.Errorf("error writing to kafka topic %s: %w", message.Topic, err)
-	}
-
-	p.log.Info("Message sent successfully to Kafka", slog.String("topic", message.Topic), slog.String("user_id", VotingID))
-	return nil
+	return p.produce(ctx, p.cfg.TopicName(config.TopicVotingRequest), "VotingDetailsRequested", VotingID, dto.VotingRequest{VotingID: VotingID})
 } // РАБОТАЕТ
 
 // VotingCreateProduce отправляет сообщение о создании нового голосования в Kafka.
 // Она принимает контекст и структуру dto.VotingReq.
 func (p *Producer) VotingCreateProduce(ctx context.Context, votingData dto.VotingReq) error {
-	// Сериализуем структуру VotingReq в JSON.
-	// JSON-тэги (`json:"..."`) гарантируют правильные имена полей в JSON-выводе.
-	value, err := json.Marshal(votingData)
-	if err != nil {
-		p.log.Error("Failed to marshal VotingReq", slog.String("voting_id", votingData.ID), slog.Any("error", err))
-		return fmt.Errorf("failed to marshal voting data: %w", err)
-	}
-
 	// Используем ID голосования в качестве ключа сообщения.
 	// Это гарантирует, что все события, относящиеся к одному голосованию,
 	// будут попадать в одну и ту же партицию, сохраняя порядок.
-	key := []byte(votingData.ID)
-
-	// Добавляем заголовки для лучшей идентификации события.
-	headers := []kafka.Header{
-		{Key: "event_type", Value: []byte("VotingCreated")},
-		{Key: "timestamp", Value: []byte(time.Now().Format(time.RFC3339))},
-	}
-
-	message := kafka.Message{
-		Topic:   "voting-create",
-		Key:     key,
-		Value:   value,
-		Headers: headers,
-	}
-
-	// Отправляем сообщение в Kafka.
-	err = p.writer.WriteMessages(ctx, message)
-	if err != nil {
-		p.log.Error("Failed to write voting creation message to Kafka",
-			slog.String("topic", message.Topic),
-			slog.String("voting_id", votingData.ID),
-			slog.Any("error", err))
-		return fmt.Errorf("error writing voting creation event to kafka topic %s: %w", message.Topic, err)
-	}
-
-	p.log.Info("Voting creation message sent successfully to Kafka",
-		slog.String("topic", message.Topic),
-		slog.String("voting_id", votingData.ID))
-	return nil
+	return p.produce(ctx, p.cfg.TopicName(config.TopicVotingCreate), "VotingCreated", votingData.ID, votingData)
 } // РАБОТАЕТ
 
 // VoteCastProduce отправляет сообщение о голосовании пользователя в Kafka.
 // Она принимает контекст и структуру dto.VoteCast.
 func (p *Producer) VoteCastProduce(ctx context.Context, voteData dto.VoteCast) error {
-	// Сериализуем структуру VoteCast в JSON.
-	value, err := json.Marshal(voteData)
-	if err != nil {
-		p.log.Error("Failed to marshal VoteCast",
-			slog.String("voting_id", voteData.VotingID),
-			slog.String("voter_id", voteData.VoterID),
-			slog.String("option_id", voteData.OptionID),
-			slog.Any("error", err))
-		return fmt.Errorf("failed to marshal vote cast data: %w", err)
-	}
-
 	// Используем VotingID + VoterID в качестве ключа сообщения для обеспечения порядка
-	// событий от одного пользователя в рамках одного голосования, или просто VotingID
-	// если порядок внутри голосования не так критичен, но все события голосования
-	// должны быть вместе.
-	// Для уникальности и распределения по партициям, можно использовать конкатенацию.
-	key := []byte(fmt.Sprintf("%s-%s", voteData.VotingID, voteData.VoterID))
-
-	// Добавляем заголовки для лучшей идентификации события.
-	headers := []kafka.Header{
-		{Key: "event_type", Value: []byte("VoteCast")},
-		{Key: "timestamp", Value: []byte(time.Now().Format(time.RFC3339))},
-		{Key: "source_service", Value: []byte("api-gateway")},
-	}
+	// событий от одного пользователя в рамках одного голосования.
+	key := fmt.Sprintf("%s-%s", voteData.VotingID, voteData.VoterID)
+	return p.produce(ctx, p.cfg.TopicName(config.TopicVoteCast), "VoteCast", key, voteData)
+} // НЕ ТЕСТИЛИ
 
-	message := kafka.Message{
-		Topic:   "vote-cast",
-		Key:     key,
-		Value:   value,
-		Headers: headers,
-	}
+// VoteChangedProduce отправляет событие об изменении или отзыве голоса в топик "vote-changed".
+func (p *Producer) VoteChangedProduce(ctx context.Context, changeData dto.VoteChanged) error {
+	// Ключ совпадает с vote-cast, чтобы изменения шли в ту же партицию, что и исходный голос
+	key := fmt.Sprintf("%s-%s", changeData.VotingID, changeData.VoterID)
+	return p.produce(ctx, p.cfg.TopicName(config.TopicVoteChanged), "VoteChanged", key, changeData)
+}
 
-	// Отправляем сообщение в Kafka.
-	err = p.writer.WriteMessages(ctx, message)
-	if err != nil {
-		p.log.Error("Failed to write vote cast message to Kafka",
-			slog.String("topic", message.Topic),
-			slog.String("voting_id", voteData.VotingID),
-			slog.String("voter_id", voteData.VoterID),
-			slog.Any("error", err))
-		return fmt.Errorf("error writing vote cast event to kafka topic %s: %w", message.Topic, err)
-	}
+// DelegationProduce отправляет событие о делегировании голоса в топик "vote-delegation",
+// чтобы Java-сервис сохранил его.
+func (p *Producer) DelegationProduce(ctx context.Context, delegationData dto.Delegation) error {
+	// Ключ по делегатору: делегирование и его отзыв должны обрабатываться по порядку
+	return p.produce(ctx, p.cfg.TopicName(config.TopicVoteDelegation), "VoteDelegation", delegationData.DelegatorID, delegationData)
+}
 
-	p.log.Info("Vote cast message sent successfully to Kafka",
-		slog.String("topic", message.Topic),
-		slog.String("voting_id", voteData.VotingID),
-		slog.String("voter_id", voteData.VoterID))
-	return nil
-} // НЕ ТЕСТИЛИ
+// VoteCommitProduce отправляет хеш скрытого голоса в топик "vote-commit".
+// Сам выбор уходит в vote-cast только после раскрытия.
+func (p *Producer) VoteCommitProduce(ctx context.Context, commitData dto.VoteCommit) error {
+	key := fmt.Sprintf("%s-%s", commitData.VotingID, commitData.VoterID)
+	return p.produce(ctx, p.cfg.TopicName(config.TopicVoteCommit), "VoteCommitted", key, commitData)
+}
+
+// VotingStatusChangedProduce отправляет событие о смене статуса голосования в топик "voting-status-changed".
+// Для завершенных голосований событие содержит итоговых победителей.
+func (p *Producer) VotingStatusChangedProduce(ctx context.Context, statusData dto.VotingStatusChanged) error {
+	return p.produce(ctx, p.cfg.TopicName(config.TopicVotingStatusChanged), "VotingStatusChanged", statusData.VotingID, statusData)
+}
 
-// Close закрывает продюсер и очищает ресурсы.
+// Close дожидается доставки всех сообщений, накопленных в пачках, и закрывает продюсер.
+// Безопасно вызывать на nil и повторно.
 func (p *Producer) Close() {
-	p.log.Info("closing kafka producer")
-	if err := p.writer.Close(); err != nil {
-		p.log.Error("failed to close Kafka writer", slog.Any("error", err))
-	} else {
-		p.log.Info("kafka producer closed.")
+	if p == nil {
+		return
 	}
+	p.closeOnce.Do(func() {
+		p.log.Info("closing kafka producer, flushing pending messages")
+		if err := p.publisher.Close(); err != nil {
+			p.log.Error("failed to close Kafka writer", slog.Any("error", err))
+			return
+		}
+		m := p.Metrics()
+		p.log.Info("kafka producer closed.", slog.Int64("delivered", m.Delivered), slog.Int64("failed", m.Failed))
+	})
 }
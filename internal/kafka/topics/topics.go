@@ -0,0 +1,30 @@
+package topics
+
+import (
+	"apiGateway/internal/config"
+	"apiGateway/internal/kafka/broker"
+	"context"
+	"github.com/segmentio/kafka-go"
+	"log/slog"
+)
+
+// Ensure проверяет, что все топики шлюза есть в кластере. Недостающие создаются,
+// если это разрешено kafka.create_topics, иначе возвращается ошибка со списком недостающих.
+func Ensure(ctx context.Context, cfg config.Kafka, b broker.Broker, log *slog.Logger) error {
+	topics := make([]kafka.TopicConfig, 0, len(config.KnownTopics))
+	for _, key := range config.KnownTopics {
+		t := cfg.Topic(key)
+		topics = append(topics, kafka.TopicConfig{
+			Topic:             t.Name,
+			NumPartitions:     t.Partitions,
+			ReplicationFactor: t.ReplicationFactor,
+		})
+	}
+
+	if err := b.EnsureTopics(ctx, topics, cfg.CreateTopics); err != nil {
+		return err
+	}
+
+	log.Info("All Kafka topics exist", slog.Int("count", len(topics)))
+	return nil
+}
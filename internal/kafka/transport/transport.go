@@ -0,0 +1,135 @@
+package transport
+
+import (
+	"apiGateway/internal/config"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"os"
+	"strings"
+	"time"
+)
+
+const dialTimeout = 10 * time.Second
+
+// Dialer возвращает dialer для ридеров и служебных соединений с настройками TLS и SASL из конфига
+func Dialer(cfg config.Kafka) (*kafka.Dialer, error) {
+	tlsConfig, mechanism, err := security(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Dialer{
+		Timeout:       dialTimeout,
+		DualStack:     true,
+		TLS:           tlsConfig,
+		SASLMechanism: mechanism,
+	}, nil
+}
+
+// Transport возвращает транспорт для kafka.Writer с теми же настройками TLS и SASL, что и Dialer
+func Transport(cfg config.Kafka) (*kafka.Transport, error) {
+	tlsConfig, mechanism, err := security(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Transport{
+		DialTimeout: dialTimeout,
+		TLS:         tlsConfig,
+		SASL:        mechanism,
+	}, nil
+}
+
+func security(cfg config.Kafka) (*tls.Config, sasl.Mechanism, error) {
+	tlsConfig, err := tlsConfig(cfg.TLS)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid kafka tls config: %w", err)
+	}
+	mechanism, err := saslMechanism(cfg.SASL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid kafka sasl config: %w", err)
+	}
+	return tlsConfig, mechanism, nil
+}
+
+func tlsConfig(cfg config.KafkaTLS) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	tc := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", cfg.CAFile)
+		}
+		tc.RootCAs = pool
+	}
+
+	// Клиентский сертификат нужен только для mTLS
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, fmt.Errorf("both cert_file and key_file are required for client certificate")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+
+	return tc, nil
+}
+
+func saslMechanism(cfg config.KafkaSASL) (sasl.Mechanism, error) {
+	if cfg.Mechanism == "" {
+		return nil, nil
+	}
+
+	username, err := secret(cfg.Username, cfg.UsernameFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read username: %w", err)
+	}
+	password, err := secret(cfg.Password, cfg.PasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("username and password are required for %s", cfg.Mechanism)
+	}
+
+	switch strings.ToLower(cfg.Mechanism) {
+	case "plain":
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, username, password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, username, password)
+	default:
+		return nil, fmt.Errorf("unsupported mechanism %s", cfg.Mechanism)
+	}
+}
+
+// secret возвращает значение из конфига/окружения, а если задан файл (например, docker/k8s secret) - его содержимое
+func secret(value, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
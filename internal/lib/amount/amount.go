@@ -0,0 +1,227 @@
+package amount
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Единицы и количество знаков после запятой относительно wei
+const (
+	Wei   = 0
+	Gwei  = 9
+	Ether = 18
+)
+
+var ErrNegative = errors.New("amount must not be negative")
+
+// units - суффиксы единиц; более длинные идут раньше, чтобы "gwei" не разобрался как "wei"
+var units = []struct {
+	suffix   string
+	decimals int
+}{
+	{"ether", Ether},
+	{"gwei", Gwei},
+	{"eth", Ether},
+	{"wei", Wei},
+}
+
+// Amount - точная сумма в wei без потерь на float64.
+// В JSON принимается строка ("0.123456789012345678", "1.5 gwei", "100 wei", "0x16345785d8a0000" - сырые wei),
+// число (в ETH) или объект {"wei": "..."}; отдается объектом View.
+type Amount struct {
+	wei *big.Int
+}
+
+// View - представление суммы в ответах API: точное значение в wei и то же значение в ETH
+type View struct {
+	Wei string `json:"wei"`
+	ETH string `json:"eth"`
+}
+
+// FromWei создает сумму из значения в wei
+func FromWei(wei *big.Int) Amount {
+	if wei == nil {
+		return Amount{}
+	}
+	return Amount{wei: new(big.Int).Set(wei)}
+}
+
+// Parse разбирает десятичную строку с необязательной единицей (wei, gwei, eth/ether; по умолчанию ETH)
+// или шестнадцатеричное число wei с префиксом 0x
+func Parse(s string) (Amount, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return Amount{}, fmt.Errorf("amount is empty")
+	}
+
+	if hex, ok := strings.CutPrefix(s, "0x"); ok {
+		// big.Int.SetString принимает знак, а сумма в hex - только цифры
+		if strings.HasPrefix(hex, "-") {
+			return Amount{}, ErrNegative
+		}
+		wei, ok := new(big.Int).SetString(hex, 16)
+		if !ok || strings.HasPrefix(hex, "+") {
+			return Amount{}, fmt.Errorf("invalid hex wei amount %q", s)
+		}
+		return Amount{wei: wei}, nil
+	}
+
+	decimals := Ether
+	for _, u := range units {
+		if number, ok := strings.CutSuffix(s, u.suffix); ok {
+			s, decimals = strings.TrimSpace(number), u.decimals
+			break
+		}
+	}
+
+	wei, err := parseDecimal(s, decimals)
+	if err != nil {
+		return Amount{}, err
+	}
+	return Amount{wei: wei}, nil
+}
+
+// parseDecimal переводит десятичную запись в целое число наименьших единиц.
+// Экспоненциальная запись не принимается, лишние знаки после запятой - ошибка, а не округление
+func parseDecimal(s string, decimals int) (*big.Int, error) {
+	if strings.HasPrefix(s, "-") {
+		return nil, ErrNegative
+	}
+	s = strings.TrimPrefix(s, "+")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	for _, part := range []string{whole, frac} {
+		if strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+			return nil, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > decimals {
+		return nil, fmt.Errorf("amount %q has more than %d decimal places", s, decimals)
+	}
+
+	digits := strings.TrimLeft(whole+frac+strings.Repeat("0", decimals-len(frac)), "0")
+	if digits == "" {
+		return new(big.Int), nil
+	}
+	wei, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return wei, nil
+}
+
+// Wei возвращает копию суммы в wei
+func (a Amount) Wei() *big.Int {
+	if a.wei == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(a.wei)
+}
+
+// Sign возвращает -1, 0 или 1
+func (a Amount) Sign() int {
+	if a.wei == nil {
+		return 0
+	}
+	return a.wei.Sign()
+}
+
+// Cmp сравнивает суммы как big.Int.Cmp
+func (a Amount) Cmp(b Amount) int {
+	return a.Wei().Cmp(b.Wei())
+}
+
+// Add возвращает a + b
+func (a Amount) Add(b Amount) Amount {
+	return Amount{wei: new(big.Int).Add(a.Wei(), b.Wei())}
+}
+
+// Ether возвращает сумму в ETH без потери точности и без лишних нулей ("0.1", "12", "0.000000001")
+func (a Amount) Ether() string {
+	return Format(a.Wei(), Ether)
+}
+
+// String - сумма в ETH с единицей, для логов и сообщений об ошибках
+func (a Amount) String() string {
+	return a.Ether() + " ETH"
+}
+
+// View возвращает представление для ответов API
+func (a Amount) View() View {
+	return View{Wei: a.Wei().String(), ETH: a.Ether()}
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.View())
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0 || bytes.Equal(data, []byte("null")):
+		*a = Amount{}
+		return nil
+	case data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		parsed, err := Parse(s)
+		if err != nil {
+			return err
+		}
+		*a = parsed
+		return nil
+	case data[0] == '{':
+		var v struct {
+			Wei string `json:"wei"`
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		wei, err := parseDecimal(v.Wei, Wei)
+		if err != nil {
+			return err
+		}
+		*a = Amount{wei: wei}
+		return nil
+	default:
+		// Число в JSON читается из исходного текста, а не через float64
+		parsed, err := Parse(string(data))
+		if err != nil {
+			return err
+		}
+		*a = parsed
+		return nil
+	}
+}
+
+// Format выводит целое число наименьших единиц как десятичную дробь с decimals знаками.
+// Подходит и для токенов ERC-20 с их decimals
+func Format(v *big.Int, decimals int) string {
+	sign := ""
+	if v.Sign() < 0 {
+		sign, v = "-", new(big.Int).Neg(v)
+	}
+	s := v.String()
+	if decimals == 0 {
+		return sign + s
+	}
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-decimals], strings.TrimRight(s[len(s)-decimals:], "0")
+	if frac == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + frac
+}
@@ -0,0 +1,170 @@
+package amount
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in  string
+		wei string
+	}{
+		{"1", "1000000000000000000"},
+		{"0.1", "100000000000000000"},
+		{"+2.5", "2500000000000000000"},
+		{".5", "500000000000000000"},
+		{"5.", "5000000000000000000"},
+		{"0", "0"},
+		{"000.000", "0"},
+		{"0.000000000000000001", "1"},
+		{"0.1000000000000000000000", "100000000000000000"}, // Нули в конце не считаются лишними знаками
+		{"1.5 gwei", "1500000000"},
+		{"1.5gwei", "1500000000"},
+		{"100 wei", "100"},
+		{"2 ETH", "2000000000000000000"},
+		{"2 ether", "2000000000000000000"},
+		{"  3 Eth  ", "3000000000000000000"},
+		{"0x16345785d8a0000", "100000000000000000"},
+		{"0XFF", "255"},
+		{"0x0", "0"},
+		{"123456789012345678901234567890.123456789012345678", "123456789012345678901234567890123456789012345678"},
+		{"0x" + strings.Repeat("f", 64), new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)).String()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			a, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.in, err)
+			}
+			if got := a.Wei().String(); got != tt.wei {
+				t.Errorf("Parse(%q) = %s wei, want %s", tt.in, got, tt.wei)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want error // nil - достаточно любой ошибки
+	}{
+		{"", nil},
+		{"   ", nil},
+		{".", nil},
+		{"eth", nil},
+		{"abc", nil},
+		{"1,5", nil},
+		{"1.2.3", nil},
+		{"1e18", nil},
+		{"1 btc", nil},
+		{"0.0000000000000000001", nil}, // 19 знаков для ETH
+		{"1.5 wei", nil},               // wei не делится
+		{"1.0000000001 gwei", nil},     // 10 знаков для gwei
+		{"0x", nil},
+		{"0xzz", nil},
+		{"0x+1", nil},
+		{"0x-1", ErrNegative},
+		{"-1", ErrNegative},
+		{"-0.5 gwei", ErrNegative},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			a, err := Parse(tt.in)
+			if err == nil {
+				t.Fatalf("Parse(%q) = %s, want error", tt.in, a.Wei())
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.in, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		wei      string
+		decimals int
+		want     string
+	}{
+		{"0", Ether, "0"},
+		{"1", Ether, "0.000000000000000001"},
+		{"100000000000000000", Ether, "0.1"},
+		{"1000000000000000000", Ether, "1"},
+		{"12345000000000000000", Ether, "12.345"},
+		{"-1500000000000000000", Ether, "-1.5"},
+		{"1500000000", Gwei, "1.5"},
+		{"42", Wei, "42"},
+		{"1234567", 6, "1.234567"},
+		{"123456789012345678901234567890123456789012345678", Ether, "123456789012345678901234567890.123456789012345678"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wei, func(t *testing.T) {
+			v, _ := new(big.Int).SetString(tt.wei, 10)
+			if got := Format(v, tt.decimals); got != tt.want {
+				t.Errorf("Format(%s, %d) = %s, want %s", tt.wei, tt.decimals, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatParseRoundTrip(t *testing.T) {
+	for _, wei := range []string{"1", "999999999999999999", "1000000000000000001", "340282366920938463463374607431768211455"} {
+		v, _ := new(big.Int).SetString(wei, 10)
+		a, err := Parse(Format(v, Ether))
+		if err != nil {
+			t.Fatalf("Parse(Format(%s)) error = %v", wei, err)
+		}
+		if a.Wei().Cmp(v) != 0 {
+			t.Errorf("Parse(Format(%s)) = %s", wei, a.Wei())
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		in  string
+		wei string
+	}{
+		{`"0.5"`, "500000000000000000"},
+		{`"1.5 gwei"`, "1500000000"},
+		{`"0x10"`, "16"},
+		{`0.1`, "100000000000000000"},
+		{`0.123456789012345678`, "123456789012345678"}, // Без потерь float64
+		{`{"wei": "42"}`, "42"},
+		{`null`, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var a Amount
+			if err := json.Unmarshal([]byte(tt.in), &a); err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", tt.in, err)
+			}
+			if got := a.Wei().String(); got != tt.wei {
+				t.Errorf("Unmarshal(%s) = %s wei, want %s", tt.in, got, tt.wei)
+			}
+		})
+	}
+
+	for _, in := range []string{`-1`, `1e18`, `{"wei": "1.5"}`, `{"wei": "-1"}`, `true`} {
+		var a Amount
+		if err := json.Unmarshal([]byte(in), &a); err == nil {
+			t.Errorf("Unmarshal(%s) = %s, want error", in, a.Wei())
+		}
+	}
+
+	out, err := json.Marshal(FromWei(big.NewInt(1500000000)))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `{"wei":"1500000000","eth":"0.0000000015"}`; string(out) != want {
+		t.Errorf("Marshal() = %s, want %s", out, want)
+	}
+}
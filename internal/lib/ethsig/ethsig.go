@@ -0,0 +1,94 @@
+package ethsig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpired          = errors.New("signed request expired")
+	ErrSignerMismatch   = errors.New("address does not match request signer")
+	ErrReplayed         = errors.New("signed request already used")
+)
+
+// Recover возвращает адрес, подписавший message через personal_sign (EIP-191).
+// signature - 65 байт в hex; v допускается как 0/1, так и 27/28
+func Recover(message, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("%w: expected %d bytes hex", ErrInvalidSignature, crypto.SignatureLength)
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pub, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// Verify проверяет, что подписанный в signedAt запрос еще действителен и подписан адресом address.
+// Возвращает адрес подписавшего; при несовпадении адресов - ErrSignerMismatch
+func Verify(message, signature string, signedAt time.Time, maxAge time.Duration, address common.Address) (common.Address, error) {
+	if age := time.Since(signedAt); age > maxAge || age < -maxAge {
+		return common.Address{}, fmt.Errorf("%w: signed at %s", ErrExpired, signedAt.UTC().Format(time.RFC3339))
+	}
+
+	signer, err := Recover(message, signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if signer != address {
+		return signer, fmt.Errorf("%w: %s signed by %s",
+			ErrSignerMismatch, strings.ToLower(address.Hex()), strings.ToLower(signer.Hex()))
+	}
+	return signer, nil
+}
+
+// ReplayGuard запоминает уже принятые подписанные запросы, пока их подпись действительна,
+// чтобы перехваченный запрос нельзя было отправить повторно
+type ReplayGuard struct {
+	maxAge time.Duration
+
+	mu   sync.Mutex
+	used map[common.Hash]time.Time // Хеш подписанного текста -> когда подпись истекает
+}
+
+// NewReplayGuard создает ReplayGuard для подписей, действующих maxAge (как в Verify)
+func NewReplayGuard(maxAge time.Duration) *ReplayGuard {
+	return &ReplayGuard{maxAge: maxAge, used: make(map[common.Hash]time.Time)}
+}
+
+// Use отмечает message, подписанный в signedAt, как использованный.
+// Запросы различаются по подписанному тексту, а не по подписи: у одной подписи есть несколько
+// допустимых кодировок. Повторный запрос до истечения подписи возвращает ErrReplayed
+func (g *ReplayGuard) Use(message string, signedAt time.Time) error {
+	now := time.Now()
+	hash := common.BytesToHash(accounts.TextHash([]byte(message)))
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for h, expires := range g.used {
+		if now.After(expires) {
+			delete(g.used, h)
+		}
+	}
+
+	if _, ok := g.used[hash]; ok {
+		return fmt.Errorf("%w: signed at %s", ErrReplayed, signedAt.UTC().Format(time.RFC3339))
+	}
+	g.used[hash] = signedAt.Add(g.maxAge)
+	return nil
+}
@@ -0,0 +1,87 @@
+package ethsig
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// sign подписывает message как personal_sign в кошельке (v = 27/28)
+func sign(t *testing.T, message string) (string, common.Address) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(sig), crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func TestVerify(t *testing.T) {
+	const message = "TrustVote unstake"
+	sig, addr := sign(t, message)
+	now := time.Now()
+
+	if signer, err := Verify(message, sig, now, time.Minute, addr); err != nil || signer != addr {
+		t.Fatalf("Verify() = %s, %v, want %s", signer.Hex(), err, addr.Hex())
+	}
+
+	tests := []struct {
+		name      string
+		message   string
+		signature string
+		signedAt  time.Time
+		address   common.Address
+		want      error
+	}{
+		{"expired", message, sig, now.Add(-2 * time.Minute), addr, ErrExpired},
+		{"signed in the future", message, sig, now.Add(2 * time.Minute), addr, ErrExpired},
+		{"other address", message, sig, now, common.HexToAddress("0x01"), ErrSignerMismatch},
+		{"other message", "TrustVote stake", sig, now, addr, ErrSignerMismatch},
+		{"not hex", message, "signature", now, addr, ErrInvalidSignature},
+		{"short signature", message, sig[:10], now, addr, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(tt.message, tt.signature, tt.signedAt, time.Minute, tt.address); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayGuard(t *testing.T) {
+	g := NewReplayGuard(time.Minute)
+	now := time.Now()
+
+	if err := g.Use("a", now); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if err := g.Use("a", now); !errors.Is(err, ErrReplayed) {
+		t.Errorf("second Use() error = %v, want ErrReplayed", err)
+	}
+	if err := g.Use("b", now); err != nil {
+		t.Errorf("Use() of another message error = %v", err)
+	}
+
+	// Истекшие подписи забываются: Verify их все равно не пропустит
+	if err := g.Use("old", now.Add(-2*time.Minute)); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	g.Use("c", now)
+	if _, ok := g.used[common.BytesToHash(accounts.TextHash([]byte("old")))]; ok {
+		t.Error("expired message was not evicted")
+	}
+	if len(g.used) != 3 {
+		t.Errorf("guard keeps %d messages, want 3", len(g.used))
+	}
+}
@@ -2,6 +2,23 @@ package models
 
 import "time"
 
+// Статусы голосования
+const (
+	StatusUpcoming         = "Upcoming"
+	StatusActive           = "Active"
+	StatusFinished         = "Finished"
+	StatusRejected         = "Rejected"
+	StatusRunoff           = "Runoff"           // Ничья, требуется повторное голосование между лидерами
+	StatusAwaitingDecision = "AwaitingDecision" // Ничья, победителя выбирает создатель
+	StatusReveal           = "Reveal"           // Commit-reveal: прием раскрытий после окончания голосования
+)
+
+// Режимы подачи голосов
+const (
+	BallotModeOpen         = "open"
+	BallotModeCommitReveal = "commit_reveal"
+)
+
 type UserActivity struct {
 	CreatedVotings      []string       `json:"created_votings"`
 	ParticipatedVotings map[string]int `json:"participated_votings"`
@@ -17,6 +34,9 @@ type Voter struct {
 	IsVoted bool   `json:"is_voted"`
 	Choice  int    `json:"choice_index"` // Изменено: храним индекс выбора
 	CanVote bool   `json:"can_vote"`     // Это поле может быть вычислено, но для контракта оставим
+	// Weight - вес голоса с учетом делегирований (1 + число представленных делегаторов)
+	Weight     int64    `json:"weight"`
+	Represents []string `json:"represents,omitempty"` // Делегаторы, чьи голоса учтены в этом голосе
 }
 
 type VoteSession struct {
@@ -32,7 +52,62 @@ type VoteSession struct {
 	Choices         []Choice         `json:"options"` // JSON-тег остался options
 	Voters          map[string]Voter `json:"voters"`
 	Winner          []string         `json:"winner"`
-	Status          string           `json:"status"` // "Upcoming", "Active", "Finished", "Rejected"
+	Status          string           `json:"status"` // "Upcoming", "Active", "Finished", "Rejected", "Runoff", "AwaitingDecision"
+	Rules           ResultRules      `json:"rules"`
+	TieDecision     *int             `json:"tie_decision,omitempty"` // Индекс варианта, выбранного создателем при ничьей
+	Result          *VotingResult    `json:"result,omitempty"`
+	AllowVoteChange bool             `json:"allow_vote_change"` // Можно ли менять/отзывать голос, пока голосование активно
+	VoteChanges     []VoteChange     `json:"vote_changes,omitempty"`
+	BallotMode      string           `json:"ballot_mode"`     // "open" (по умолчанию) или "commit_reveal"
+	RevealEndTime   time.Time        `json:"reveal_end_date"` // Конец фазы раскрытия для commit_reveal
+	// Commitments - хеши голосов в режиме commit_reveal. Наружу не отдаются: ballot.PublicView их убирает
+	Commitments map[string]Commitment `json:"commitments,omitempty"`
+}
+
+// Commitment - скрытый голос: хеш выбора, раскрываемый после окончания голосования
+type Commitment struct {
+	Hash        string    `json:"hash"`
+	CommittedAt time.Time `json:"committed_at"`
+	Revealed    bool      `json:"revealed"`
+}
+
+// IsCommitReveal сообщает, что голоса подаются в виде хешей с последующим раскрытием
+func (v VoteSession) IsCommitReveal() bool {
+	return v.BallotMode == BallotModeCommitReveal
+}
+
+// VoteChange - запись в истории изменений голоса
+type VoteChange struct {
+	VoterAddress string    `json:"voter_address"`
+	FromChoice   int       `json:"from_choice_index"`
+	ToChoice     int       `json:"to_choice_index"` // -1, если голос отозван
+	Weight       int64     `json:"weight"`          // Перенесенный или снятый вес голоса с учетом делегирований
+	Delegators   []string  `json:"delegators,omitempty"`
+	ChangedAt    time.Time `json:"changed_at"`
+}
+
+// ResultRules описывает правила подведения итогов голосования.
+// Нулевое значение соответствует старому поведению: кворум = MinNumberVotes,
+// побеждает вариант с наибольшим числом голосов, ничья дает нескольких победителей.
+type ResultRules struct {
+	QuorumType     string  `json:"quorum_type"`     // "absolute" (по умолчанию) или "percent"
+	Quorum         float64 `json:"quorum"`          // Кол-во голосов или процент от имеющих право голоса
+	ThresholdType  string  `json:"threshold_type"`  // "plurality" (по умолчанию), "simple_majority", "supermajority"
+	ThresholdBase  string  `json:"threshold_base"`  // "votes" (по умолчанию) или "eligible"
+	TieBreak       string  `json:"tie_break"`       // "none" (по умолчанию), "runoff", "earliest_option", "creator_decides"
+	EligibleVoters int64   `json:"eligible_voters"` // Если 0, считается по Voters с CanVote
+}
+
+// VotingResult объясняет, почему голосование завершилось именно так
+type VotingResult struct {
+	TotalVotes     int64    `json:"total_votes"`
+	EligibleVoters int64    `json:"eligible_voters"`
+	QuorumRequired int64    `json:"quorum_required"`
+	QuorumReached  bool     `json:"quorum_reached"`
+	ThresholdVotes int64    `json:"threshold_votes"` // Минимум голосов у победителя
+	Tie            bool     `json:"tie"`
+	RunoffChoices  []string `json:"runoff_choices,omitempty"`
+	Explanation    []string `json:"explanation"`
 }
 
 // UserDataResponse структура ответа для получения данных пользователя
@@ -56,9 +131,34 @@ type UserVotingDetail struct {
 	Status         string `json:"status"` // Добавлено поле Status для UserVotingDetail
 }
 
+// RevokeVoteRequest структура для приема запроса на отзыв голоса
+type RevokeVoteRequest struct {
+	VotingID    string `json:"voting_id"`
+	UserAddress string `json:"user_address"`
+	Signature   string `json:"signature"` // 0x-подпись текста отзыва голоса
+	SignedAt    int64  `json:"signed_at"` // Unix-время подписи
+}
+
+// CommitVoteRequest структура для приема скрытого голоса (commit)
+type CommitVoteRequest struct {
+	VotingID    string `json:"voting_id"`
+	UserAddress string `json:"user_address"`
+	Commitment  string `json:"commitment"` // 0x-хеш, см. ballot.CommitmentHash
+}
+
+// RevealVoteRequest структура для раскрытия голоса (reveal)
+type RevealVoteRequest struct {
+	VotingID            string `json:"voting_id"`
+	UserAddress         string `json:"user_address"`
+	SelectedOptionIndex int    `json:"selected_option_index"`
+	Salt                string `json:"salt"`
+}
+
 // VoteRequest структура для приема запроса на голосование
 type VoteRequest struct {
 	VotingID            string `json:"voting_id"`
 	UserAddress         string `json:"user_address"`
 	SelectedOptionIndex int    `json:"selected_option_index"`
+	Signature           string `json:"signature,omitempty"` // Нужна только для изменения уже отданного голоса
+	SignedAt            int64  `json:"signed_at,omitempty"` // Unix-время подписи
 }
@@ -0,0 +1,196 @@
+package refresh
+
+import (
+	"apiGateway/internal/config"
+	"apiGateway/internal/events"
+	"apiGateway/internal/lib/logger/sl"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// AllVotings - ключ списка всех голосований; остальные ключи - ID голосований
+const AllVotings = ""
+
+// triggerTimeout - сколько ждать отправку триггера в Kafka. Триггер не привязан
+// к контексту HTTP-запроса: его результат нужен и другим читателям
+const triggerTimeout = 5 * time.Second
+
+// maxKeys - предел ключей в каждой карте координатора. ID голосований приходят из URL как есть,
+// и без предела поток запросов с выдуманными ID раздувал бы карты и слал бы триггеры в Kafka
+const maxKeys = 10_000
+
+// Coordinator решает, когда читающим эндпоинтам нужно просить Java-сервис обновить данные.
+// Чтение всегда идет из кэша; триггер в Kafka отправляется, только если данные старше
+// бюджета устаревания, не чаще MinInterval на ключ и одним сообщением на все одновременные запросы.
+// Часто читаемые ключи дополнительно обновляются в фоне.
+type Coordinator struct {
+	cfg        config.Refresh
+	log        *slog.Logger
+	requestAll func(ctx context.Context) error
+	requestOne func(ctx context.Context, votingID string) error
+
+	group singleflight.Group
+
+	mu        sync.Mutex
+	requested map[string]time.Time // Когда последний раз отправлен триггер
+	refreshed map[string]time.Time // Когда последний раз пришли данные
+	hits      map[string]int       // Обращения за текущий период HotInterval
+}
+
+func New(cfg config.Refresh, log *slog.Logger, requestAll func(ctx context.Context) error, requestOne func(ctx context.Context, votingID string) error) *Coordinator {
+	if cfg.HotInterval <= 0 {
+		cfg.HotInterval = 5 * time.Second
+	}
+	if cfg.HotThreshold < 1 {
+		cfg.HotThreshold = 1
+	}
+
+	return &Coordinator{
+		cfg:        cfg,
+		log:        log.With(slog.String("component", "refresh")),
+		requestAll: requestAll,
+		requestOne: requestOne,
+		requested:  make(map[string]time.Time),
+		refreshed:  make(map[string]time.Time),
+		hits:       make(map[string]int),
+	}
+}
+
+// Touch отмечает чтение key и, если данные устарели, запускает обновление в фоне
+func (c *Coordinator) Touch(key string) {
+	c.mu.Lock()
+	if _, ok := c.hits[key]; ok || len(c.hits) < maxKeys {
+		c.hits[key]++
+	}
+	c.mu.Unlock()
+
+	c.refreshIfStale(key)
+}
+
+// Fresh отмечает, что данные по key только что пришли из Kafka
+func (c *Coordinator) Fresh(key string) {
+	c.mu.Lock()
+	if _, ok := c.refreshed[key]; ok || len(c.refreshed) < maxKeys {
+		c.refreshed[key] = time.Now()
+	}
+	c.mu.Unlock()
+}
+
+// Age возвращает возраст данных по key (ok = false, если данные еще не приходили
+// или голосование давно устарело и вытеснено из карты)
+func (c *Coordinator) Age(key string) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	at, ok := c.refreshed[key]
+	if !ok {
+		return 0, false
+	}
+	return time.Since(at), true
+}
+
+// Run отмечает свежесть данных по событиям шины и обновляет часто читаемые ключи в фоне
+func (c *Coordinator) Run(ctx context.Context, wg *sync.WaitGroup, bus *events.Bus) {
+	defer wg.Done()
+
+	updates, unsubscribe := bus.Subscribe(1024, events.VotingUpdated)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(c.cfg.HotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-updates:
+			// Пустой VotingID - пришел полный список голосований
+			c.Fresh(e.VotingID)
+		case <-ticker.C:
+			c.refreshHot()
+		}
+	}
+}
+
+// refreshHot обновляет ключи, которые читали не реже HotThreshold раз за период, и начинает новый период
+func (c *Coordinator) refreshHot() {
+	c.mu.Lock()
+	c.evictLocked(time.Now())
+	var hot []string
+	for key, n := range c.hits {
+		if n >= c.cfg.HotThreshold {
+			hot = append(hot, key)
+		}
+	}
+	clear(c.hits)
+	c.mu.Unlock()
+
+	for _, key := range hot {
+		c.refreshIfStale(key)
+	}
+}
+
+// evictLocked убирает записи, которые уже не влияют на решения: триггер старше MinInterval
+// ничего не откладывает, а данные старше StalenessBudget и так считаются устаревшими.
+// Возраст списка всех голосований хранится всегда, это один ключ
+func (c *Coordinator) evictLocked(now time.Time) {
+	for key, at := range c.requested {
+		if now.Sub(at) >= c.cfg.MinInterval {
+			delete(c.requested, key)
+		}
+	}
+	for key, at := range c.refreshed {
+		if key != AllVotings && now.Sub(at) >= c.cfg.StalenessBudget {
+			delete(c.refreshed, key)
+		}
+	}
+}
+
+// refreshIfStale отправляет триггер, если данные старше бюджета устаревания
+// и с прошлого триггера прошло не меньше MinInterval
+func (c *Coordinator) refreshIfStale(key string) {
+	now := time.Now()
+
+	c.mu.Lock()
+	fresh := now.Sub(c.refreshed[key]) < c.cfg.StalenessBudget
+	debounced := now.Sub(c.requested[key]) < c.cfg.MinInterval
+	if fresh || debounced {
+		c.mu.Unlock()
+		return
+	}
+	if _, ok := c.requested[key]; !ok && len(c.requested) >= maxKeys {
+		c.mu.Unlock()
+		c.log.Warn("Too many pending refresh triggers, skipping", slog.String("voting_id", key))
+		return
+	}
+	c.requested[key] = now
+	c.mu.Unlock()
+
+	// Одновременные запросы одного ключа делят одну отправку
+	go c.group.Do(key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), triggerTimeout)
+		defer cancel()
+
+		var err error
+		if key == AllVotings {
+			err = c.requestAll(ctx)
+		} else {
+			err = c.requestOne(ctx, key)
+		}
+		if err != nil {
+			c.log.Error("Failed to request data refresh from Kafka", sl.Err(err), slog.String("voting_id", key))
+			// Следующее чтение может повторить попытку сразу
+			c.mu.Lock()
+			delete(c.requested, key)
+			c.mu.Unlock()
+			return nil, err
+		}
+
+		c.log.Debug("Data refresh requested from Kafka", slog.String("voting_id", key))
+		return nil, nil
+	})
+}
@@ -0,0 +1,298 @@
+package results
+
+import (
+	"apiGateway/internal/models"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Типы кворума
+const (
+	QuorumAbsolute = "absolute"
+	QuorumPercent  = "percent"
+)
+
+// Типы порога прохождения
+const (
+	ThresholdPlurality      = "plurality"
+	ThresholdSimpleMajority = "simple_majority"
+	ThresholdSupermajority  = "supermajority"
+)
+
+// База, от которой считается порог
+const (
+	BaseVotes    = "votes"
+	BaseEligible = "eligible"
+)
+
+// Политики разрешения ничьей
+const (
+	TieBreakNone           = "none"
+	TieBreakRunoff         = "runoff"
+	TieBreakEarliestOption = "earliest_option"
+	TieBreakCreatorDecides = "creator_decides"
+)
+
+// Outcome - итог подсчета голосов завершенного голосования
+type Outcome struct {
+	Status  string
+	Winners []string
+	Result  models.VotingResult
+}
+
+// Normalize подставляет значения по умолчанию в пустые поля правил
+func Normalize(rules models.ResultRules) models.ResultRules {
+	if rules.QuorumType == "" {
+		rules.QuorumType = QuorumAbsolute
+	}
+	if rules.ThresholdType == "" {
+		rules.ThresholdType = ThresholdPlurality
+	}
+	if rules.ThresholdBase == "" {
+		rules.ThresholdBase = BaseVotes
+	}
+	if rules.TieBreak == "" {
+		rules.TieBreak = TieBreakNone
+	}
+	return rules
+}
+
+// Validate проверяет, что правила заданы корректно
+func Validate(rules models.ResultRules) error {
+	rules = Normalize(rules)
+
+	switch rules.QuorumType {
+	case QuorumAbsolute:
+		if rules.Quorum < 0 {
+			return fmt.Errorf("quorum must not be negative")
+		}
+	case QuorumPercent:
+		if rules.Quorum < 0 || rules.Quorum > 100 {
+			return fmt.Errorf("quorum percent must be between 0 and 100")
+		}
+	default:
+		return fmt.Errorf("unknown quorum type: %s", rules.QuorumType)
+	}
+
+	switch rules.ThresholdType {
+	case ThresholdPlurality, ThresholdSimpleMajority, ThresholdSupermajority:
+	default:
+		return fmt.Errorf("unknown threshold type: %s", rules.ThresholdType)
+	}
+
+	switch rules.ThresholdBase {
+	case BaseVotes, BaseEligible:
+	default:
+		return fmt.Errorf("unknown threshold base: %s", rules.ThresholdBase)
+	}
+
+	switch rules.TieBreak {
+	case TieBreakNone, TieBreakRunoff, TieBreakEarliestOption, TieBreakCreatorDecides:
+	default:
+		return fmt.Errorf("unknown tie break policy: %s", rules.TieBreak)
+	}
+
+	if rules.EligibleVoters < 0 {
+		return fmt.Errorf("eligible voters must not be negative")
+	}
+
+	return nil
+}
+
+// EligibleVoters возвращает число имеющих право голоса.
+// Явно заданное в правилах значение важнее списка Voters.
+func EligibleVoters(v models.VoteSession) int64 {
+	if v.Rules.EligibleVoters > 0 {
+		return v.Rules.EligibleVoters
+	}
+	var n int64
+	for _, voter := range v.Voters {
+		if voter.CanVote || voter.IsVoted {
+			n++
+		}
+	}
+	return n
+}
+
+// Evaluate подводит итоги завершенного голосования по его правилам
+func Evaluate(v models.VoteSession) Outcome {
+	rules := Normalize(v.Rules)
+
+	var total int64
+	for _, choice := range v.Choices {
+		total += choice.CountVotes
+	}
+	// TempNumberVotes может быть больше суммы по вариантам, если Java-сервис прислал его отдельно
+	if v.TempNumberVotes > total {
+		total = v.TempNumberVotes
+	}
+
+	eligible := EligibleVoters(v)
+	res := models.VotingResult{
+		TotalVotes:     total,
+		EligibleVoters: eligible,
+	}
+
+	// --- Кворум ---
+	switch rules.QuorumType {
+	case QuorumPercent:
+		res.QuorumRequired = int64(math.Ceil(float64(eligible) * rules.Quorum / 100))
+		res.Explanation = append(res.Explanation, fmt.Sprintf("quorum is %.2f%% of %d eligible voters = %d votes", rules.Quorum, eligible, res.QuorumRequired))
+	default:
+		res.QuorumRequired = int64(math.Ceil(rules.Quorum))
+		// Старые голосования задают кворум только через min_votes
+		if v.MinNumberVotes > res.QuorumRequired {
+			res.QuorumRequired = v.MinNumberVotes
+		}
+		res.Explanation = append(res.Explanation, fmt.Sprintf("quorum is %d votes", res.QuorumRequired))
+	}
+
+	if rules.QuorumType == QuorumPercent && eligible == 0 && rules.Quorum > 0 {
+		res.Explanation = append(res.Explanation, "number of eligible voters is unknown, percent quorum cannot be reached")
+		return Outcome{Status: models.StatusRejected, Winners: []string{}, Result: res}
+	}
+
+	if total < res.QuorumRequired {
+		res.Explanation = append(res.Explanation, fmt.Sprintf("quorum not reached: %d of %d votes", total, res.QuorumRequired))
+		return Outcome{Status: models.StatusRejected, Winners: []string{}, Result: res}
+	}
+	res.QuorumReached = true
+	res.Explanation = append(res.Explanation, fmt.Sprintf("quorum reached: %d of %d votes", total, res.QuorumRequired))
+
+	if len(v.Choices) == 0 {
+		res.Explanation = append(res.Explanation, "voting has no options")
+		return Outcome{Status: models.StatusRejected, Winners: []string{}, Result: res}
+	}
+
+	// --- Лидеры ---
+	maxVotes := int64(-1)
+	var leaders []int
+	for i, choice := range v.Choices {
+		if choice.CountVotes > maxVotes {
+			maxVotes = choice.CountVotes
+			leaders = []int{i}
+		} else if choice.CountVotes == maxVotes {
+			leaders = append(leaders, i)
+		}
+	}
+
+	// --- Порог ---
+	base := total
+	baseName := "cast votes"
+	if rules.ThresholdBase == BaseEligible {
+		base = eligible
+		baseName = "eligible voters"
+	}
+
+	switch rules.ThresholdType {
+	case ThresholdSimpleMajority:
+		res.ThresholdVotes = base/2 + 1
+		res.Explanation = append(res.Explanation, fmt.Sprintf("simple majority requires more than half of %d %s = %d votes", base, baseName, res.ThresholdVotes))
+	case ThresholdSupermajority:
+		res.ThresholdVotes = (2*base + 2) / 3
+		res.Explanation = append(res.Explanation, fmt.Sprintf("supermajority requires 2/3 of %d %s = %d votes", base, baseName, res.ThresholdVotes))
+	default:
+		res.ThresholdVotes = 0
+		res.Explanation = append(res.Explanation, "plurality: the option with the most votes wins")
+	}
+
+	if maxVotes < res.ThresholdVotes || (rules.ThresholdType != ThresholdPlurality && maxVotes == 0) {
+		res.Explanation = append(res.Explanation, fmt.Sprintf("no option reached the threshold: best result is %d votes", maxVotes))
+		return Outcome{Status: models.StatusRejected, Winners: []string{}, Result: res}
+	}
+
+	if len(leaders) == 1 {
+		winner := v.Choices[leaders[0]].Title
+		res.Explanation = append(res.Explanation, fmt.Sprintf("option %q wins with %d votes", winner, maxVotes))
+		return Outcome{Status: models.StatusFinished, Winners: []string{winner}, Result: res}
+	}
+
+	// --- Ничья ---
+	res.Tie = true
+	tied := make([]string, 0, len(leaders))
+	for _, i := range leaders {
+		tied = append(tied, v.Choices[i].Title)
+	}
+	res.Explanation = append(res.Explanation, fmt.Sprintf("tie between %d options with %d votes each", len(leaders), maxVotes))
+
+	switch rules.TieBreak {
+	case TieBreakEarliestOption:
+		winner := v.Choices[leaders[0]].Title
+		res.Explanation = append(res.Explanation, fmt.Sprintf("tie broken in favour of the earliest option %q", winner))
+		return Outcome{Status: models.StatusFinished, Winners: []string{winner}, Result: res}
+
+	case TieBreakRunoff:
+		res.RunoffChoices = tied
+		res.Explanation = append(res.Explanation, "runoff between tied options is required")
+		return Outcome{Status: models.StatusRunoff, Winners: []string{}, Result: res}
+
+	case TieBreakCreatorDecides:
+		if v.TieDecision != nil {
+			for _, i := range leaders {
+				if i == *v.TieDecision {
+					winner := v.Choices[i].Title
+					res.Explanation = append(res.Explanation, fmt.Sprintf("creator decided the tie in favour of %q", winner))
+					return Outcome{Status: models.StatusFinished, Winners: []string{winner}, Result: res}
+				}
+			}
+			res.Explanation = append(res.Explanation, "creator decision does not match any tied option")
+		}
+		res.RunoffChoices = tied
+		res.Explanation = append(res.Explanation, "waiting for the creator to pick one of the tied options")
+		return Outcome{Status: models.StatusAwaitingDecision, Winners: []string{}, Result: res}
+
+	default:
+		res.Explanation = append(res.Explanation, "no tie break policy, all tied options are winners")
+		return Outcome{Status: models.StatusFinished, Winners: tied, Result: res}
+	}
+}
+
+// Refresh пересчитывает статус голосования на момент now, а для завершенных - победителей и объяснение итогов
+func Refresh(v *models.VoteSession, now time.Time) {
+	switch {
+	case now.Before(v.StartTime):
+		// Начало могли перенести позже уже подведенных итогов
+		v.Status = models.StatusUpcoming
+		v.Winner = []string{}
+		v.Result = nil
+	case now.After(v.EndTime) && v.IsCommitReveal() && !now.After(v.RevealEndTime):
+		// Прием голосов закончен, идет фаза раскрытия - итоги еще не подводятся
+		v.Status = models.StatusReveal
+		v.Winner = []string{}
+		v.Result = nil
+	case now.After(v.EndTime):
+		// Голосование завершено - итоги подводятся по правилам голосования
+		outcome := Evaluate(*v)
+		v.Status = outcome.Status
+		v.Winner = outcome.Winners
+		v.Result = &outcome.Result
+	default:
+		// Конец могли перенести позже уже подведенных итогов
+		v.Status = models.StatusActive
+		v.Winner = []string{}
+		v.Result = nil
+	}
+}
+
+// Transitions возвращает моменты, в которые статус голосования меняется
+func Transitions(v models.VoteSession) []time.Time {
+	at := []time.Time{v.StartTime, v.EndTime}
+	if v.IsCommitReveal() {
+		at = append(at, v.RevealEndTime)
+	}
+	return at
+}
+
+// IsTied проверяет, входит ли вариант в число разделивших первое место
+func IsTied(v models.VoteSession, index int) bool {
+	if v.Result == nil || !v.Result.Tie || index < 0 || index >= len(v.Choices) {
+		return false
+	}
+	for _, title := range v.Result.RunoffChoices {
+		if v.Choices[index].Title == title {
+			return true
+		}
+	}
+	return false
+}
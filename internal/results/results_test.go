@@ -0,0 +1,341 @@
+package results
+
+import (
+	"apiGateway/internal/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+// session возвращает голосование с вариантами A, B, C... и заданными счетчиками голосов
+func session(rules models.ResultRules, counts ...int64) models.VoteSession {
+	choices := make([]models.Choice, len(counts))
+	for i, n := range counts {
+		choices[i] = models.Choice{Title: string(rune('A' + i)), CountVotes: n}
+	}
+	return models.VoteSession{ID: "1", Rules: rules, Choices: choices}
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name   string
+		voting models.VoteSession
+
+		status         string
+		winners        []string
+		quorumRequired int64
+		quorumReached  bool
+		threshold      int64
+		tie            bool
+		runoff         []string
+	}{
+		// --- Кворум ---
+		{
+			name:          "default rules: plurality without quorum",
+			voting:        session(models.ResultRules{}, 3, 1),
+			status:        models.StatusFinished,
+			winners:       []string{"A"},
+			quorumReached: true,
+		},
+		{
+			name:           "absolute quorum not reached",
+			voting:         session(models.ResultRules{Quorum: 5}, 2, 2),
+			status:         models.StatusRejected,
+			winners:        []string{},
+			quorumRequired: 5,
+		},
+		{
+			name: "min_votes is the quorum of old votings",
+			voting: func() models.VoteSession {
+				v := session(models.ResultRules{}, 3, 1)
+				v.MinNumberVotes = 4
+				return v
+			}(),
+			status:         models.StatusFinished,
+			winners:        []string{"A"},
+			quorumRequired: 4,
+			quorumReached:  true,
+		},
+		{
+			name: "votes_count above the sum of options counts towards quorum",
+			voting: func() models.VoteSession {
+				v := session(models.ResultRules{Quorum: 5}, 2, 1)
+				v.TempNumberVotes = 5
+				return v
+			}(),
+			status:         models.StatusFinished,
+			winners:        []string{"A"},
+			quorumRequired: 5,
+			quorumReached:  true,
+		},
+		{
+			name:           "percent quorum rounds up",
+			voting:         session(models.ResultRules{QuorumType: QuorumPercent, Quorum: 50, EligibleVoters: 5}, 2, 0),
+			status:         models.StatusRejected,
+			winners:        []string{},
+			quorumRequired: 3,
+		},
+		{
+			name:           "percent quorum reached",
+			voting:         session(models.ResultRules{QuorumType: QuorumPercent, Quorum: 50, EligibleVoters: 5}, 3, 0),
+			status:         models.StatusFinished,
+			winners:        []string{"A"},
+			quorumRequired: 3,
+			quorumReached:  true,
+		},
+		{
+			name:    "percent quorum with unknown eligible voters is never reached",
+			voting:  session(models.ResultRules{QuorumType: QuorumPercent, Quorum: 10}, 5, 0),
+			status:  models.StatusRejected,
+			winners: []string{},
+		},
+		{
+			name:          "zero percent quorum with unknown eligible voters",
+			voting:        session(models.ResultRules{QuorumType: QuorumPercent}, 1, 0),
+			status:        models.StatusFinished,
+			winners:       []string{"A"},
+			quorumReached: true,
+		},
+		{
+			name: "eligible voters are counted from the voters list",
+			voting: func() models.VoteSession {
+				v := session(models.ResultRules{QuorumType: QuorumPercent, Quorum: 100}, 1, 0)
+				v.Voters = map[string]models.Voter{
+					"0xa": {CanVote: true},
+					"0xb": {IsVoted: true},
+					"0xc": {},
+				}
+				return v
+			}(),
+			status:         models.StatusRejected,
+			winners:        []string{},
+			quorumRequired: 2,
+		},
+		{
+			name:          "no options",
+			voting:        session(models.ResultRules{}),
+			status:        models.StatusRejected,
+			winners:       []string{},
+			quorumReached: true,
+		},
+
+		// --- Порог и база ---
+		{
+			name:          "simple majority of votes reached",
+			voting:        session(models.ResultRules{ThresholdType: ThresholdSimpleMajority}, 3, 2),
+			status:        models.StatusFinished,
+			winners:       []string{"A"},
+			quorumReached: true,
+			threshold:     3,
+		},
+		{
+			name:          "simple majority of votes not reached",
+			voting:        session(models.ResultRules{ThresholdType: ThresholdSimpleMajority}, 2, 2, 1),
+			status:        models.StatusRejected,
+			winners:       []string{},
+			quorumReached: true,
+			threshold:     3,
+		},
+		{
+			name:          "simple majority of eligible voters not reached",
+			voting:        session(models.ResultRules{ThresholdType: ThresholdSimpleMajority, ThresholdBase: BaseEligible, EligibleVoters: 10}, 5, 1),
+			status:        models.StatusRejected,
+			winners:       []string{},
+			quorumReached: true,
+			threshold:     6,
+		},
+		{
+			name:          "simple majority of eligible voters reached",
+			voting:        session(models.ResultRules{ThresholdType: ThresholdSimpleMajority, ThresholdBase: BaseEligible, EligibleVoters: 10}, 6, 1),
+			status:        models.StatusFinished,
+			winners:       []string{"A"},
+			quorumReached: true,
+			threshold:     6,
+		},
+		{
+			name:          "supermajority of votes reached",
+			voting:        session(models.ResultRules{ThresholdType: ThresholdSupermajority}, 4, 2),
+			status:        models.StatusFinished,
+			winners:       []string{"A"},
+			quorumReached: true,
+			threshold:     4,
+		},
+		{
+			name:          "supermajority of votes rounds up",
+			voting:        session(models.ResultRules{ThresholdType: ThresholdSupermajority}, 3, 2),
+			status:        models.StatusRejected,
+			winners:       []string{},
+			quorumReached: true,
+			threshold:     4,
+		},
+		{
+			name:          "supermajority of eligible voters",
+			voting:        session(models.ResultRules{ThresholdType: ThresholdSupermajority, ThresholdBase: BaseEligible, EligibleVoters: 10}, 7, 0),
+			status:        models.StatusFinished,
+			winners:       []string{"A"},
+			quorumReached: true,
+			threshold:     7,
+		},
+		{
+			name:          "threshold is never reached without votes",
+			voting:        session(models.ResultRules{ThresholdType: ThresholdSupermajority}, 0, 0),
+			status:        models.StatusRejected,
+			winners:       []string{},
+			quorumReached: true,
+		},
+
+		// --- Ничья ---
+		{
+			name:          "tie without policy: all tied options win",
+			voting:        session(models.ResultRules{}, 2, 2, 1),
+			status:        models.StatusFinished,
+			winners:       []string{"A", "B"},
+			quorumReached: true,
+			tie:           true,
+		},
+		{
+			name:          "tie broken by the earliest option",
+			voting:        session(models.ResultRules{TieBreak: TieBreakEarliestOption}, 1, 2, 2),
+			status:        models.StatusFinished,
+			winners:       []string{"B"},
+			quorumReached: true,
+			tie:           true,
+		},
+		{
+			name:          "tie goes to runoff",
+			voting:        session(models.ResultRules{TieBreak: TieBreakRunoff}, 2, 2, 1),
+			status:        models.StatusRunoff,
+			winners:       []string{},
+			quorumReached: true,
+			tie:           true,
+			runoff:        []string{"A", "B"},
+		},
+		{
+			name:          "tie waits for the creator",
+			voting:        session(models.ResultRules{TieBreak: TieBreakCreatorDecides}, 2, 2, 1),
+			status:        models.StatusAwaitingDecision,
+			winners:       []string{},
+			quorumReached: true,
+			tie:           true,
+			runoff:        []string{"A", "B"},
+		},
+		{
+			name: "tie decided by the creator",
+			voting: func() models.VoteSession {
+				v := session(models.ResultRules{TieBreak: TieBreakCreatorDecides}, 2, 2, 1)
+				v.TieDecision = intPtr(1)
+				return v
+			}(),
+			status:        models.StatusFinished,
+			winners:       []string{"B"},
+			quorumReached: true,
+			tie:           true,
+		},
+		{
+			name: "creator decision outside the tie is ignored",
+			voting: func() models.VoteSession {
+				v := session(models.ResultRules{TieBreak: TieBreakCreatorDecides}, 2, 2, 1)
+				v.TieDecision = intPtr(2)
+				return v
+			}(),
+			status:        models.StatusAwaitingDecision,
+			winners:       []string{},
+			quorumReached: true,
+			tie:           true,
+			runoff:        []string{"A", "B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Evaluate(tt.voting)
+
+			if got.Status != tt.status {
+				t.Errorf("Status = %s, want %s (explanation: %q)", got.Status, tt.status, got.Result.Explanation)
+			}
+			if !slices.Equal(got.Winners, tt.winners) || got.Winners == nil {
+				t.Errorf("Winners = %#v, want %#v", got.Winners, tt.winners)
+			}
+			if got.Result.QuorumRequired != tt.quorumRequired {
+				t.Errorf("QuorumRequired = %d, want %d", got.Result.QuorumRequired, tt.quorumRequired)
+			}
+			if got.Result.QuorumReached != tt.quorumReached {
+				t.Errorf("QuorumReached = %t, want %t", got.Result.QuorumReached, tt.quorumReached)
+			}
+			if got.Result.ThresholdVotes != tt.threshold {
+				t.Errorf("ThresholdVotes = %d, want %d", got.Result.ThresholdVotes, tt.threshold)
+			}
+			if got.Result.Tie != tt.tie {
+				t.Errorf("Tie = %t, want %t", got.Result.Tie, tt.tie)
+			}
+			if !slices.Equal(got.Result.RunoffChoices, tt.runoff) {
+				t.Errorf("RunoffChoices = %v, want %v", got.Result.RunoffChoices, tt.runoff)
+			}
+			if len(got.Result.Explanation) == 0 {
+				t.Error("Explanation is empty")
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	revealEnd := end.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		ballotMode string
+		now        time.Time
+		status     string
+		hasResult  bool
+	}{
+		{"before start", "", start.Add(-time.Minute), models.StatusUpcoming, false},
+		{"during voting", "", start.Add(time.Minute), models.StatusActive, false},
+		{"after end", "", end.Add(time.Minute), models.StatusFinished, true},
+		{"commit-reveal during voting", models.BallotModeCommitReveal, start.Add(time.Minute), models.StatusActive, false},
+		{"commit-reveal reveal phase", models.BallotModeCommitReveal, end.Add(time.Minute), models.StatusReveal, false},
+		{"commit-reveal after reveal", models.BallotModeCommitReveal, revealEnd.Add(time.Minute), models.StatusFinished, true},
+		{"end moved after the results", "", end.Add(-time.Minute), models.StatusActive, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := session(models.ResultRules{}, 3, 1)
+			v.StartTime, v.EndTime, v.RevealEndTime = start, end, revealEnd
+			v.BallotMode = tt.ballotMode
+			// Устаревшие итоги и победитель не должны пережить пересчет
+			v.Result = &models.VotingResult{TotalVotes: 100}
+			v.Winner = []string{"stale"}
+
+			Refresh(&v, tt.now)
+
+			if v.Status != tt.status {
+				t.Errorf("Status = %s, want %s", v.Status, tt.status)
+			}
+			if (v.Result != nil) != tt.hasResult {
+				t.Errorf("Result = %+v, want present = %t", v.Result, tt.hasResult)
+			}
+			if tt.hasResult {
+				if v.Result.TotalVotes != 4 || !slices.Equal(v.Winner, []string{"A"}) {
+					t.Errorf("Result = %+v, Winner = %v, want 4 votes and winner A", v.Result, v.Winner)
+				}
+			} else if v.Winner == nil || len(v.Winner) != 0 {
+				t.Errorf("Winner = %#v, want empty", v.Winner)
+			}
+		})
+	}
+}
+
+func TestIsTied(t *testing.T) {
+	v := session(models.ResultRules{TieBreak: TieBreakCreatorDecides}, 2, 2, 1)
+	Refresh(&v, time.Now())
+
+	for index, want := range map[int]bool{-1: false, 0: true, 1: true, 2: false, 3: false} {
+		if got := IsTied(v, index); got != want {
+			t.Errorf("IsTied(%d) = %t, want %t", index, got, want)
+		}
+	}
+}
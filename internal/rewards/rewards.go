@@ -0,0 +1,119 @@
+package rewards
+
+import (
+	"errors"
+	"math/big"
+	"time"
+)
+
+// Year - год для годовой доходности (365 дней)
+const Year = 365 * 24 * time.Hour
+
+var (
+	ErrInvalidAmount   = errors.New("stake amount must be greater than zero")
+	ErrInvalidDuration = errors.New("duration must be at least one second")
+	ErrNoRewardRate    = errors.New("reward rate is unknown")
+	ErrEmptyPool       = errors.New("nothing is staked in the pool")
+)
+
+var weiPerETH = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// Params - параметры наград стейк-менеджера. Награды начисляются пулу с постоянной скоростью
+// и делятся между стейкерами пропорционально стейку
+type Params struct {
+	RewardRate     *big.Int      // Минимальных единиц токена наград в секунду на весь пул
+	TotalStaked    *big.Int      // Весь стейк пула в wei
+	Cooldown       time.Duration // Минимальный интервал между клеймами; 0 - клеймить можно всегда
+	RewardDecimals uint8         // decimals токена наград
+}
+
+// Input - что считаем: стейк Amount (wei) на срок Duration
+type Input struct {
+	Amount   *big.Int
+	Duration time.Duration
+	NewStake bool // Amount еще не в пуле: при расчете доли он добавляется к TotalStaked
+
+	// TokenPriceETH - цена токена наград в ETH. Без нее доходность считается только в токенах на 1 ETH
+	TokenPriceETH *big.Rat
+}
+
+// Projection - прогноз наград
+type Projection struct {
+	RewardPerSecond *big.Int // Доля стейка в скорости наград, минимальные единицы токена в секунду
+	Total           *big.Int // Накопится за срок
+
+	// Кулдаун: за срок удастся сделать Claims клеймов и забрать Claimable, остаток Pending ждет следующего окна
+	Claims    int64
+	Claimable *big.Int
+	Pending   *big.Int
+
+	TokensPerETHYear *big.Rat // Токенов наград (с учетом decimals) в год на 1 ETH стейка
+	APR              *big.Rat // Годовая доходность в процентах; nil без TokenPriceETH
+	EffectiveAPR     *big.Rat // То же по сумме, которую реально можно забрать за срок с учетом кулдауна
+}
+
+// Project считает прогноз наград. Деление целочисленное с округлением вниз, как в контракте
+func Project(p Params, in Input) (Projection, error) {
+	if in.Amount == nil || in.Amount.Sign() <= 0 {
+		return Projection{}, ErrInvalidAmount
+	}
+	// Награды начисляются посекундно: срок меньше секунды дал бы ноль секунд и деление на ноль в EffectiveAPR
+	if in.Duration < time.Second {
+		return Projection{}, ErrInvalidDuration
+	}
+	if p.RewardRate == nil {
+		return Projection{}, ErrNoRewardRate
+	}
+
+	pool := new(big.Int)
+	if p.TotalStaked != nil {
+		pool.Set(p.TotalStaked)
+	}
+	if in.NewStake {
+		pool.Add(pool, in.Amount)
+	}
+	if pool.Sign() <= 0 {
+		return Projection{}, ErrEmptyPool
+	}
+
+	seconds := big.NewInt(int64(in.Duration / time.Second))
+	share := new(big.Int).Mul(p.RewardRate, in.Amount)
+
+	proj := Projection{
+		RewardPerSecond: new(big.Int).Quo(share, pool),
+		Total:           new(big.Int).Quo(new(big.Int).Mul(share, seconds), pool),
+	}
+
+	// Клеймить можно раз в Cooldown: к концу срока доступно то, что накопилось к последнему окну
+	proj.Claims, proj.Claimable = 1, proj.Total
+	if p.Cooldown > 0 {
+		proj.Claims = int64(in.Duration / p.Cooldown)
+		claimSeconds := big.NewInt(proj.Claims * int64(p.Cooldown/time.Second))
+		proj.Claimable = new(big.Int).Quo(new(big.Int).Mul(share, claimSeconds), pool)
+	}
+	proj.Pending = new(big.Int).Sub(proj.Total, proj.Claimable)
+
+	year := big.NewInt(int64(Year / time.Second))
+	proj.TokensPerETHYear = perETHYear(new(big.Rat).SetFrac(new(big.Int).Mul(share, year), pool), in.Amount, p.RewardDecimals)
+
+	if in.TokenPriceETH != nil {
+		proj.APR = percent(proj.TokensPerETHYear, in.TokenPriceETH)
+		// Реально забранное за срок, пересчитанное на год
+		claimedPerYear := new(big.Rat).SetFrac(new(big.Int).Mul(proj.Claimable, year), seconds)
+		proj.EffectiveAPR = percent(perETHYear(claimedPerYear, in.Amount, p.RewardDecimals), in.TokenPriceETH)
+	}
+	return proj, nil
+}
+
+// perETHYear переводит годовые награды в минимальных единицах в токены на 1 ETH стейка
+func perETHYear(unitsPerYear *big.Rat, amountWei *big.Int, decimals uint8) *big.Rat {
+	tokenUnit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	stakeETH := new(big.Rat).SetFrac(amountWei, weiPerETH)
+	tokens := new(big.Rat).Quo(unitsPerYear, new(big.Rat).SetInt(tokenUnit))
+	return tokens.Quo(tokens, stakeETH)
+}
+
+func percent(tokensPerETH, priceETH *big.Rat) *big.Rat {
+	r := new(big.Rat).Mul(tokensPerETH, priceETH)
+	return r.Mul(r, big.NewRat(100, 1))
+}
@@ -0,0 +1,166 @@
+package rewards
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func eth(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), weiPerETH)
+}
+
+func TestProject(t *testing.T) {
+	tests := []struct {
+		name   string
+		params Params
+		in     Input
+
+		perSecond, total, claimable, pending int64
+		claims                               int64
+		tokensPerETHYear                     string
+		apr, effectiveAPR                    string // Пусто - APR не считается
+	}{
+		{
+			name:             "new stake joins the pool",
+			params:           Params{RewardRate: big.NewInt(10), TotalStaked: eth(90)},
+			in:               Input{Amount: eth(10), Duration: time.Hour, NewStake: true},
+			perSecond:        1,
+			total:            3600,
+			claims:           1,
+			claimable:        3600,
+			pending:          0,
+			tokensPerETHYear: "3153600.0000",
+		},
+		{
+			name:             "existing stake is already in the pool",
+			params:           Params{RewardRate: big.NewInt(10), TotalStaked: eth(100)},
+			in:               Input{Amount: eth(10), Duration: time.Hour},
+			perSecond:        1,
+			total:            3600,
+			claims:           1,
+			claimable:        3600,
+			pending:          0,
+			tokensPerETHYear: "3153600.0000",
+		},
+		{
+			name:             "cooldown leaves the tail pending",
+			params:           Params{RewardRate: big.NewInt(10), TotalStaked: eth(90), Cooldown: 25 * time.Minute},
+			in:               Input{Amount: eth(10), Duration: time.Hour, NewStake: true, TokenPriceETH: big.NewRat(1, 1_000_000)},
+			perSecond:        1,
+			total:            3600,
+			claims:           2,
+			claimable:        3000,
+			pending:          600,
+			tokensPerETHYear: "3153600.0000",
+			apr:              "315.3600",
+			effectiveAPR:     "262.8000",
+		},
+		{
+			name:             "cooldown longer than duration allows no claims",
+			params:           Params{RewardRate: big.NewInt(10), TotalStaked: eth(90), Cooldown: 2 * time.Hour},
+			in:               Input{Amount: eth(10), Duration: time.Hour, NewStake: true, TokenPriceETH: big.NewRat(1, 1_000_000)},
+			perSecond:        1,
+			total:            3600,
+			claims:           0,
+			claimable:        0,
+			pending:          3600,
+			tokensPerETHYear: "3153600.0000",
+			apr:              "315.3600",
+			effectiveAPR:     "0.0000",
+		},
+		{
+			name:             "integer division rounds down like the contract",
+			params:           Params{RewardRate: big.NewInt(1), TotalStaked: eth(2)},
+			in:               Input{Amount: eth(1), Duration: 3 * time.Second},
+			perSecond:        0,
+			total:            1,
+			claims:           1,
+			claimable:        1,
+			pending:          0,
+			tokensPerETHYear: "15768000.0000",
+		},
+		{
+			name:             "token decimals",
+			params:           Params{RewardRate: big.NewInt(1_000_000), TotalStaked: eth(1), RewardDecimals: 6},
+			in:               Input{Amount: eth(1), Duration: 24 * time.Hour, TokenPriceETH: big.NewRat(1, 3)},
+			perSecond:        1_000_000,
+			total:            86_400_000_000,
+			claims:           1,
+			claimable:        86_400_000_000,
+			pending:          0,
+			tokensPerETHYear: "31536000.0000",
+			apr:              "1051200000.0000",
+			effectiveAPR:     "1051200000.0000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proj, err := Project(tt.params, tt.in)
+			if err != nil {
+				t.Fatalf("Project() error = %v", err)
+			}
+
+			for _, c := range []struct {
+				field     string
+				got, want *big.Int
+			}{
+				{"RewardPerSecond", proj.RewardPerSecond, big.NewInt(tt.perSecond)},
+				{"Total", proj.Total, big.NewInt(tt.total)},
+				{"Claimable", proj.Claimable, big.NewInt(tt.claimable)},
+				{"Pending", proj.Pending, big.NewInt(tt.pending)},
+			} {
+				if c.got.Cmp(c.want) != 0 {
+					t.Errorf("%s = %s, want %s", c.field, c.got, c.want)
+				}
+			}
+			if proj.Claims != tt.claims {
+				t.Errorf("Claims = %d, want %d", proj.Claims, tt.claims)
+			}
+			if got := proj.TokensPerETHYear.FloatString(4); got != tt.tokensPerETHYear {
+				t.Errorf("TokensPerETHYear = %s, want %s", got, tt.tokensPerETHYear)
+			}
+
+			if tt.apr == "" {
+				if proj.APR != nil || proj.EffectiveAPR != nil {
+					t.Errorf("APR = %v, EffectiveAPR = %v, want nil without token price", proj.APR, proj.EffectiveAPR)
+				}
+				return
+			}
+			if got := proj.APR.FloatString(4); got != tt.apr {
+				t.Errorf("APR = %s, want %s", got, tt.apr)
+			}
+			if got := proj.EffectiveAPR.FloatString(4); got != tt.effectiveAPR {
+				t.Errorf("EffectiveAPR = %s, want %s", got, tt.effectiveAPR)
+			}
+		})
+	}
+}
+
+func TestProjectErrors(t *testing.T) {
+	valid := Params{RewardRate: big.NewInt(10), TotalStaked: eth(90)}
+
+	tests := []struct {
+		name   string
+		params Params
+		in     Input
+		want   error
+	}{
+		{"nil amount", valid, Input{Duration: time.Hour}, ErrInvalidAmount},
+		{"zero amount", valid, Input{Amount: new(big.Int), Duration: time.Hour}, ErrInvalidAmount},
+		{"zero duration", valid, Input{Amount: eth(1)}, ErrInvalidDuration},
+		{"sub-second duration", valid, Input{Amount: eth(1), Duration: 500 * time.Millisecond, TokenPriceETH: big.NewRat(1, 1)}, ErrInvalidDuration},
+		{"unknown reward rate", Params{TotalStaked: eth(1)}, Input{Amount: eth(1), Duration: time.Hour}, ErrNoRewardRate},
+		{"empty pool", Params{RewardRate: big.NewInt(1)}, Input{Amount: eth(1), Duration: time.Hour}, ErrEmptyPool},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Project(tt.params, tt.in); !errors.Is(err, tt.want) {
+				t.Errorf("Project() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Scheduler взводит таймеры на моменты смены статуса голосований
+// (начало, окончание, конец фазы раскрытия) и вызывает fire ровно в эти моменты.
+type Scheduler struct {
+	mu      sync.Mutex
+	timers  map[string][]*time.Timer
+	fire    func(votingID string)
+	log     *slog.Logger
+	stopped bool
+}
+
+// New создает планировщик; fire вызывается в отдельной горутине таймера
+func New(log *slog.Logger, fire func(votingID string)) *Scheduler {
+	return &Scheduler{
+		timers: make(map[string][]*time.Timer),
+		fire:   fire,
+		log:    log,
+	}
+}
+
+// Schedule перевзводит таймеры голосования на переданные моменты.
+// Прошедшие и нулевые моменты пропускаются.
+func (s *Scheduler) Schedule(votingID string, at ...time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+
+	s.cancelLocked(votingID)
+
+	now := time.Now()
+	var timers []*time.Timer
+	for _, t := range at {
+		if t.IsZero() || !t.After(now) {
+			continue
+		}
+		timers = append(timers, time.AfterFunc(t.Sub(now), func() {
+			s.log.Debug("Voting timer fired", slog.String("voting_id", votingID), slog.Time("at", t))
+			s.fire(votingID)
+		}))
+	}
+
+	if len(timers) > 0 {
+		s.timers[votingID] = timers
+	}
+}
+
+// Cancel снимает все таймеры голосования
+func (s *Scheduler) Cancel(votingID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancelLocked(votingID)
+}
+
+// Stop останавливает все таймеры; новые не взводятся
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.timers {
+		s.cancelLocked(id)
+	}
+	s.stopped = true
+}
+
+func (s *Scheduler) cancelLocked(votingID string) {
+	for _, t := range s.timers[votingID] {
+		t.Stop()
+	}
+	delete(s.timers, votingID)
+}
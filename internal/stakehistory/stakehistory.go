@@ -0,0 +1,139 @@
+package stakehistory
+
+import (
+	"apiGateway/internal/client"
+	"apiGateway/internal/config"
+	"apiGateway/internal/lib/logger/sl"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Indexer читает события стейкинга стейк-менеджера (stake, unstake, claim) с blockchain.logs_from_block
+// и хранит их в памяти по адресам. Новые блоки дочитываются раз в blockchain.stake_history_interval
+type Indexer struct {
+	sc  *client.StakeClient
+	cfg config.Blockchain
+	log *slog.Logger
+
+	mu        sync.RWMutex
+	next      uint64                         // Первый еще не прочитанный блок
+	byAddress map[string][]client.StakeEvent // Адрес в нижнем регистре -> события от старых к новым
+	seen      map[eventKey]struct{}
+}
+
+type eventKey struct {
+	txHash   string
+	logIndex uint
+}
+
+func New(cfg config.Blockchain, sc *client.StakeClient, log *slog.Logger) *Indexer {
+	if cfg.StakeHistoryInterval <= 0 {
+		cfg.StakeHistoryInterval = 15 * time.Second
+	}
+	return &Indexer{
+		sc:        sc,
+		cfg:       cfg,
+		log:       log.With(slog.String("component", "stakehistory")),
+		next:      cfg.LogsFromBlock,
+		byAddress: make(map[string][]client.StakeEvent),
+		seen:      make(map[eventKey]struct{}),
+	}
+}
+
+// Run индексирует события до остановки ctx
+func (ix *Indexer) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(ix.cfg.StakeHistoryInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := ix.sync(ctx); err != nil && ctx.Err() == nil {
+			ix.log.Error("Failed to index stake manager events", sl.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync дочитывает события до последнего блока окнами по blockchain.logs_block_range.
+// Прочитанное окно сохраняется сразу, поэтому после ошибки чтение продолжится с нее
+func (ix *Indexer) sync(ctx context.Context) error {
+	head, err := ix.sc.HeadBlock(ctx)
+	if err != nil {
+		return err
+	}
+
+	step := max(ix.cfg.LogsBlockRange, 1)
+	for from := ix.nextBlock(); from <= head; from = ix.nextBlock() {
+		to := min(from+step-1, head)
+		events, err := ix.sc.StakeEvents(ctx, from, to)
+		if err != nil {
+			return err
+		}
+		ix.add(events, to+1)
+		if len(events) > 0 {
+			ix.log.Debug("Indexed stake manager events",
+				slog.Int("count", len(events)), slog.Uint64("from_block", from), slog.Uint64("to_block", to))
+		}
+	}
+	return nil
+}
+
+func (ix *Indexer) nextBlock() uint64 {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.next
+}
+
+func (ix *Indexer) add(events []client.StakeEvent, next uint64) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for _, e := range events {
+		key := eventKey{txHash: e.TxHash, logIndex: e.LogIndex}
+		if _, ok := ix.seen[key]; ok {
+			continue
+		}
+		ix.seen[key] = struct{}{}
+		ix.byAddress[e.Address] = append(ix.byAddress[e.Address], e)
+	}
+	ix.next = next
+}
+
+// Events возвращает события address от новых к старым, начиная с offset, не больше limit,
+// и общее число событий адреса
+func (ix *Indexer) Events(address string, offset, limit int) ([]client.StakeEvent, int) {
+	if ix == nil {
+		return []client.StakeEvent{}, 0
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	all := ix.byAddress[strings.ToLower(address)]
+	total := len(all)
+	page := []client.StakeEvent{}
+	for i := total - 1 - offset; i >= 0 && len(page) < limit; i-- {
+		page = append(page, all[i])
+	}
+	return page, total
+}
+
+// IndexedTo возвращает последний проиндексированный блок (ok = false, пока не прочитан ни один)
+func (ix *Indexer) IndexedTo() (uint64, bool) {
+	if ix == nil {
+		return 0, false
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if ix.next <= ix.cfg.LogsFromBlock {
+		return 0, false
+	}
+	return ix.next - 1, true
+}